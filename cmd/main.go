@@ -14,9 +14,15 @@ import (
 	"time"
 	"unicode/utf8"
 
+	atcoder "github.com/yuqzii/konkurransetilsynet/internal/atcoder"
+	codechef "github.com/yuqzii/konkurransetilsynet/internal/codechef"
 	codeforces "github.com/yuqzii/konkurransetilsynet/internal/codeforces"
 	database "github.com/yuqzii/konkurransetilsynet/internal/database"
 	guessTheFunction "github.com/yuqzii/konkurransetilsynet/internal/guessTheFunction"
+	judge "github.com/yuqzii/konkurransetilsynet/internal/judge"
+	kattis "github.com/yuqzii/konkurransetilsynet/internal/kattis"
+	leetcode "github.com/yuqzii/konkurransetilsynet/internal/leetcode"
+	olympiad "github.com/yuqzii/konkurransetilsynet/internal/olympiad"
 	utils "github.com/yuqzii/konkurransetilsynet/internal/utils"
 
 	"github.com/bwmarrin/discordgo"
@@ -27,8 +33,21 @@ const (
 
 	cfAPIRequestsPerSecond   float64       = 0.5
 	cfAPIMaxBurst            int           = 1
+	acRequestsPerSecond      float64       = 0.5
+	acMaxBurst               int           = 1
+	kattisRequestsPerSecond  float64       = 0.5
+	kattisMaxBurst           int           = 1
+	ccRequestsPerSecond      float64       = 0.5
+	ccMaxBurst               int           = 1
+	lcRequestsPerSecond      float64       = 0.5
+	lcMaxBurst               int           = 1
 	contestUpdateInterval    time.Duration = 1 * time.Hour
 	contestPingCheckInterval time.Duration = 1 * time.Minute
+	handleRenameInterval     time.Duration = 24 * time.Hour
+	submissionFeedInterval   time.Duration = 5 * time.Minute
+	historySyncInterval      time.Duration = 6 * time.Hour
+	olympiadCalendarFile     string        = "olympiads.json"
+	dbInitDir                string        = "dbinit"
 
 	dbHost string = "db"
 	dbUser string = "postgres"
@@ -55,6 +74,9 @@ func main() {
 		log.Fatal("Could not connect to database: ", err)
 	}
 	log.Println("Connected to database.")
+	if err := db.Migrate(context.Background(), dbInitDir); err != nil {
+		log.Fatal("Could not migrate database: ", err)
+	}
 	// Close database when application exits
 	defer db.Close()
 
@@ -65,7 +87,9 @@ func main() {
 		log.Fatal("Could not create bot, ", err)
 	}
 
-	session.Identify.Intents = discordgo.IntentsAllWithoutPrivileged
+	// The privileged GUILD_MEMBERS intent is needed to list the members of guilds, and must also be
+	// enabled for the bot in the Discord developer portal
+	session.Identify.Intents = discordgo.IntentsAllWithoutPrivileged | discordgo.IntentGuildMembers
 
 	err = session.Open()
 	if err != nil {
@@ -86,8 +110,63 @@ func main() {
 		log.Fatal("Failed to create Codeforces handler:", err)
 	}
 	cf.Contests.StartContestUpdate(contestUpdateInterval)
-	cf.Pinger.StartContestPingCheck(contestPingCheckInterval)
+	cf.Renames.StartHandleRenameCheck(handleRenameInterval)
+	cf.Submissions.StartSubmissionFeed(submissionFeedInterval)
+	cf.History.StartHistorySync(historySyncInterval)
+
+	acClient := atcoder.NewClient(http.DefaultClient, acRequestsPerSecond, acMaxBurst, "https://atcoder.jp/")
+	ac, err := atcoder.NewHandler(db, session, acClient, session.State.Guilds)
+	if err != nil {
+		log.Fatal("Failed to create AtCoder handler:", err)
+	}
+	ac.Contests.StartContestUpdate(contestUpdateInterval)
+
+	ccClient := codechef.NewClient(http.DefaultClient, ccRequestsPerSecond, ccMaxBurst, "https://www.codechef.com/")
+	ccContests := judge.NewContestService(session, ccClient,
+		judge.WithContestListURL("https://www.codechef.com/contests"), judge.WithContestListColor(0x5b4638))
+	ccContests.StartContestUpdate(contestUpdateInterval)
+
+	lcClient := leetcode.NewClient(http.DefaultClient, lcRequestsPerSecond, lcMaxBurst, "https://leetcode.com/")
+	lcContests := judge.NewContestService(session, lcClient,
+		judge.WithContestListURL("https://leetcode.com/contest/"), judge.WithContestListColor(0xffa116))
+	lcContests.StartContestUpdate(contestUpdateInterval)
+
+	kattisClient := kattis.NewClient(http.DefaultClient, kattisRequestsPerSecond, kattisMaxBurst,
+		"https://open.kattis.com/")
+	kat := kattis.NewHandler(db, session, kattisClient)
+
+	calendar, err := olympiad.LoadCalendar(context.Background(), db, olympiadCalendarFile)
+	if err != nil {
+		log.Fatal("Failed to load olympiad calendar:", err)
+	}
+	var olyOpts []olympiad.HandlerOption
+	if guildID := os.Getenv("OWNER_GUILD_ID"); guildID != "" {
+		olyOpts = append(olyOpts, olympiad.WithOwnerGuild(guildID))
+	}
+	if owners := os.Getenv("BOT_OWNER_IDS"); owners != "" {
+		olyOpts = append(olyOpts, olympiad.WithOwners(strings.Split(owners, ",")...))
+	}
+	oly := olympiad.NewHandler(session, calendar, olyOpts...)
+
+	gtf := guessTheFunction.NewHandler(db, session)
+
+	// Judges are added before the manually maintained calendar, so their contests are kept when
+	// an olympiad round is also hosted on a judge, even if the calendar lists it a little earlier
+	feed := judge.NewContestFeed(session, db)
+	feed.AddProvider(cf.Contests)
+	feed.AddProvider(ac.Contests)
+	feed.AddProvider(ccContests)
+	feed.AddProvider(lcContests)
+	feed.AddProvider(calendar)
+	feed.AddDeadlineProvider(calendar)
 
+	pinger := judge.NewPinger(session, cf, feed)
+	if err := pinger.UpdatePingData(); err != nil {
+		log.Fatal("Failed to initialize contest pings:", err)
+	}
+	pinger.StartContestPingCheck(contestPingCheckInterval)
+
+	session.AddHandler(utils.HandlePageButton)
 	session.AddHandler(func(session *discordgo.Session, message *discordgo.MessageCreate) {
 		// Don't react to messages from this bot
 		if message.Author.ID == session.State.User.ID {
@@ -116,8 +195,32 @@ func main() {
 				log.Println("Codeforces command failed:", err)
 			}
 
+		case "ac":
+			err := ac.HandleCommand(args, message)
+			if err != nil {
+				log.Println("AtCoder command failed:", err)
+			}
+
+		case "contests":
+			err := feed.HandleCommand(args, message)
+			if err != nil {
+				log.Println("Contests command failed:", err)
+			}
+
+		case "olympiad":
+			err := oly.HandleCommand(args, message)
+			if err != nil {
+				log.Println("Olympiad command failed:", err)
+			}
+
+		case "kattis":
+			err := kat.HandleCommand(args, message)
+			if err != nil {
+				log.Println("Kattis command failed:", err)
+			}
+
 		case "guessTheFunction", "gtf":
-			err := guessTheFunction.HandleGuessTheFunctionCommands(args, session, message)
+			err := gtf.HandleCommand(args, message)
 			if err != nil {
 				log.Println("GuessTheFunction command failed:", err)
 			}
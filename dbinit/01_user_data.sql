@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_data (
+	discord_id NUMERIC(20) NOT NULL
+);
+
+-- Constraints have no IF NOT EXISTS, and this file runs on every startup
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname='unique_discord_id') THEN
+		ALTER TABLE user_data ADD CONSTRAINT unique_discord_id UNIQUE(discord_id);
+	END IF;
+END $$;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS linked_accounts (
+	discord_id NUMERIC(20) NOT NULL REFERENCES user_data(discord_id) ON DELETE CASCADE,
+	platform VARCHAR(32) NOT NULL,
+	handle VARCHAR(255) NOT NULL,
+	PRIMARY KEY (discord_id, platform)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_platform_handle ON linked_accounts (platform, LOWER(handle));
+
+-- Databases created before linked accounts stored the Codeforces handle in user_data
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM information_schema.columns
+		WHERE table_name='user_data' AND column_name='codeforces_handle') THEN
+
+		INSERT INTO linked_accounts (discord_id, platform, handle)
+			SELECT discord_id, 'codeforces', codeforces_handle FROM user_data
+			WHERE codeforces_handle IS NOT NULL
+			ON CONFLICT DO NOTHING;
+
+		DROP INDEX IF EXISTS unique_codeforces_handle;
+		ALTER TABLE user_data DROP COLUMN codeforces_handle;
+	END IF;
+END $$;
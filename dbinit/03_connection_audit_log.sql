@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS connection_audit_log (
+	id SERIAL PRIMARY KEY,
+	platform VARCHAR(32) NOT NULL DEFAULT 'codeforces',
+	-- Guild the change was made in, NULL for changes made by the bot or in DMs
+	guild_id NUMERIC(20),
+	discord_id NUMERIC(20) NOT NULL,
+	actor_id NUMERIC(20),
+	action VARCHAR(32) NOT NULL,
+	old_handle VARCHAR(255),
+	new_handle VARCHAR(255),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Audit logs created before linked accounts only contained Codeforces entries
+ALTER TABLE connection_audit_log ADD COLUMN IF NOT EXISTS platform VARCHAR(32) NOT NULL DEFAULT 'codeforces';
+
+-- Entries created before this column are treated like changes made outside of a guild
+ALTER TABLE connection_audit_log ADD COLUMN IF NOT EXISTS guild_id NUMERIC(20);
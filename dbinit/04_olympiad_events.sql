@@ -0,0 +1,7 @@
+-- Olympiad events added, edited or removed through commands, applied on top of olympiads.json
+CREATE TABLE IF NOT EXISTS olympiad_events (
+	id VARCHAR(64) PRIMARY KEY,
+	event JSONB,
+	removed BOOLEAN NOT NULL DEFAULT FALSE,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,7 @@
+-- Platforms are enabled in the contest feed of a guild unless there is a row disabling them
+CREATE TABLE IF NOT EXISTS guild_platform_settings (
+	guild_id NUMERIC(20) NOT NULL,
+	platform VARCHAR(32) NOT NULL,
+	enabled BOOLEAN NOT NULL,
+	PRIMARY KEY (guild_id, platform)
+);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS mashups (
+	id SERIAL PRIMARY KEY,
+	guild_id NUMERIC(20) NOT NULL,
+	channel_id NUMERIC(20) NOT NULL,
+	creator_id NUMERIC(20) NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	problems JSONB NOT NULL,
+	start_time TIMESTAMPTZ NOT NULL,
+	duration_seconds INTEGER NOT NULL,
+	scoreboard_message_id NUMERIC(20),
+	-- The final scoreboard, set when the mashup has finished
+	results JSONB,
+	finished BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS mashup_participants (
+	mashup_id INTEGER NOT NULL REFERENCES mashups(id) ON DELETE CASCADE,
+	discord_id NUMERIC(20) NOT NULL,
+	PRIMARY KEY (mashup_id, discord_id)
+);
@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS teams (
+	id SERIAL PRIMARY KEY,
+	guild_id NUMERIC(20) NOT NULL,
+	name VARCHAR(32) NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS teams_guild_name_key ON teams (guild_id, LOWER(name));
+
+CREATE TABLE IF NOT EXISTS team_members (
+	team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+	guild_id NUMERIC(20) NOT NULL,
+	discord_id NUMERIC(20) NOT NULL,
+	PRIMARY KEY (team_id, discord_id),
+	-- A user can only be in one team per guild
+	CONSTRAINT team_members_guild_member_key UNIQUE (guild_id, discord_id)
+);
+
+CREATE TABLE IF NOT EXISTS team_trainings (
+	id SERIAL PRIMARY KEY,
+	guild_id NUMERIC(20) NOT NULL,
+	channel_id NUMERIC(20) NOT NULL,
+	creator_id NUMERIC(20) NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	problems JSONB NOT NULL,
+	start_time TIMESTAMPTZ NOT NULL,
+	duration_seconds INTEGER NOT NULL,
+	scoreboard_message_id NUMERIC(20),
+	-- The final team scoreboard, set when the training has finished
+	results JSONB,
+	finished BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS team_training_teams (
+	training_id INTEGER NOT NULL REFERENCES team_trainings(id) ON DELETE CASCADE,
+	team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+	PRIMARY KEY (training_id, team_id)
+);
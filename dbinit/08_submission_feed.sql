@@ -0,0 +1,13 @@
+-- Guilds that have enabled the Codeforces submission feed
+CREATE TABLE IF NOT EXISTS submission_feed_settings (
+	guild_id NUMERIC(20) PRIMARY KEY,
+	channel_id NUMERIC(20) NOT NULL,
+	min_rating INTEGER NOT NULL DEFAULT 0,
+	max_posts_per_hour INTEGER NOT NULL DEFAULT 10
+);
+
+CREATE TABLE IF NOT EXISTS submission_feed_opt_outs (
+	guild_id NUMERIC(20) NOT NULL,
+	discord_id NUMERIC(20) NOT NULL,
+	PRIMARY KEY (guild_id, discord_id)
+);
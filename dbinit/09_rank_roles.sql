@@ -0,0 +1,6 @@
+-- Guilds that have enabled Codeforces rank roles
+CREATE TABLE IF NOT EXISTS rank_role_settings (
+	guild_id NUMERIC(20) PRIMARY KEY,
+	-- title, short or prefixed
+	naming VARCHAR(16) NOT NULL DEFAULT 'title'
+);
@@ -0,0 +1,23 @@
+-- Rating changes and solved problems of linked Codeforces handles, used for leaderboards
+CREATE TABLE IF NOT EXISTS cf_rating_changes (
+	handle VARCHAR(64) NOT NULL,
+	contest_id INTEGER NOT NULL,
+	old_rating INTEGER NOT NULL,
+	new_rating INTEGER NOT NULL,
+	update_time TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (handle, contest_id)
+);
+
+CREATE TABLE IF NOT EXISTS cf_solves (
+	handle VARCHAR(64) NOT NULL,
+	problem_id VARCHAR(16) NOT NULL,
+	solved_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (handle, problem_id)
+);
+
+CREATE TABLE IF NOT EXISTS guild_seasons (
+	id SERIAL PRIMARY KEY,
+	guild_id NUMERIC(20) NOT NULL,
+	name VARCHAR(64) NOT NULL,
+	start_time TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,11 @@
+-- Points awarded for correct Guess the Function guesses, used for the leaderboard
+CREATE TABLE IF NOT EXISTS gtf_scores (
+	id SERIAL PRIMARY KEY,
+	guild_id NUMERIC(20) NOT NULL,
+	discord_id NUMERIC(20) NOT NULL,
+	username VARCHAR(64) NOT NULL,
+	points INTEGER NOT NULL,
+	queries INTEGER NOT NULL,
+	duration_seconds DOUBLE PRECISION NOT NULL,
+	scored_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,39 @@
+-- Guess the Function rounds with every query and guess, so active rounds survive restarts
+CREATE TABLE IF NOT EXISTS gtf_rounds (
+	id SERIAL PRIMARY KEY,
+	-- Empty for rounds outside of guilds
+	guild_id NUMERIC(20),
+	channel_id NUMERIC(20) NOT NULL,
+	author_id NUMERIC(20) NOT NULL,
+	definition TEXT NOT NULL,
+	-- The parsed function as serialized by the bot
+	function JSONB NOT NULL,
+	lower_bound DOUBLE PRECISION NOT NULL,
+	upper_bound DOUBLE PRECISION NOT NULL,
+	start_time TIMESTAMPTZ NOT NULL,
+	-- Set when the round has finished
+	end_time TIMESTAMPTZ,
+	winner_id NUMERIC(20)
+);
+
+-- Channels have at most one active round
+CREATE UNIQUE INDEX IF NOT EXISTS gtf_rounds_active_channel ON gtf_rounds (channel_id) WHERE end_time IS NULL;
+
+CREATE TABLE IF NOT EXISTS gtf_queries (
+	round_id INTEGER NOT NULL REFERENCES gtf_rounds(id) ON DELETE CASCADE,
+	discord_id NUMERIC(20) NOT NULL,
+	x DOUBLE PRECISION NOT NULL,
+	-- NaN or infinite where the function is undefined
+	y DOUBLE PRECISION NOT NULL,
+	derivative BOOLEAN NOT NULL,
+	cost INTEGER NOT NULL,
+	query_time TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS gtf_guesses (
+	round_id INTEGER NOT NULL REFERENCES gtf_rounds(id) ON DELETE CASCADE,
+	discord_id NUMERIC(20) NOT NULL,
+	definition TEXT NOT NULL,
+	correct BOOLEAN NOT NULL,
+	guess_time TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,241 @@
+package atcoder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+	"golang.org/x/time/rate"
+)
+
+type api interface {
+	judge.Judge
+
+	contestListURL() string
+	hasUpdatedRating(ctx context.Context, contestID string) (bool, error)
+}
+
+type client struct {
+	client  *http.Client
+	limiter *rate.Limiter
+	url     string
+
+	profileTimeout time.Duration
+}
+
+type clientOption func(*client)
+
+// Creates an AtCoder client. AtCoder does not have an official API, so the client reads the
+// JSON endpoints used by the website and scrapes the rest from the HTML pages under url.
+func NewClient(httpClient *http.Client, requestsPerSecond float64, burst int, url string,
+	opts ...clientOption) *client {
+
+	const defaultProfileTimeout time.Duration = 5 * time.Minute
+
+	c := &client{
+		client:         httpClient,
+		limiter:        rate.NewLimiter(rate.Limit(requestsPerSecond), burst),
+		url:            url,
+		profileTimeout: defaultProfileTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Sets the timeout of authentication through the affiliation token.
+func WithProfileTimeout(timeout time.Duration) clientOption {
+	return func(c *client) {
+		c.profileTimeout = timeout
+	}
+}
+
+var ErrAtCoderIssue = errors.New("issue with the AtCoder server")
+var ErrClientIssue = errors.New("(skill) issue with our client")
+var errNotFound = errors.New("not found")
+
+type contest struct {
+	ID       string
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+}
+
+type historyEntry struct {
+	IsRated           bool   `json:"IsRated"`
+	Place             int    `json:"Place"`
+	OldRating         int    `json:"OldRating"`
+	NewRating         int    `json:"NewRating"`
+	Performance       int    `json:"Performance"`
+	ContestName       string `json:"ContestName"`
+	ContestScreenName string `json:"ContestScreenName"`
+	EndTime           string `json:"EndTime"`
+}
+
+// Performs a GET request and returns the body if the response status is successful.
+func (c *client) get(ctx context.Context, endpoint string) (body []byte, err error) {
+	// Wait for rate limiter permission
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.url+endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	// Ask for English pages, since the scraper relies on English labels
+	req.Header.Set("Accept-Language", "en")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, res.Body.Close())
+	}()
+
+	if err = responseCodeCheck(res); err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(res.Body)
+}
+
+var (
+	contestTableRegex = regexp.MustCompile(`(?s)id="contest-table-(?:action|upcoming)".*?<tbody>(.*?)</tbody>`)
+	contestRowRegex   = regexp.MustCompile(`(?s)<tr>(.*?)</tr>`)
+	contestTimeRegex  = regexp.MustCompile(`<time class='fixtime fixtime-full'>([^<]+)</time>`)
+	contestLinkRegex  = regexp.MustCompile(`<a href="/contests/([^"/]+)">([^<]+)</a>`)
+	durationRegex     = regexp.MustCompile(`<td class="text-center">(\d+):(\d{2})</td>`)
+	affiliationRegex  = regexp.MustCompile(`(?s)<th[^>]*>Affiliation</th>\s*<td[^>]*>(.*?)</td>`)
+)
+
+const contestTimeLayout = "2006-01-02 15:04:05-0700"
+
+// Scrapes the running and upcoming contests from the contest page.
+func (c *client) getUpcomingContests(ctx context.Context) ([]contest, error) {
+	body, err := c.get(ctx, "contests/?lang=en")
+	if err != nil {
+		return nil, fmt.Errorf("getting AtCoder contest page: %w", err)
+	}
+
+	var contests []contest
+	for _, table := range contestTableRegex.FindAllSubmatch(body, -1) {
+		for _, row := range contestRowRegex.FindAllSubmatch(table[1], -1) {
+			cont, err := parseContestRow(string(row[1]))
+			if err != nil {
+				return nil, err
+			}
+			contests = append(contests, cont)
+		}
+	}
+
+	return contests, nil
+}
+
+func parseContestRow(row string) (contest, error) {
+	timeMatch := contestTimeRegex.FindStringSubmatch(row)
+	linkMatch := contestLinkRegex.FindStringSubmatch(row)
+	durationMatch := durationRegex.FindStringSubmatch(row)
+	if timeMatch == nil || linkMatch == nil || durationMatch == nil {
+		return contest{}, fmt.Errorf("%w: unexpected contest row format", ErrClientIssue)
+	}
+
+	start, err := time.Parse(contestTimeLayout, timeMatch[1])
+	if err != nil {
+		return contest{}, fmt.Errorf("parsing start time of %s: %w", linkMatch[1], err)
+	}
+	hours, _ := strconv.Atoi(durationMatch[1])
+	minutes, _ := strconv.Atoi(durationMatch[2])
+
+	return contest{
+		ID:       linkMatch[1],
+		Name:     html.UnescapeString(linkMatch[2]),
+		Start:    start,
+		Duration: time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute,
+	}, nil
+}
+
+// Returns the contest history of the user, oldest first.
+func (c *client) getUserHistory(ctx context.Context, handle string) ([]historyEntry, error) {
+	body, err := c.get(ctx, "users/"+url.PathEscape(handle)+"/history/json")
+	if err != nil {
+		return nil, fmt.Errorf("getting AtCoder history of '%s': %w", handle, err)
+	}
+
+	var history []historyEntry
+	if err = json.Unmarshal(body, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// Returns the affiliation field of the user's profile.
+func (c *client) getAffiliation(ctx context.Context, handle string) (string, error) {
+	body, err := c.get(ctx, "users/"+url.PathEscape(handle)+"?lang=en")
+	if err != nil {
+		return "", fmt.Errorf("getting AtCoder profile of '%s': %w", handle, err)
+	}
+
+	match := affiliationRegex.FindSubmatch(body)
+	if match == nil {
+		// Users without an affiliation do not have the row at all
+		return "", nil
+	}
+	return html.UnescapeString(strings.TrimSpace(string(match[1]))), nil
+}
+
+func (c *client) userExists(ctx context.Context, handle string) (bool, error) {
+	_, err := c.get(ctx, "users/"+url.PathEscape(handle))
+	if errors.Is(err, errNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *client) contestListURL() string {
+	return c.url + "contests/"
+}
+
+// AtCoder publishes the results of a contest once the ratings have been updated.
+func (c *client) hasUpdatedRating(ctx context.Context, contestID string) (bool, error) {
+	body, err := c.get(ctx, "contests/"+url.PathEscape(contestID)+"/results/json")
+	if err != nil {
+		return false, fmt.Errorf("getting AtCoder results of %s: %w", contestID, err)
+	}
+
+	var results []json.RawMessage
+	if err = json.Unmarshal(body, &results); err != nil {
+		return false, err
+	}
+	return len(results) != 0, nil
+}
+
+func responseCodeCheck(res *http.Response) error {
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %s", errNotFound, res.Status)
+	case res.StatusCode/100 == 4:
+		return fmt.Errorf("%w: %s", ErrClientIssue, res.Status)
+	case res.StatusCode/100 == 5:
+		return fmt.Errorf("%w: %s", ErrAtCoderIssue, res.Status)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,137 @@
+package atcoder
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+)
+
+// Serves the fixtures in testdata in place of atcoder.jp
+func newFakeAtCoder(t *testing.T) *client {
+	serveFile := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			data, err := os.ReadFile("testdata/" + name)
+			if err != nil {
+				t.Fatalf("reading fixture %s: %s", name, err)
+			}
+			_, _ = w.Write(data)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/contests/", serveFile("contests.html"))
+	mux.HandleFunc("/users/tourist", serveFile("user.html"))
+	mux.HandleFunc("/users/tourist/history/json", serveFile("history.json"))
+	mux.HandleFunc("/users/newbie/history/json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("[]"))
+	})
+	mux.HandleFunc("/contests/abc410/results/json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("[]"))
+	})
+	mux.HandleFunc("/contests/abc409/results/json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"UserScreenName":"tourist","IsRated":true}]`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return NewClient(server.Client(), 1000, 1000, server.URL+"/")
+}
+
+func Test_UpcomingContests(t *testing.T) {
+	c := newFakeAtCoder(t)
+
+	contests, err := c.UpcomingContests(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []judge.Contest{
+		{ID: "abc410", Name: "AtCoder Beginner Contest 410",
+			Start: time.Date(2025, 6, 14, 12, 0, 0, 0, time.UTC), Duration: 100 * time.Minute},
+		{ID: "arc200", Name: "AtCoder Regular Contest 200 (Div. 1)",
+			Start: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC), Duration: 2 * time.Hour},
+		{ID: "ahc050", Name: "AtCoder Heuristic Contest 050 & Friends",
+			Start: time.Date(2025, 6, 20, 10, 0, 0, 0, time.UTC), Duration: 240 * time.Hour},
+	}
+	if len(contests) != len(expected) {
+		t.Fatalf("expected %d contests, got %d: %+v", len(expected), len(contests), contests)
+	}
+	for i, exp := range expected {
+		got := contests[i]
+		if got.Platform != judge.AtCoder || got.ID != exp.ID || got.Name != exp.Name ||
+			!got.Start.Equal(exp.Start) || got.Duration != exp.Duration {
+			t.Errorf("contest %d: expected %+v, got %+v", i, exp, got)
+		}
+		if got.URL != c.url+"contests/"+exp.ID {
+			t.Errorf("contest %d: unexpected url %s", i, got.URL)
+		}
+	}
+}
+
+func Test_UserRating(t *testing.T) {
+	c := newFakeAtCoder(t)
+
+	rating, err := c.UserRating(context.Background(), "tourist")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// The latest participation is unrated, so the rating comes from the one before
+	if rating.Rating != 1850 || rating.OldRating != 1200 {
+		t.Errorf("expected rating 1200 -> 1850, got %d -> %d", rating.OldRating, rating.Rating)
+	}
+
+	_, err = c.UserRating(context.Background(), "newbie")
+	if !errors.Is(err, judge.ErrNoRating) {
+		t.Errorf("expected ErrNoRating for unrated user, got %v", err)
+	}
+}
+
+func Test_UserExists(t *testing.T) {
+	c := newFakeAtCoder(t)
+
+	exists, err := c.UserExists(context.Background(), "tourist")
+	if err != nil || !exists {
+		t.Errorf("expected tourist to exist, got %t, %v", exists, err)
+	}
+
+	exists, err = c.UserExists(context.Background(), "nobody")
+	if err != nil || exists {
+		t.Errorf("expected nobody to not exist, got %t, %v", exists, err)
+	}
+}
+
+func Test_AffiliationVerifier(t *testing.T) {
+	c := newFakeAtCoder(t)
+
+	v := &affiliationVerifier{client: c, token: "konk-0123456789ab"}
+	ok, err := v.Check(context.Background(), "tourist")
+	if err != nil || !ok {
+		t.Errorf("expected token in affiliation to verify, got %t, %v", ok, err)
+	}
+
+	v.token = "konk-ffffffffffff"
+	ok, err = v.Check(context.Background(), "tourist")
+	if err != nil || ok {
+		t.Errorf("expected missing token to not verify, got %t, %v", ok, err)
+	}
+}
+
+func Test_HasUpdatedRating(t *testing.T) {
+	c := newFakeAtCoder(t)
+
+	updated, err := c.hasUpdatedRating(context.Background(), "abc410")
+	if err != nil || updated {
+		t.Errorf("expected abc410 to not be updated, got %t, %v", updated, err)
+	}
+
+	updated, err = c.hasUpdatedRating(context.Background(), "abc409")
+	if err != nil || !updated {
+		t.Errorf("expected abc409 to be updated, got %t, %v", updated, err)
+	}
+}
@@ -0,0 +1,149 @@
+package atcoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+	"github.com/yuqzii/konkurransetilsynet/internal/utils"
+)
+
+const lbChannelName string = "ac-leaderboard"
+
+type Handler struct {
+	discord *discordgo.Session
+	guilds  []*discordgo.Guild
+	mu      sync.RWMutex
+
+	client api
+
+	Contests    *judge.ContestService
+	auth        *judge.AuthService
+	accounts    *judge.AccountService
+	leaderboard *judge.LeaderboardService
+}
+
+func NewHandler(db judge.AccountRepository, discord *discordgo.Session, client api,
+	guilds []*discordgo.Guild) (*Handler, error) {
+
+	h := Handler{discord: discord, guilds: guilds, client: client}
+
+	h.Contests = judge.NewContestService(discord, client,
+		judge.WithContestListURL(client.contestListURL()), judge.WithContestListColor(0x222222))
+	h.Contests.AddListener(&h)
+
+	h.auth = judge.NewAuthService(db, discord, client)
+	h.accounts = judge.NewAccountService(db, discord, client)
+	h.leaderboard = judge.NewLeaderboardService(discord, client, db, &h,
+		judge.WithLeaderboardChannelName(lbChannelName))
+
+	if err := h.leaderboard.UpdateData(); err != nil {
+		return nil, fmt.Errorf("initializing leaderboard guild data: %w", err)
+	}
+
+	return &h, nil
+}
+
+func (h *Handler) HandleCommand(args []string, m *discordgo.MessageCreate) error {
+	if len(args) < 2 {
+		return utils.UnknownCommand(h.discord, m)
+	}
+
+	switch args[1] {
+	case "contests":
+		if err := h.Contests.UpdateContests(context.TODO()); err != nil {
+			err = errors.Join(err, h.checkAPIError(err, m.ChannelID))
+			return fmt.Errorf("failed updating upcoming contests: %w", err)
+		}
+
+		err := h.Contests.ListContests(m.ChannelID)
+		if err != nil {
+			return fmt.Errorf("listing future contests: %w", err)
+		}
+	case "authenticate":
+		err := h.auth.AuthCommand(args, m)
+		if err != nil {
+			err = errors.Join(err, h.checkAPIError(err, m.ChannelID))
+			return fmt.Errorf("authentication command failed: %w", err)
+		}
+	case "unlink":
+		err := h.accounts.UnlinkCommand(m)
+		if err != nil {
+			return fmt.Errorf("unlink command failed: %w", err)
+		}
+	case "admin":
+		err := h.accounts.AdminCommand(args, m)
+		if err != nil {
+			err = errors.Join(err, h.checkAPIError(err, m.ChannelID))
+			return fmt.Errorf("admin command failed: %w", err)
+		}
+	default:
+		err := utils.UnknownCommand(h.discord, m)
+		return err
+	}
+
+	return nil
+}
+
+func (h *Handler) GetGuilds() []*discordgo.Guild {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	res := make([]*discordgo.Guild, len(h.guilds))
+	copy(res, h.guilds)
+	return res
+}
+
+// Sends a leaderboard once the ratings of a rated contest have been updated.
+func (h *Handler) OnContestFinish(c judge.Contest) {
+	if !isRatedContest(c) {
+		return
+	}
+
+	ratingUpdates := h.leaderboard.StartRatingUpdateCheck(func(ctx context.Context) (bool, error) {
+		return h.client.hasUpdatedRating(ctx, c.ID)
+	})
+	for updated := range ratingUpdates {
+		if updated {
+			h.leaderboard.SendLeaderboardMessageAll(c)
+		}
+	}
+}
+
+// Only the regular ABC, ARC and AGC rounds are rated
+func isRatedContest(c judge.Contest) bool {
+	for _, prefix := range []string{"abc", "arc", "agc"} {
+		if strings.HasPrefix(c.ID, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) checkAPIError(checkErr error, channelID string) error {
+	var err error
+	if errors.Is(checkErr, ErrAtCoderIssue) {
+		err = h.sendAtCoderIssueMessage(channelID)
+	} else if errors.Is(checkErr, ErrClientIssue) {
+		err = h.sendClientIssueMessage(channelID)
+	}
+	return err
+}
+
+func (h *Handler) sendAtCoderIssueMessage(channelID string) error {
+	msg := "There is an issue with the AtCoder servers."
+	_, err := h.discord.ChannelMessageSend(channelID, msg)
+	return err
+}
+
+func (h *Handler) sendClientIssueMessage(channelID string) error {
+	msg := "There is an issue with our AtCoder client.\n" +
+		"Please open an issue on [Github](https://github.com/Yuqzii/Konkurransetilsynet/issues) " +
+		"or contact the developers."
+	_, err := h.discord.ChannelMessageSend(channelID, msg)
+	return err
+}
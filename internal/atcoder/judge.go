@@ -0,0 +1,127 @@
+package atcoder
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+)
+
+// The client implements judge.Judge on top of the AtCoder specific methods.
+
+func (c *client) Platform() string {
+	return judge.AtCoder
+}
+
+func (c *client) Name() string {
+	return "AtCoder"
+}
+
+func (c *client) UpcomingContests(ctx context.Context) ([]judge.Contest, error) {
+	contests, err := c.getUpcomingContests(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]judge.Contest, len(contests))
+	for i, cont := range contests {
+		result[i] = judge.Contest{
+			Platform: judge.AtCoder,
+			ID:       cont.ID,
+			Name:     cont.Name,
+			URL:      c.url + "contests/" + cont.ID,
+			Start:    cont.Start,
+			Duration: cont.Duration,
+		}
+	}
+	return result, nil
+}
+
+func (c *client) UserRating(ctx context.Context, handle string) (*judge.Rating, error) {
+	history, err := c.getUserHistory(ctx, handle)
+	if err != nil {
+		return nil, err
+	}
+
+	// The history contains unrated participations as well, find the latest rated one
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].IsRated {
+			return &judge.Rating{
+				Handle:    handle,
+				Rating:    history[i].NewRating,
+				OldRating: history[i].OldRating,
+			}, nil
+		}
+	}
+	return nil, judge.ErrNoRating
+}
+
+// The AtCoder rating colors, every 400 rating from gray to red.
+var rankColors = []int{0x808080, 0x804000, 0x008000, 0x00c0c0, 0x0000ff, 0xc0c000, 0xff8000, 0xff0000}
+
+func (c *client) RankColor(rating int) int {
+	if rating <= 0 {
+		return 0
+	}
+	return rankColors[min(rating/400, len(rankColors)-1)]
+}
+
+func (c *client) Submissions(ctx context.Context, handle string, count int) ([]judge.Submission, error) {
+	return nil, judge.ErrUnsupported
+}
+
+func (c *client) UserExists(ctx context.Context, handle string) (bool, error) {
+	return c.userExists(ctx, handle)
+}
+
+const verifierProfile = "profile"
+
+func (c *client) DefaultVerifier() string {
+	return verifierProfile
+}
+
+func (c *client) NewVerifier(method string) (judge.Verifier, bool) {
+	if method != verifierProfile {
+		return nil, false
+	}
+	return &affiliationVerifier{client: c, timeout: c.profileTimeout}, true
+}
+
+// Verifies by having the user put a random token in the affiliation field of their AtCoder profile.
+type affiliationVerifier struct {
+	client  *client
+	timeout time.Duration
+
+	token string
+}
+
+func (v *affiliationVerifier) Prepare(ctx context.Context, handle string) (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating authentication token: %w", err)
+	}
+	v.token = "konk-" + hex.EncodeToString(b)
+
+	return fmt.Sprintf("Put `%s` in the affiliation field of your [AtCoder profile](%ssettings)",
+		v.token, v.client.url), nil
+}
+
+func (v *affiliationVerifier) Check(ctx context.Context, handle string) (bool, error) {
+	affiliation, err := v.client.getAffiliation(ctx, handle)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(affiliation, v.token), nil
+}
+
+func (v *affiliationVerifier) FailReason() string {
+	return fmt.Sprintf("Did not find `%s` in the affiliation field of the profile.", v.token)
+}
+
+func (v *affiliationVerifier) Timeout() time.Duration {
+	return v.timeout
+}
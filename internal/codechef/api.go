@@ -0,0 +1,125 @@
+package codechef
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+	"golang.org/x/time/rate"
+)
+
+// Reads the contest list used by the CodeChef website. The client implements judge.ContestSource.
+type client struct {
+	client  *http.Client
+	limiter *rate.Limiter
+	url     string
+}
+
+func NewClient(httpClient *http.Client, requestsPerSecond float64, burst int, url string) *client {
+	return &client{
+		client:  httpClient,
+		limiter: rate.NewLimiter(rate.Limit(requestsPerSecond), burst),
+		url:     url,
+	}
+}
+
+var ErrCodeChefIssue = errors.New("issue with the CodeChef server")
+var ErrClientIssue = errors.New("(skill) issue with our client")
+
+type contest struct {
+	Code  string `json:"contest_code"`
+	Name  string `json:"contest_name"`
+	Start string `json:"contest_start_date_iso"`
+	// Minutes, as a string
+	Duration string `json:"contest_duration"`
+}
+
+type contestList struct {
+	Status  string    `json:"status"`
+	Present []contest `json:"present_contests"`
+	Future  []contest `json:"future_contests"`
+}
+
+func (c *client) Platform() string {
+	return judge.CodeChef
+}
+
+func (c *client) Name() string {
+	return "CodeChef"
+}
+
+func (c *client) UpcomingContests(ctx context.Context) ([]judge.Contest, error) {
+	list, err := c.getContestList(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []judge.Contest
+	for _, cont := range append(list.Present, list.Future...) {
+		start, err := time.Parse(time.RFC3339, cont.Start)
+		if err != nil {
+			return nil, fmt.Errorf("%w: parsing start of %s: %w", ErrClientIssue, cont.Code, err)
+		}
+		minutes, err := strconv.Atoi(cont.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("%w: parsing duration of %s: %w", ErrClientIssue, cont.Code, err)
+		}
+
+		result = append(result, judge.Contest{
+			Platform: judge.CodeChef,
+			ID:       cont.Code,
+			Name:     cont.Name,
+			URL:      c.url + cont.Code,
+			Start:    start,
+			Duration: time.Duration(minutes) * time.Minute,
+		})
+	}
+	return result, nil
+}
+
+func (c *client) getContestList(ctx context.Context) (list *contestList, err error) {
+	// Wait for rate limiter permission
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET",
+		c.url+"api/list/contests/all?sort_by=START&sorting_order=asc&offset=0&mode=all", nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, res.Body.Close())
+	}()
+
+	switch {
+	case res.StatusCode/100 == 4:
+		return nil, fmt.Errorf("%w: %s", ErrClientIssue, res.Status)
+	case res.StatusCode/100 == 5:
+		return nil, fmt.Errorf("%w: %s", ErrCodeChefIssue, res.Status)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading CodeChef contest list: %w", err)
+	}
+	list = &contestList{}
+	if err = json.Unmarshal(body, list); err != nil {
+		return nil, fmt.Errorf("%w: decoding CodeChef contest list: %w", ErrClientIssue, err)
+	}
+	if list.Status != "success" {
+		return nil, fmt.Errorf("%w: contest list status %s", ErrCodeChefIssue, list.Status)
+	}
+	return list, nil
+}
@@ -0,0 +1,62 @@
+package codechef
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+)
+
+func Test_UpcomingContests(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/list/contests/all", func(w http.ResponseWriter, r *http.Request) {
+		data, err := os.ReadFile("testdata/contests.json")
+		if err != nil {
+			t.Fatalf("reading fixture: %s", err)
+		}
+		_, _ = w.Write(data)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	c := NewClient(server.Client(), 1000, 1000, server.URL+"/")
+
+	contests, err := c.UpcomingContests(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []judge.Contest{
+		{Platform: judge.CodeChef, ID: "START190", Name: "Starters 190", URL: server.URL + "/START190",
+			Start: time.Date(2025, 6, 18, 14, 30, 0, 0, time.UTC), Duration: 2 * time.Hour},
+		{Platform: judge.CodeChef, ID: "START191", Name: "Starters 191", URL: server.URL + "/START191",
+			Start: time.Date(2025, 6, 25, 14, 30, 0, 0, time.UTC), Duration: 2 * time.Hour},
+	}
+	if len(contests) != len(expected) {
+		t.Fatalf("expected %d contests, got %+v", len(expected), contests)
+	}
+	for i, exp := range expected {
+		got := contests[i]
+		if got.Platform != exp.Platform || got.ID != exp.ID || got.Name != exp.Name || got.URL != exp.URL ||
+			!got.Start.Equal(exp.Start) || got.Duration != exp.Duration {
+			t.Errorf("contest %d: expected %+v, got %+v", i, exp, got)
+		}
+	}
+}
+
+func Test_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	c := NewClient(server.Client(), 1000, 1000, server.URL+"/")
+
+	_, err := c.UpcomingContests(context.Background())
+	if !errors.Is(err, ErrCodeChefIssue) {
+		t.Errorf("expected ErrCodeChefIssue, got %v", err)
+	}
+}
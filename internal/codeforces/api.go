@@ -6,33 +6,101 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
 	"golang.org/x/time/rate"
 )
 
 type api interface {
+	judge.Judge
+
 	getContests(ctx context.Context) ([]*contest, error)
 	getProblems(ctx context.Context) ([]problem, error)
+	getContestProblems(ctx context.Context, contestID int) (*contest, []problem, error)
+	getContestParticipants(ctx context.Context, contestID int, handles []string) ([]string, error)
+	getEditorialURL(ctx context.Context, contestID int) (string, error)
 	getSubmissions(ctx context.Context, handle string, count uint16) ([]submission, error)
 	getRating(ctx context.Context, handle string) (*ratingChange, error)
+	getRatingHistory(ctx context.Context, handle string) ([]ratingChange, error)
 	hasUpdatedRating(ctx context.Context, c *contest) (bool, error)
 	checkUserExistence(ctx context.Context, handle string) (bool, error)
+	getUserInfo(ctx context.Context, handle string, checkHistoricHandles bool) (*user, error)
+	getUsers(ctx context.Context, handles []string, checkHistoricHandles bool) ([]user, error)
 }
 
 type client struct {
 	client  *http.Client
 	limiter *rate.Limiter
 	url     string
+
+	maxAuthProblemRating    uint16
+	authSubmissionCount     uint16
+	compilationErrorTimeout time.Duration
+	profileTimeout          time.Duration
+}
+
+type clientOption func(*client)
+
+func NewClient(httpClient *http.Client, requestsPerSecond float64, burst int, url string,
+	opts ...clientOption) *client {
+
+	const (
+		defaultMaxAuthProblemRating    uint16        = 1500
+		defaultAuthSubmissionCount     uint16        = 5
+		defaultCompilationErrorTimeout time.Duration = 2 * time.Minute
+		defaultProfileTimeout          time.Duration = 5 * time.Minute
+	)
+
+	c := &client{
+		client:                  httpClient,
+		limiter:                 rate.NewLimiter(rate.Limit(requestsPerSecond), burst),
+		url:                     url,
+		maxAuthProblemRating:    defaultMaxAuthProblemRating,
+		authSubmissionCount:     defaultAuthSubmissionCount,
+		compilationErrorTimeout: defaultCompilationErrorTimeout,
+		profileTimeout:          defaultProfileTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
-func NewClient(httpClient *http.Client, requestsPerSecond float64, burst int, url string) *client {
-	return &client{
-		client:  httpClient,
-		limiter: rate.NewLimiter(rate.Limit(requestsPerSecond), burst),
-		url:     url,
+// Sets the maximum rating of the problem users submit a compilation error to when authenticating.
+func WithMaxProblemRating(maxRating uint16) clientOption {
+	return func(c *client) {
+		c.maxAuthProblemRating = maxRating
+	}
+}
+
+// Sets how many of the latest submissions are checked for the compilation error when authenticating.
+func WithSubmissionCheckCount(cnt uint16) clientOption {
+	return func(c *client) {
+		c.authSubmissionCount = cnt
+	}
+}
+
+func WithCompilationErrorTimeout(timeout time.Duration) clientOption {
+	return func(c *client) {
+		c.compilationErrorTimeout = timeout
+	}
+}
+
+// Sets the timeout of authentication through the profile token method, which usually takes
+// longer than submitting a compilation error.
+func WithProfileTimeout(timeout time.Duration) clientOption {
+	return func(c *client) {
+		c.profileTimeout = timeout
 	}
 }
 
@@ -50,7 +118,6 @@ func (c *client) makeRequest(ctx context.Context, method, endpoint string) (*htt
 	return c.client.Do(req)
 }
 
-var ErrNoRating = errors.New("the user does not have a rating")
 var ErrCodeforcesIssue = errors.New("issue with the Codeforces server")
 var ErrClientIssue = errors.New("(skill) issue with our client")
 
@@ -92,15 +159,33 @@ type submission struct {
 		ContestID int    `json:"contestId"`
 		Index     string `json:"index"`
 		Name      string `json:"name"`
+		Rating    uint16 `json:"rating,omitempty"`
 	}
+	Author struct {
+		// CONTESTANT for submissions made during the contest
+		ParticipantType string `json:"participantType"`
+	} `json:"author"`
 	Verdict string `json:"verdict"`
 }
 
 type ratingChange struct {
-	Handle    string `json:"handle"`
-	OldRating int    `json:"oldRating"`
-	NewRating int    `json:"newRating"`
-	discordID string
+	ContestID               int    `json:"contestId"`
+	Handle                  string `json:"handle"`
+	OldRating               int    `json:"oldRating"`
+	NewRating               int    `json:"newRating"`
+	RatingUpdateTimeSeconds int64  `json:"ratingUpdateTimeSeconds"`
+	discordID               string
+}
+
+type user struct {
+	Handle       string `json:"handle"`
+	FirstName    string `json:"firstName,omitempty"`
+	LastName     string `json:"lastName,omitempty"`
+	Organization string `json:"organization,omitempty"`
+	Rating       int    `json:"rating,omitempty"`
+	MaxRating    int    `json:"maxRating,omitempty"`
+	Rank         string `json:"rank,omitempty"`
+	MaxRank      string `json:"maxRank,omitempty"`
 }
 
 type ratingChangeAPIReturn struct {
@@ -183,6 +268,146 @@ func (c *client) getProblems(ctx context.Context) (problems []problem, err error
 	return apiStruct.Result.Problems, err
 }
 
+// Returns a contest or gym and its problems from the contest standings.
+func (c *client) getContestProblems(ctx context.Context, contestID int) (con *contest, problems []problem, err error) {
+	endpoint := "contest.standings?"
+	params := url.Values{}
+	params.Set("contestId", strconv.Itoa(contestID))
+	params.Set("from", "1")
+	params.Set("count", "1")
+	res, err := c.makeRequest(ctx, "GET", endpoint+params.Encode())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		err = errors.Join(err, res.Body.Close())
+	}()
+
+	if err = responseCodeCheck(res); err != nil {
+		return nil, nil, err
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var apiStruct struct {
+		Status string `json:"status"`
+		Result struct {
+			Contest  contest   `json:"contest"`
+			Problems []problem `json:"problems"`
+		} `json:"result"`
+		Comment string `json:"comment,omitempty"`
+	}
+	err = json.Unmarshal(body, &apiStruct)
+
+	if apiStruct.Status == "FAILED" {
+		return nil, nil, errors.New(apiStruct.Comment)
+	}
+
+	return &apiStruct.Result.Contest, apiStruct.Result.Problems, err
+}
+
+// Returns the handles that participated in the contest among the given handles, including
+// virtual and unofficial participants.
+func (c *client) getContestParticipants(ctx context.Context, contestID int,
+	handles []string) (participants []string, err error) {
+
+	endpoint := "contest.standings?"
+	params := url.Values{}
+	params.Set("contestId", strconv.Itoa(contestID))
+	params.Set("handles", strings.Join(handles, ";"))
+	params.Set("showUnofficial", "true")
+	res, err := c.makeRequest(ctx, "GET", endpoint+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, res.Body.Close())
+	}()
+
+	if err = responseCodeCheck(res); err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiStruct struct {
+		Status string `json:"status"`
+		Result struct {
+			Rows []struct {
+				Party struct {
+					Members []struct {
+						Handle string `json:"handle"`
+					} `json:"members"`
+				} `json:"party"`
+			} `json:"rows"`
+		} `json:"result"`
+		Comment string `json:"comment,omitempty"`
+	}
+	err = json.Unmarshal(body, &apiStruct)
+
+	if apiStruct.Status == "FAILED" {
+		return nil, errors.New(apiStruct.Comment)
+	}
+
+	for _, row := range apiStruct.Result.Rows {
+		for _, member := range row.Party.Members {
+			if !slices.Contains(participants, member.Handle) {
+				participants = append(participants, member.Handle)
+			}
+		}
+	}
+	return participants, err
+}
+
+var editorialLinkRegex = regexp.MustCompile(`(?i)<a[^>]+href="(/blog/entry/\d+)"[^>]*>\s*(?:tutorial|editorial)`)
+
+// Returns the link to the editorial of the contest from the contest materials on the contest page,
+// or an empty string if the editorial has not been published.
+func (c *client) getEditorialURL(ctx context.Context, contestID int) (editorial string, err error) {
+	// The contest pages are on the website, not the API
+	pageURL, err := url.Parse(c.url)
+	if err != nil {
+		return "", fmt.Errorf("parsing client URL: %w", err)
+	}
+	pageURL = pageURL.JoinPath("..", "contest", strconv.Itoa(contestID))
+
+	if err := c.limiter.Wait(ctx); err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		err = errors.Join(err, res.Body.Close())
+	}()
+
+	if err = responseCodeCheck(res); err != nil {
+		return "", err
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+
+	match := editorialLinkRegex.FindSubmatch(body)
+	if match == nil {
+		return "", nil
+	}
+	return pageURL.ResolveReference(&url.URL{Path: string(match[1])}).String(), nil
+}
+
 func (c *client) getSubmissions(ctx context.Context, handle string,
 	count uint16) (submissions []submission, err error) {
 
@@ -222,7 +447,19 @@ func (c *client) getSubmissions(ctx context.Context, handle string,
 	return apiStruct.Submissions, err
 }
 
-func (c *client) getRating(ctx context.Context, handle string) (rating *ratingChange, err error) {
+func (c *client) getRating(ctx context.Context, handle string) (*ratingChange, error) {
+	history, err := c.getRatingHistory(ctx, handle)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, judge.ErrNoRating
+	}
+	return &history[len(history)-1], nil
+}
+
+// Returns every rating change of the user, oldest first.
+func (c *client) getRatingHistory(ctx context.Context, handle string) (history []ratingChange, err error) {
 	endpoint := "user.rating?"
 	params := url.Values{}
 	params.Set("handle", handle)
@@ -249,11 +486,7 @@ func (c *client) getRating(ctx context.Context, handle string) (rating *ratingCh
 		return nil, errors.New(apiReturn.Comment)
 	}
 
-	if len(apiReturn.Result) == 0 {
-		return nil, ErrNoRating
-	}
-
-	return &apiReturn.Result[len(apiReturn.Result)-1], err
+	return apiReturn.Result, err
 }
 
 func (c *client) hasUpdatedRating(ctx context.Context, contest *contest) (updated bool, err error) {
@@ -324,6 +557,100 @@ func (c *client) checkUserExistence(ctx context.Context, handle string) (exists
 	return exists, err
 }
 
+// Gets information about a single Codeforces user. If checkHistoricHandles is true, Codeforces
+// also resolves handles the user has had in the past, and the returned user contains the current handle.
+func (c *client) getUserInfo(ctx context.Context, handle string, checkHistoricHandles bool) (*user, error) {
+	users, err := c.getUsers(ctx, []string{handle}, checkHistoricHandles)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, fmt.Errorf("no user returned for handle '%s'", handle)
+	}
+	return &users[0], nil
+}
+
+// Returns the users with the given handles in one request.
+func (c *client) getUsers(ctx context.Context, handles []string, checkHistoricHandles bool) (users []user, err error) {
+	endpoint := "user.info?"
+	params := url.Values{}
+	params.Set("handles", strings.Join(handles, ";"))
+	params.Set("checkHistoricHandles", strconv.FormatBool(checkHistoricHandles))
+	res, err := c.makeRequest(ctx, "GET", endpoint+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Codeforces user.info api: %w", err)
+	}
+	defer func() {
+		err = errors.Join(err, res.Body.Close())
+	}()
+
+	if err = responseCodeCheck(res); err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiStruct struct {
+		Status  string `json:"status"`
+		Users   []user `json:"result"`
+		Comment string `json:"comment,omitempty"`
+	}
+	err = json.Unmarshal(body, &apiStruct)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiStruct.Status == "FAILED" {
+		return nil, errors.New(apiStruct.Comment)
+	}
+
+	return apiStruct.Users, nil
+}
+
+// Returns the users of the handles by lowercase handle, fetched in batches. Codeforces fails the
+// whole batch if one handle was renamed or deleted, so the handles of a failed batch are fetched one
+// by one and the handles that still fail are left out.
+func getUsersByHandle(ctx context.Context, c api, handles []string, batchSize int) (map[string]user, error) {
+	users := make(map[string]user, len(handles))
+	for batch := range slices.Chunk(handles, batchSize) {
+		batchUsers, err := c.getUsers(ctx, batch, false)
+		if err != nil && len(batch) > 1 {
+			batchUsers, err = getUsersOneByOne(ctx, c, batch, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("getting %d users: %w", len(batch), err)
+		}
+		for _, u := range batchUsers {
+			users[strings.ToLower(u.Handle)] = u
+		}
+	}
+	return users, nil
+}
+
+// Fetches the users of a batch that failed with batchErr one at a time. Returns batchErr if every
+// handle fails, as the API is then likely down rather than a handle being invalid.
+func getUsersOneByOne(ctx context.Context, c api, handles []string, batchErr error) ([]user, error) {
+	var users []user
+	for _, handle := range handles {
+		u, err := c.getUsers(ctx, []string{handle}, false)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("Skipping Codeforces user %s: %s", handle, err)
+			continue
+		}
+		users = append(users, u...)
+	}
+	if len(users) == 0 {
+		return nil, batchErr
+	}
+	return users, nil
+}
+
 func responseCodeCheck(res *http.Response) error {
 	switch res.StatusCode / 100 {
 	case 4:
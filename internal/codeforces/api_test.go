@@ -0,0 +1,105 @@
+package codeforces
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Serves the fixtures in testdata in place of codeforces.com
+func newFakeCodeforces(t *testing.T) *client {
+	serveFile := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			data, err := os.ReadFile("testdata/" + name)
+			if err != nil {
+				t.Fatalf("reading fixture %s: %s", name, err)
+			}
+			_, _ = w.Write(data)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/contest.standings", serveFile("standings.json"))
+	mux.HandleFunc("/contest/1903", serveFile("contest.html"))
+	mux.HandleFunc("/contest/1904", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html><body>No materials yet</body></html>"))
+	})
+
+	// Like Codeforces, fails the whole request if a handle does not exist and returns handles in
+	// their canonical case
+	mux.HandleFunc("/api/user.info", func(w http.ResponseWriter, r *http.Request) {
+		var users []string
+		for _, handle := range strings.Split(r.URL.Query().Get("handles"), ";") {
+			if handle == "deleted" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"status":"FAILED","comment":"handles: User with handle deleted not found"}`))
+				return
+			}
+			users = append(users, fmt.Sprintf(`{"handle":"%s","rating":%d}`,
+				strings.ToUpper(handle[:1])+handle[1:], 1000+100*len(handle)))
+		}
+		_, _ = w.Write([]byte(`{"status":"OK","result":[` + strings.Join(users, ",") + `]}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return NewClient(server.Client(), 1000, 1000, server.URL+"/api/")
+}
+
+func Test_GetContestParticipants(t *testing.T) {
+	c := newFakeCodeforces(t)
+
+	participants, err := c.getContestParticipants(context.Background(), 1903, []string{"alice", "bob", "carol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(participants, []string{"alice", "bob"}) {
+		t.Errorf("expected alice and bob, got %v", participants)
+	}
+}
+
+func Test_GetEditorialURL(t *testing.T) {
+	c := newFakeCodeforces(t)
+
+	editorial, err := c.getEditorialURL(context.Background(), 1903)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := c.url[:len(c.url)-len("api/")] + "blog/entry/122677"; editorial != expected {
+		t.Errorf("expected %s, got %s", expected, editorial)
+	}
+
+	editorial, err = c.getEditorialURL(context.Background(), 1904)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if editorial != "" {
+		t.Errorf("expected no editorial, got %s", editorial)
+	}
+}
+
+func Test_GetUsersByHandle(t *testing.T) {
+	c := newFakeCodeforces(t)
+
+	users, err := getUsersByHandle(context.Background(), c, []string{"alice", "deleted", "Bob", "carol"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 3 {
+		t.Errorf("expected 3 users, got %v", users)
+	}
+	for handle, expected := range map[string]string{"alice": "Alice", "bob": "Bob", "carol": "Carol"} {
+		if u, ok := users[handle]; !ok || u.Handle != expected {
+			t.Errorf("expected user %s under %s, got %v", expected, handle, users)
+		}
+	}
+
+	if _, err := getUsersByHandle(context.Background(), c, []string{"deleted", "deleted"}, 2); err == nil {
+		t.Error("expected an error when every handle fails")
+	}
+}
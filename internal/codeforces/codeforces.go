@@ -4,55 +4,76 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
 	"github.com/yuqzii/konkurransetilsynet/internal/utils"
 )
 
-type guildProvider interface {
-	getGuilds() []*discordgo.Guild
-}
-
 type Handler struct {
 	discord *discordgo.Session
 	guilds  []*discordgo.Guild
 	mu      sync.RWMutex
 
+	client api
+
 	Contests    *contestService
-	Pinger      *contestPinger
-	auth        *authService
-	leaderboard *lbService
+	Renames     *renameService
+	Submissions *submissionFeedService
+	History     *historyService
+	mashups     *mashupService
+	teams       *teamService
+	ranks       *rankRoleService
+	auth        *judge.AuthService
+	accounts    *judge.AccountService
+	leaderboard *judge.LeaderboardService
 }
 
-var ErrUserNotConnected error = errors.New("user not connected")
+const lbChannelName string = "cf-leaderboard"
 
+// Connected Codeforces accounts are stored as linked accounts on the judge.Codeforces platform.
 type Repository interface {
-	DiscordIDExists(ctx context.Context, discID string) (bool, error)
-	AddCodeforcesUser(ctx context.Context, discID, handle string) error
-	UpdateCodeforcesUser(ctx context.Context, discID, handle string) error
-	GetConnectedCodeforces(ctx context.Context, discID string) (string, error)
+	judge.AccountRepository
+	MashupRepository
+	TeamRepository
+	SubmissionFeedRepository
+	RankRoleRepository
+	HistoryRepository
 }
 
 func NewHandler(db Repository, discord *discordgo.Session, client api, guilds []*discordgo.Guild) (*Handler, error) {
-	h := Handler{discord: discord, guilds: guilds}
+	h := Handler{discord: discord, guilds: guilds, client: client}
 
 	h.Contests = newContestService(discord, client)
 	h.Contests.addListener(&h)
-
-	h.Pinger = newPinger(discord, h.Contests, &h)
-
-	h.auth = newAuthService(db, discord, client)
-
-	h.leaderboard = newLeaderboardService(discord, client, db, &h, WithRatingUpdateInterval(30*time.Minute))
-
-	if err := h.Pinger.updatePingData(); err != nil {
-		return nil, fmt.Errorf("initializing ping guild data: %w", err)
+	h.Contests.addListener(newPostMortemService(discord, db, client, &h))
+
+	h.ranks = newRankRoleService(discord, db, client)
+	syncRanks := func(string) { h.ranks.syncAll() }
+	h.auth = judge.NewAuthService(db, discord, client, judge.WithLinkHook(syncRanks))
+	h.accounts = judge.NewAccountService(db, discord, client, judge.WithAccountChangeHook(syncRanks))
+	h.Renames = newRenameService(db, client)
+	h.mashups = newMashupService(discord, db, client)
+	h.teams = newTeamService(discord, db, client)
+	h.Submissions = newSubmissionFeedService(discord, db, client)
+	h.History = newHistoryService(discord, db, client)
+
+	h.leaderboard = judge.NewLeaderboardService(discord, client, db, &h,
+		judge.WithRatingUpdateInterval(30*time.Minute), judge.WithLeaderboardChannelName(lbChannelName),
+		judge.WithLeaderboardImage())
+
+	if err := h.leaderboard.UpdateData(); err != nil {
+		return nil, fmt.Errorf("initializing leaderboard guild data: %w", err)
 	}
 
-	if err := h.leaderboard.updateData(); err != nil {
-		return nil, fmt.Errorf("initializing leaderboard guild data: %w", err)
+	if err := h.mashups.resume(context.Background()); err != nil {
+		return nil, fmt.Errorf("resuming mashups: %w", err)
+	}
+	if err := h.teams.resume(context.Background()); err != nil {
+		return nil, fmt.Errorf("resuming team trainings: %w", err)
 	}
 
 	return &h, nil
@@ -77,18 +98,54 @@ func (h *Handler) HandleCommand(args []string, m *discordgo.MessageCreate) error
 			return fmt.Errorf("adding debug contest: %w", err)
 		}
 	case "authenticate":
-		err := h.auth.authCommand(args, m)
+		err := h.auth.AuthCommand(args, m)
 		if err != nil {
 			err = errors.Join(err, h.checkAPIError(err, m.ChannelID))
 			return fmt.Errorf("authentication command failed: %w", err)
 		}
-	case "leaderboard":
-		// This is only for testing purposes
-		c := h.Contests.addContest("Leaderboard Test Contest", 69, uint32(time.Now().Unix()))
-		err := h.leaderboard.sendLeaderboardMessage(m.GuildID, m.ChannelID, c)
+	case "unlink":
+		err := h.accounts.UnlinkCommand(m)
+		if err != nil {
+			return fmt.Errorf("unlink command failed: %w", err)
+		}
+	case "admin":
+		err := h.accounts.AdminCommand(args, m)
 		if err != nil {
 			err = errors.Join(err, h.checkAPIError(err, m.ChannelID))
-			return fmt.Errorf("sending test leaderboard message: %w", err)
+			return fmt.Errorf("admin command failed: %w", err)
+		}
+	case "mashup":
+		err := h.mashups.handleCommand(args, m)
+		if err != nil {
+			err = errors.Join(err, h.checkAPIError(err, m.ChannelID))
+			return fmt.Errorf("mashup command failed: %w", err)
+		}
+	case "team":
+		err := h.teams.handleCommand(args, m)
+		if err != nil {
+			err = errors.Join(err, h.checkAPIError(err, m.ChannelID))
+			return fmt.Errorf("team command failed: %w", err)
+		}
+	case "ranks":
+		err := h.ranks.handleCommand(args, m)
+		if err != nil {
+			err = errors.Join(err, h.checkAPIError(err, m.ChannelID))
+			return fmt.Errorf("rank roles command failed: %w", err)
+		}
+	case "feed":
+		err := h.Submissions.handleCommand(args, m)
+		if err != nil {
+			return fmt.Errorf("submission feed command failed: %w", err)
+		}
+	case "leaderboard":
+		err := h.History.leaderboardCommand(args, m)
+		if err != nil {
+			return fmt.Errorf("leaderboard command failed: %w", err)
+		}
+	case "season":
+		err := h.History.seasonCommand(args, m)
+		if err != nil {
+			return fmt.Errorf("season command failed: %w", err)
 		}
 	default:
 		err := utils.UnknownCommand(h.discord, m)
@@ -98,7 +155,7 @@ func (h *Handler) HandleCommand(args []string, m *discordgo.MessageCreate) error
 	return nil
 }
 
-func (h *Handler) getGuilds() []*discordgo.Guild {
+func (h *Handler) GetGuilds() []*discordgo.Guild {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
@@ -108,10 +165,16 @@ func (h *Handler) getGuilds() []*discordgo.Guild {
 }
 
 func (h *Handler) onContestFinish(c *contest) {
-	ratingUpdates := h.leaderboard.startRatingUpdateCheck(c)
+	ratingUpdates := h.leaderboard.StartRatingUpdateCheck(func(ctx context.Context) (bool, error) {
+		return h.client.hasUpdatedRating(ctx, c)
+	})
 	for updated := range ratingUpdates {
 		if updated {
-			h.leaderboard.sendLeaderboardMessageAll(c)
+			h.leaderboard.SendLeaderboardMessageAll(c.toJudge())
+			h.ranks.syncAll()
+			if err := h.History.sync(context.Background()); err != nil {
+				log.Println("Failed to sync Codeforces history after rating update:", err)
+			}
 		}
 	}
 }
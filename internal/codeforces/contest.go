@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
 	"github.com/yuqzii/konkurransetilsynet/internal/utils"
 )
 
@@ -19,10 +20,6 @@ type contestFinishListener interface {
 	onContestFinish(c *contest)
 }
 
-type contestProvider interface {
-	getContests() []*contest
-}
-
 type contestService struct {
 	discord *discordgo.Session
 	client  api
@@ -60,51 +57,35 @@ func WithContestUpdateInterval(interval time.Duration) contestOption {
 	}
 }
 
+// Updates the contests immediately, and then every interval.
 func (s *contestService) StartContestUpdate(interval time.Duration) {
 	go func() {
 		for {
-			time.Sleep(interval)
 			err := s.updateContests()
 			if err != nil {
 				log.Println("Failed to update upcoming contests:", err)
 			}
+			time.Sleep(interval)
 		}
 	}()
 }
 
+func (s *contestService) Platform() string {
+	return judge.Codeforces
+}
+
+func (s *contestService) Name() string {
+	return "Codeforces"
+}
+
 func (s *contestService) addListener(l contestFinishListener) {
 	s.listeners = append(s.listeners, l)
 }
 
 func (s *contestService) listContests(m *discordgo.MessageCreate) error {
-	embed := discordgo.MessageEmbed{
-		Title:     "Upcoming Codeforces contests",
-		URL:       "https://codeforces.com/contests",
-		Color:     0x50e6ac,
-		Timestamp: time.Now().Format(time.RFC3339),
-	}
-
-	// Add embed for each contest
-	s.mu.RLock()
-	for _, contest := range s.contests {
-		f := &discordgo.MessageEmbedField{
-			Name:   contest.Name,
-			Inline: false,
-		}
-
-		if contest.Phase == "BEFORE" {
-			f.Value = fmt.Sprintf("Starts <t:%d:R>", contest.StartTimeSeconds)
-		} else {
-			f.Value = fmt.Sprintf("In progress, ends <t:%d:R>",
-				contest.StartTimeSeconds+contest.DurationSeconds)
-		}
-
-		embed.Fields = append(embed.Fields, f)
-	}
-	s.mu.RUnlock()
-
-	_, err := s.discord.ChannelMessageSendEmbed(m.ChannelID, &embed)
-	return err
+	pages := judge.ContestPages("Upcoming Codeforces contests", "https://codeforces.com/contests", 0x50e6ac,
+		s.GetContests())
+	return utils.SendPages(s.discord, m.ChannelID, pages)
 }
 
 // Updates Service.contests with upcoming contests from the Codeforces API.
@@ -151,12 +132,14 @@ func (s *contestService) checkContestsFinish(contests []*contest) {
 	}
 }
 
-func (s *contestService) getContests() []*contest {
+func (s *contestService) GetContests() []judge.Contest {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	res := make([]*contest, len(s.contests))
-	copy(res, s.contests)
+	res := make([]judge.Contest, len(s.contests))
+	for i, c := range s.contests {
+		res[i] = c.toJudge()
+	}
 	return res
 }
 
@@ -248,8 +231,9 @@ func filterContests(contests []*contest, f func(*contest) bool) (result []*conte
 	return result
 }
 
+// Notifies the listeners concurrently, as they may keep running until ratings are updated.
 func (s *contestService) onContestFinish(c contest) {
 	for _, l := range s.listeners {
-		l.onContestFinish(&c)
+		go l.onContestFinish(&c)
 	}
 }
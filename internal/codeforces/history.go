@@ -0,0 +1,391 @@
+package codeforces
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+	"github.com/yuqzii/konkurransetilsynet/internal/utils"
+)
+
+type HistoryRepository interface {
+	// Stores the rating changes, ignoring the ones that are already stored.
+	AddRatingChanges(ctx context.Context, changes []RatingChange) error
+	GetRatingChanges(ctx context.Context, handles []string) ([]RatingChange, error)
+	// Stores the solves, keeping the earliest time of problems that are already stored.
+	AddSolves(ctx context.Context, solves []Solve) error
+	// Returns the number of problems each handle solved between from and to.
+	CountSolves(ctx context.Context, handles []string, from, to time.Time) (map[string]int, error)
+	HasSolves(ctx context.Context, handle string) (bool, error)
+
+	StartSeason(ctx context.Context, season Season) error
+	// Returns the seasons of the guild, oldest first.
+	GetSeasons(ctx context.Context, guildID string) ([]Season, error)
+}
+
+type RatingChange struct {
+	Handle    string
+	ContestID int
+	OldRating int
+	NewRating int
+	Time      time.Time
+}
+
+// The first accepted submission of a user to a problem.
+type Solve struct {
+	Handle string
+	// The contest ID and index, e.g. 1850A
+	ProblemID string
+	Time      time.Time
+}
+
+// A guild defined leaderboard period, lasting until the next season starts.
+type Season struct {
+	GuildID string
+	Name    string
+	Start   time.Time
+}
+
+// A leaderboard metric. value returns false for users who should not be on the leaderboard.
+type leaderboardMetric struct {
+	name        string
+	description string
+	value       func(changes []RatingChange, solved int, from, to time.Time) (int, bool)
+}
+
+var leaderboardMetrics = []leaderboardMetric{
+	{"rating", "current rating", func(changes []RatingChange, _ int, _, _ time.Time) (int, bool) {
+		if len(changes) == 0 {
+			return 0, false
+		}
+		return changes[len(changes)-1].NewRating, true
+	}},
+	{"max", "max rating", func(changes []RatingChange, _ int, from, to time.Time) (int, bool) {
+		best, ok := 0, false
+		for _, c := range inPeriod(changes, from, to) {
+			best, ok = max(best, c.NewRating), true
+		}
+		return best, ok
+	}},
+	{"gained", "rating gained", func(changes []RatingChange, _ int, from, to time.Time) (int, bool) {
+		period := inPeriod(changes, from, to)
+		gained := 0
+		for _, c := range period {
+			gained += c.NewRating - c.OldRating
+		}
+		return gained, len(period) > 0
+	}},
+	{"contests", "rated contests", func(changes []RatingChange, _ int, from, to time.Time) (int, bool) {
+		played := len(inPeriod(changes, from, to))
+		return played, played > 0
+	}},
+	{"solved", "problems solved", func(_ []RatingChange, solved int, _, _ time.Time) (int, bool) {
+		return solved, solved > 0
+	}},
+}
+
+// Returns the changes between from and to. The changes are sorted by time.
+func inPeriod(changes []RatingChange, from, to time.Time) []RatingChange {
+	start, _ := slices.BinarySearchFunc(changes, from, func(c RatingChange, t time.Time) int {
+		return c.Time.Compare(t)
+	})
+	end, _ := slices.BinarySearchFunc(changes, to, func(c RatingChange, t time.Time) int {
+		return c.Time.Compare(t)
+	})
+	return changes[start:end]
+}
+
+type standing struct {
+	discordID string
+	handle    string
+	value     int
+}
+
+// Computes the metric for every account, sorted by value descending. changes are keyed by handle
+// and sorted by time, and solved holds the number of problems solved in the period by handle.
+func computeStandings(accounts []judge.Account, changes map[string][]RatingChange, solved map[string]int,
+	metric *leaderboardMetric, from, to time.Time) []standing {
+
+	var result []standing
+	for _, a := range accounts {
+		value, ok := metric.value(changes[a.Handle], solved[a.Handle], from, to)
+		if ok {
+			result = append(result, standing{discordID: a.DiscordID, handle: a.Handle, value: value})
+		}
+	}
+	slices.SortStableFunc(result, func(a, b standing) int {
+		if a.value != b.value {
+			return b.value - a.value
+		}
+		return strings.Compare(strings.ToLower(a.handle), strings.ToLower(b.handle))
+	})
+	return result
+}
+
+// Stores the rating history and solved problems of connected users, and shows leaderboards
+// computed from them.
+type historyService struct {
+	discord *discordgo.Session
+	db      Repository
+	client  api
+
+	initialSubmissionCount uint16
+	submissionCount        uint16
+}
+
+func newHistoryService(discord *discordgo.Session, db Repository, client api) *historyService {
+	const (
+		defaultInitialSubmissionCount uint16 = 10000
+		defaultSubmissionCount        uint16 = 200
+	)
+
+	return &historyService{
+		discord:                discord,
+		db:                     db,
+		client:                 client,
+		initialSubmissionCount: defaultInitialSubmissionCount,
+		submissionCount:        defaultSubmissionCount,
+	}
+}
+
+// Starts a goroutine that stores the history of every linked handle immediately, and then every
+// interval.
+func (s *historyService) StartHistorySync(interval time.Duration) {
+	go func() {
+		for {
+			if err := s.sync(context.Background()); err != nil {
+				log.Println("Failed to sync Codeforces history:", err)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func (s *historyService) sync(ctx context.Context) error {
+	accounts, err := s.db.GetLinkedAccounts(ctx, judge.Codeforces)
+	if err != nil {
+		return fmt.Errorf("getting linked Codeforces accounts: %w", err)
+	}
+
+	var errs []error
+	for _, a := range accounts {
+		if err := s.syncHandle(ctx, a.Handle); err != nil {
+			errs = append(errs, fmt.Errorf("syncing history of %s: %w", a.Handle, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *historyService) syncHandle(ctx context.Context, handle string) error {
+	history, err := s.client.getRatingHistory(ctx, handle)
+	if err != nil {
+		return fmt.Errorf("getting rating history: %w", err)
+	}
+	changes := make([]RatingChange, len(history))
+	for i, c := range history {
+		changes[i] = RatingChange{
+			Handle:    handle,
+			ContestID: c.ContestID,
+			OldRating: c.OldRating,
+			NewRating: c.NewRating,
+			Time:      time.Unix(c.RatingUpdateTimeSeconds, 0),
+		}
+	}
+	if err := s.db.AddRatingChanges(ctx, changes); err != nil {
+		return err
+	}
+
+	// Get every submission the first time, and only recent ones after that
+	hasSolves, err := s.db.HasSolves(ctx, handle)
+	if err != nil {
+		return err
+	}
+	count := s.submissionCount
+	if !hasSolves {
+		count = s.initialSubmissionCount
+	}
+	subs, err := s.client.getSubmissions(ctx, handle, count)
+	if err != nil {
+		return fmt.Errorf("getting submissions: %w", err)
+	}
+	return s.db.AddSolves(ctx, firstSolves(handle, subs))
+}
+
+// Returns the first accepted submission to every problem.
+func firstSolves(handle string, subs []submission) []Solve {
+	first := make(map[string]time.Time)
+	for _, sub := range subs {
+		if sub.Verdict != "OK" || sub.Problem.ContestID == 0 {
+			continue
+		}
+		id := fmt.Sprintf("%d%s", sub.Problem.ContestID, sub.Problem.Index)
+		t := time.Unix(sub.CreationTimeSeconds, 0)
+		if prev, ok := first[id]; !ok || t.Before(prev) {
+			first[id] = t
+		}
+	}
+
+	solves := make([]Solve, 0, len(first))
+	for id, t := range first {
+		solves = append(solves, Solve{Handle: handle, ProblemID: id, Time: t})
+	}
+	slices.SortFunc(solves, func(a, b Solve) int {
+		return cmp.Or(a.Time.Compare(b.Time), strings.Compare(a.ProblemID, b.ProblemID))
+	})
+	return solves
+}
+
+// leaderboard [all|season|month] [metric] [png]
+func (s *historyService) leaderboardCommand(args []string, m *discordgo.MessageCreate) error {
+	withImage := len(args) >= 3 && strings.EqualFold(args[len(args)-1], "png")
+	if withImage {
+		args = args[:len(args)-1]
+	}
+
+	period, metricName := "all", "rating"
+	if len(args) >= 3 {
+		period = strings.ToLower(args[2])
+	}
+	if len(args) >= 4 {
+		metricName = strings.ToLower(args[3])
+	}
+	idx := slices.IndexFunc(leaderboardMetrics, func(metric leaderboardMetric) bool {
+		return metric.name == metricName
+	})
+	if idx == -1 || len(args) > 4 {
+		return s.sendLeaderboardUsage(m.ChannelID)
+	}
+	metric := &leaderboardMetrics[idx]
+
+	now := time.Now()
+	var (
+		from, to = time.Time{}, now
+		title    string
+	)
+	switch period {
+	case "all":
+		title = "all-time"
+	case "month":
+		year, month, _ := now.UTC().Date()
+		from = time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		title = now.UTC().Format("January 2006")
+	case "season":
+		seasons, err := s.db.GetSeasons(context.TODO(), m.GuildID)
+		if err != nil {
+			return fmt.Errorf("getting seasons: %w", err)
+		}
+		if len(seasons) == 0 {
+			_, err := s.discord.ChannelMessageSend(m.ChannelID,
+				"No season has started in this server. Administrators can start one with `!cf season start [name]`.")
+			return err
+		}
+		current := seasons[len(seasons)-1]
+		from = current.Start
+		title = "season " + current.Name
+	default:
+		return s.sendLeaderboardUsage(m.ChannelID)
+	}
+
+	accounts, err := judge.GetLinkedAccountsInGuild(context.TODO(), s.db, s.discord, judge.Codeforces, m.GuildID)
+	if err != nil {
+		return err
+	}
+	handles := make([]string, len(accounts))
+	for i, a := range accounts {
+		handles[i] = a.Handle
+	}
+
+	stored, err := s.db.GetRatingChanges(context.TODO(), handles)
+	if err != nil {
+		return fmt.Errorf("getting rating changes: %w", err)
+	}
+	changes := make(map[string][]RatingChange)
+	for _, c := range stored {
+		changes[c.Handle] = append(changes[c.Handle], c)
+	}
+	for _, handleChanges := range changes {
+		slices.SortFunc(handleChanges, func(a, b RatingChange) int {
+			return a.Time.Compare(b.Time)
+		})
+	}
+	solved, err := s.db.CountSolves(context.TODO(), handles, from, to)
+	if err != nil {
+		return fmt.Errorf("counting solves: %w", err)
+	}
+
+	standings := computeStandings(accounts, changes, solved, metric, from, to)
+	lb := judge.Leaderboard{
+		Title:     fmt.Sprintf("Codeforces %s leaderboard, %s", metric.description, title),
+		ValueName: metric.description,
+		Entries:   make([]judge.LeaderboardEntry, len(standings)),
+	}
+	for i, st := range standings {
+		lb.Entries[i] = judge.LeaderboardEntry{
+			DiscordID: st.discordID,
+			Handle:    st.handle,
+			Value:     strconv.Itoa(st.value),
+		}
+		// Color by current rating whatever the metric is
+		if handleChanges := changes[st.handle]; len(handleChanges) > 0 {
+			lb.Entries[i].Color = rankColor(handleChanges[len(handleChanges)-1].NewRating)
+		}
+	}
+	return judge.SendLeaderboard(s.discord, m.GuildID, m.ChannelID, &lb, withImage)
+}
+
+func (s *historyService) sendLeaderboardUsage(channelID string) error {
+	metrics := make([]string, len(leaderboardMetrics))
+	for i, metric := range leaderboardMetrics {
+		metrics[i] = fmt.Sprintf("`%s` (%s)", metric.name, metric.description)
+	}
+	msg := "Usage: `!cf leaderboard [all|season|month (optional)] [metric (optional)] [png (optional)]`\n" +
+		"Metrics: " + strings.Join(metrics, ", ") + "\n" +
+		"Add `png` to attach the leaderboard as an image."
+	_, err := s.discord.ChannelMessageSend(channelID, msg)
+	return err
+}
+
+// season | season start <name>
+func (s *historyService) seasonCommand(args []string, m *discordgo.MessageCreate) error {
+	if len(args) == 2 {
+		seasons, err := s.db.GetSeasons(context.TODO(), m.GuildID)
+		if err != nil {
+			return fmt.Errorf("getting seasons: %w", err)
+		}
+		msg := "No season has started in this server."
+		if len(seasons) > 0 {
+			current := seasons[len(seasons)-1]
+			msg = fmt.Sprintf("Season **%s** started <t:%d:R>.", current.Name, current.Start.Unix())
+		}
+		_, err = s.discord.ChannelMessageSend(m.ChannelID, msg)
+		return err
+	}
+
+	if args[2] != "start" || len(args) < 4 {
+		_, err := s.discord.ChannelMessageSend(m.ChannelID, "Usage: `!cf season` or `!cf season start [name]`")
+		return err
+	}
+
+	isAdmin, err := utils.IsAdmin(s.discord, m.Author.ID, m.ChannelID)
+	if err != nil {
+		return fmt.Errorf("checking permissions of %s: %w", m.Author.ID, err)
+	}
+	if !isAdmin {
+		return utils.NoPermission(s.discord, m)
+	}
+
+	season := Season{GuildID: m.GuildID, Name: strings.Join(args[3:], " "), Start: time.Now()}
+	if err := s.db.StartSeason(context.TODO(), season); err != nil {
+		return fmt.Errorf("starting season %s: %w", season.Name, err)
+	}
+	_, err = s.discord.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Season **%s** has started!", season.Name))
+	return err
+}
@@ -0,0 +1,82 @@
+package codeforces
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+)
+
+func Test_ComputeStandings(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 18, 0, 0, 0, time.UTC) }
+	accounts := []judge.Account{
+		{DiscordID: "1", Handle: "alice"},
+		{DiscordID: "2", Handle: "bob"},
+		{DiscordID: "3", Handle: "carol"},
+	}
+	changes := map[string][]RatingChange{
+		"alice": {
+			{Handle: "alice", OldRating: 0, NewRating: 1400, Time: day(1)},
+			{Handle: "alice", OldRating: 1400, NewRating: 1550, Time: day(10)},
+			{Handle: "alice", OldRating: 1550, NewRating: 1500, Time: day(20)},
+		},
+		"bob": {
+			{Handle: "bob", OldRating: 0, NewRating: 1700, Time: day(2)},
+			{Handle: "bob", OldRating: 1700, NewRating: 1600, Time: day(12)},
+		},
+	}
+	solved := map[string]int{"alice": 3, "carol": 7}
+	from, to := day(5), day(25)
+
+	tests := []struct {
+		metric   string
+		expected []standing
+	}{
+		{"rating", []standing{{"1", "alice", 1500}, {"2", "bob", 1600}}},
+		{"max", []standing{{"1", "alice", 1550}, {"2", "bob", 1600}}},
+		{"gained", []standing{{"1", "alice", 100}, {"2", "bob", -100}}},
+		{"contests", []standing{{"1", "alice", 2}, {"2", "bob", 1}}},
+		{"solved", []standing{{"3", "carol", 7}, {"1", "alice", 3}}},
+	}
+
+	for _, test := range tests {
+		idx := slices.IndexFunc(leaderboardMetrics, func(m leaderboardMetric) bool { return m.name == test.metric })
+		result := computeStandings(accounts, changes, solved, &leaderboardMetrics[idx], from, to)
+
+		// Sorted by value descending
+		expected := slices.Clone(test.expected)
+		slices.SortStableFunc(expected, func(a, b standing) int { return b.value - a.value })
+		if !slices.Equal(result, expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.metric, expected, result)
+		}
+	}
+}
+
+func Test_FirstSolves(t *testing.T) {
+	at := func(minutes int) time.Time {
+		return time.Date(2026, 3, 1, 18, minutes, 0, 0, time.UTC)
+	}
+	subs := []submission{
+		newSubmission(1850, "A", "OK", at(30)),
+		newSubmission(1850, "B", "WRONG_ANSWER", at(20)),
+		newSubmission(1850, "A", "OK", at(10)),
+		newSubmission(1849, "C", "OK", at(5)),
+		// Problems without a contest are skipped
+		newSubmission(0, "A", "OK", at(1)),
+	}
+
+	expected := []Solve{
+		{Handle: "alice", ProblemID: "1849C", Time: at(5)},
+		{Handle: "alice", ProblemID: "1850A", Time: at(10)},
+	}
+	result := firstSolves("alice", subs)
+	if len(result) != len(expected) {
+		t.Fatalf("expected %+v, got %+v", expected, result)
+	}
+	for i := range expected {
+		if result[i].ProblemID != expected[i].ProblemID || !result[i].Time.Equal(expected[i].Time) {
+			t.Errorf("solve %d: expected %+v, got %+v", i, expected[i], result[i])
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package codeforces
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+)
+
+// The client implements judge.Judge on top of the Codeforces specific API methods.
+
+func (c *client) Platform() string {
+	return judge.Codeforces
+}
+
+func (c *client) Name() string {
+	return "Codeforces"
+}
+
+func (c *client) UpcomingContests(ctx context.Context) ([]judge.Contest, error) {
+	contests, err := c.getContests(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	upcoming := filterUpcoming(contests)
+	result := make([]judge.Contest, len(upcoming))
+	for i, contest := range upcoming {
+		result[i] = contest.toJudge()
+	}
+	return result, nil
+}
+
+func (c *client) UserRating(ctx context.Context, handle string) (*judge.Rating, error) {
+	rating, err := c.getRating(ctx, handle)
+	if err != nil {
+		return nil, err
+	}
+	return &judge.Rating{
+		Handle:    rating.Handle,
+		Rating:    rating.NewRating,
+		OldRating: rating.OldRating,
+	}, nil
+}
+
+func (c *client) RankColor(rating int) int {
+	return rankColor(rating)
+}
+
+func (c *client) Submissions(ctx context.Context, handle string, count int) ([]judge.Submission, error) {
+	subs, err := c.getSubmissions(ctx, handle, uint16(count))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]judge.Submission, len(subs))
+	for i, sub := range subs {
+		result[i] = judge.Submission{
+			ID:          strconv.Itoa(sub.ID),
+			ProblemID:   fmt.Sprintf("%d%s", sub.Problem.ContestID, sub.Problem.Index),
+			ProblemName: sub.Problem.Name,
+			Time:        time.Unix(sub.CreationTimeSeconds, 0),
+			Accepted:    sub.Verdict == "OK",
+		}
+	}
+	return result, nil
+}
+
+func (c *client) UserExists(ctx context.Context, handle string) (bool, error) {
+	return c.checkUserExistence(ctx, handle)
+}
+
+func (c *contest) toJudge() judge.Contest {
+	url := c.WebsiteURL
+	if url == "" {
+		url = fmt.Sprintf("https://codeforces.com/contests/%d", c.ID)
+	}
+	return judge.Contest{
+		Platform: judge.Codeforces,
+		ID:       strconv.FormatUint(uint64(c.ID), 10),
+		Name:     c.Name,
+		URL:      url,
+		Start:    time.Unix(int64(c.StartTimeSeconds), 0),
+		Duration: time.Duration(c.DurationSeconds) * time.Second,
+	}
+}
@@ -0,0 +1,351 @@
+package codeforces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+	"github.com/yuqzii/konkurransetilsynet/internal/utils"
+)
+
+// A contest of Codeforces problems hosted in a Discord channel, either a mashup or a team training.
+type LiveContest struct {
+	ID        int
+	GuildID   string
+	ChannelID string
+	CreatorID string
+	Name      string
+	Problems  []MashupProblem
+	Start     time.Time
+	Duration  time.Duration
+	// The message the live scoreboard is edited in, empty before the contest starts
+	ScoreboardMessageID string
+	// The final scoreboard, only set when the contest has finished
+	Results []ScoreboardRow
+}
+
+func (c *LiveContest) End() time.Time {
+	return c.Start.Add(c.Duration)
+}
+
+// The parts of a live contest that differ between mashups and team trainings.
+type liveContestSource interface {
+	// Names the contest in messages.
+	title() string
+	// Names the kind of contest in logs, e.g. "mashup".
+	kind() string
+	// Returns the Discord IDs of the users to ping when the contest starts.
+	mentions(ctx context.Context) ([]string, error)
+	// Returns the rows of the scoreboard and whose submissions count for them.
+	entrants(ctx context.Context) ([]entrant, error)
+	setScoreboardMessage(ctx context.Context, messageID string) error
+	// Stores the final scoreboard and marks the contest as finished.
+	archive(ctx context.Context, rows []ScoreboardRow) error
+	// Stops tracking the contest as active in its guild. Called with the runner locked.
+	deactivate()
+}
+
+// A row of a live scoreboard, with the Discord users whose submissions count for it.
+type entrant struct {
+	// Shown on the scoreboard, empty for single users who are shown by their handle
+	name    string
+	members []string
+}
+
+// Runs the lifecycle shared by live contests: waiting for the start, pinging the participants,
+// keeping the scoreboard message updated and archiving the results. Embedded by the services
+// hosting live contests.
+type liveContestRunner struct {
+	discord *discordgo.Session
+	db      Repository
+	client  api
+
+	pollInterval    time.Duration
+	submissionCount uint16
+
+	// Guards the running contests and the state of the embedding service
+	mu sync.Mutex
+}
+
+// An invalid command argument given by the user.
+type inputError struct {
+	msg string
+}
+
+func (e *inputError) Error() string {
+	return e.msg
+}
+
+// Parses the duration of a live contest and the optional delay before it starts, and returns
+// the start time and duration. Returns an *inputError if either is invalid.
+func parseSchedule(durationArg, delayArg, durationExample string) (time.Time, time.Duration, error) {
+	const (
+		defaultStartDelay time.Duration = 10 * time.Minute
+		maxDuration       time.Duration = 24 * time.Hour
+		maxStartDelay     time.Duration = 7 * 24 * time.Hour
+	)
+
+	duration, err := time.ParseDuration(durationArg)
+	if err != nil || duration <= 0 || duration > maxDuration {
+		return time.Time{}, 0, &inputError{
+			fmt.Sprintf("The duration must be between 0 and %s, e.g. `%s`.", maxDuration, durationExample)}
+	}
+
+	startDelay := defaultStartDelay
+	if delayArg != "" {
+		startDelay, err = time.ParseDuration(delayArg)
+		if err != nil || startDelay < 0 || startDelay > maxStartDelay {
+			return time.Time{}, 0, &inputError{
+				fmt.Sprintf("The start delay must be between 0 and %s, e.g. `30m`.", maxStartDelay)}
+		}
+	}
+
+	return time.Now().Add(startDelay).Truncate(time.Minute), duration, nil
+}
+
+// Waits for the contest to start, keeps the scoreboard updated while it runs, and archives the
+// results when it ends. The contest ends early if every entrant has solved every problem.
+func (r *liveContestRunner) run(c *LiveContest, src liveContestSource) {
+	time.Sleep(time.Until(c.Start))
+
+	r.mu.Lock()
+	// Resumed contests may already have started, or even ended while the bot was offline
+	needsStart := c.ScoreboardMessageID == "" && time.Now().Before(c.End())
+	r.mu.Unlock()
+	if needsStart {
+		if err := r.start(c, src); err != nil {
+			log.Printf("Failed to start %s %s: %s", src.kind(), c.Name, err)
+		}
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), c.End())
+	defer cancel()
+	judge.Poll(ctx, r.pollInterval, func(ctx context.Context) (bool, error) {
+		rows, err := r.updateScoreboard(ctx, c, src)
+		if err != nil {
+			return false, fmt.Errorf("updating scoreboard of %s %s: %w", src.kind(), c.Name, err)
+		}
+		return len(rows) > 0 && !slices.ContainsFunc(rows, func(row ScoreboardRow) bool {
+			return row.Solved < len(c.Problems)
+		}), nil
+	}, nil)
+
+	if err := r.finish(c, src); err != nil {
+		log.Printf("Failed to finish %s %s: %s", src.kind(), c.Name, err)
+	}
+}
+
+// Pings the participants with the problems and sends the scoreboard message.
+func (r *liveContestRunner) start(c *LiveContest, src liveContestSource) error {
+	mentions, err := src.mentions(context.TODO())
+	if err != nil {
+		return fmt.Errorf("getting participants: %w", err)
+	}
+
+	var sb strings.Builder
+	for _, id := range mentions {
+		fmt.Fprintf(&sb, "<@%s> ", id)
+	}
+	fmt.Fprintf(&sb, "\n## %s has started!\nEnds <t:%d:R>", src.title(), c.End().Unix())
+	for i, p := range c.Problems {
+		fmt.Fprintf(&sb, "\n%s. [%s](%s)", problemLetter(i), p.Name, p.url())
+	}
+	msgData := discordgo.MessageSend{
+		Content: sb.String(),
+		// Avoid embedding every problem
+		Flags: discordgo.MessageFlagsSuppressEmbeds,
+	}
+	if err := utils.SendSplitMessage(r.discord, c.ChannelID, &msgData); err != nil {
+		return fmt.Errorf("sending start message: %w", err)
+	}
+
+	msg, err := r.discord.ChannelMessageSend(c.ChannelID, formatScoreboard(c.Problems, nil))
+	if err != nil {
+		return fmt.Errorf("sending scoreboard message: %w", err)
+	}
+	if err := src.setScoreboardMessage(context.TODO(), msg.ID); err != nil {
+		return fmt.Errorf("storing scoreboard message: %w", err)
+	}
+
+	r.mu.Lock()
+	c.ScoreboardMessageID = msg.ID
+	r.mu.Unlock()
+	return nil
+}
+
+// Computes the current scoreboard and edits the scoreboard message. Members whose submissions
+// could not be fetched are left out, and the returned rows are valid even if an error is returned.
+func (r *liveContestRunner) updateScoreboard(ctx context.Context, c *LiveContest,
+	src liveContestSource) ([]ScoreboardRow, error) {
+
+	rows, err := r.scoreboard(ctx, c, src)
+	if editErr := r.editScoreboard(c, src, rows); editErr != nil {
+		err = errors.Join(err, editErr)
+	}
+	return rows, err
+}
+
+// Shows the rows in the scoreboard message, if it has been sent.
+func (r *liveContestRunner) editScoreboard(c *LiveContest, src liveContestSource, rows []ScoreboardRow) error {
+	r.mu.Lock()
+	messageID := c.ScoreboardMessageID
+	r.mu.Unlock()
+	if messageID == "" {
+		return nil
+	}
+
+	content := fmt.Sprintf("**%s** scoreboard, updated <t:%d:R>\n%s",
+		src.title(), time.Now().Unix(), formatScoreboard(c.Problems, rows))
+	// Messages cannot be split when editing, so show what fits
+	content = utils.SplitMessage(content, utils.MaxMessageLength)[0]
+	if _, err := r.discord.ChannelMessageEdit(c.ChannelID, messageID, content); err != nil {
+		return fmt.Errorf("editing scoreboard message: %w", err)
+	}
+	return nil
+}
+
+// Computes the current scoreboard. A failed fetch for one member does not stop the others, the
+// member is left out and the errors are returned along with the rows.
+func (r *liveContestRunner) scoreboard(ctx context.Context, c *LiveContest,
+	src liveContestSource) ([]ScoreboardRow, error) {
+
+	entrants, err := src.entrants(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	handles := make(map[string]string)
+	submissions := make(map[string][]submission)
+	var errs []error
+	for _, e := range entrants {
+		for _, discID := range e.members {
+			handle, err := r.db.GetLinkedAccount(ctx, judge.Codeforces, discID)
+			if errors.Is(err, judge.ErrAccountNotLinked) {
+				// Unlinked during the contest
+				continue
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("getting handle of %s: %w", discID, err))
+				continue
+			}
+
+			subs, err := r.client.getSubmissions(ctx, handle, r.submissionCount)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("getting submissions of %s: %w", handle, err))
+				continue
+			}
+			handles[discID] = handle
+			submissions[handle] = subs
+		}
+	}
+
+	participants, entrantSubs := entrantParticipants(entrants, handles, submissions)
+	return computeScoreboard(c.Problems, c.Start, c.End(), participants, entrantSubs), errors.Join(errs...)
+}
+
+// Turns the entrants into scoreboard participants with the submissions of all their members.
+// handles maps the Discord IDs of the members to their handles, and members without a handle are
+// skipped. Unnamed entrants are single users shown by their handle, and are left out if they have
+// no handle.
+func entrantParticipants(entrants []entrant, handles map[string]string,
+	submissions map[string][]submission) ([]participant, map[string][]submission) {
+
+	var participants []participant
+	entrantSubs := make(map[string][]submission, len(entrants))
+	for _, e := range entrants {
+		p := participant{handle: e.name}
+		if e.name == "" {
+			handle, ok := handles[e.members[0]]
+			if !ok {
+				continue
+			}
+			p = participant{discordID: e.members[0], handle: handle}
+		}
+
+		for _, discID := range e.members {
+			if handle, ok := handles[discID]; ok {
+				entrantSubs[p.handle] = append(entrantSubs[p.handle], submissions[handle]...)
+			}
+		}
+		participants = append(participants, p)
+	}
+	return participants, entrantSubs
+}
+
+// Archives the final scoreboard and stops tracking the contest. Fetching the final scoreboard is
+// retried a few times, after which the contest is archived with the members that could be
+// fetched, so that a failing member cannot keep the guild from starting new contests.
+func (r *liveContestRunner) finish(c *LiveContest, src liveContestSource) error {
+	const finishAttempts int = 3
+
+	var rows []ScoreboardRow
+	var err error
+	for range finishAttempts {
+		// Give the judge time to finish judging submissions made right before the end
+		time.Sleep(r.pollInterval)
+
+		rows, err = r.scoreboard(context.TODO(), c, src)
+		if err == nil {
+			break
+		}
+		log.Printf("Failed to get final scoreboard of %s %s: %s", src.kind(), c.Name, err)
+	}
+
+	if err := r.editScoreboard(c, src, rows); err != nil {
+		log.Printf("Failed to edit final scoreboard of %s %s: %s", src.kind(), c.Name, err)
+	}
+
+	r.mu.Lock()
+	c.Results = rows
+	src.deactivate()
+	r.mu.Unlock()
+
+	if err := src.archive(context.TODO(), rows); err != nil {
+		return fmt.Errorf("archiving results: %w", err)
+	}
+
+	msg := fmt.Sprintf("**%s** has ended!", src.title())
+	if len(rows) > 0 && rows[0].Solved > 0 {
+		winner := fmt.Sprintf("**%s**", rows[0].Handle)
+		if rows[0].DiscordID != "" {
+			winner = fmt.Sprintf("<@%s>", rows[0].DiscordID)
+		}
+		msg += fmt.Sprintf(" Congratulations to %s for winning with %d solved.", winner, rows[0].Solved)
+	}
+	return utils.SendSplitMessage(r.discord, c.ChannelID, &discordgo.MessageSend{
+		Content: msg + "\n" + formatScoreboard(c.Problems, rows),
+	})
+}
+
+// Sends the problems and final scoreboard of a finished contest, followed by the names of other
+// recent contests.
+func (r *liveContestRunner) sendResults(channelID string, c *LiveContest, recentLabel string,
+	recent []string) error {
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## Results of %s\n<t:%d:f>, %s\n", c.Name, c.Start.Unix(), c.Duration)
+	for i, p := range c.Problems {
+		fmt.Fprintf(&sb, "%s. [%s](%s)\n", problemLetter(i), p.Name, p.url())
+	}
+	sb.WriteString(formatScoreboard(c.Problems, c.Results))
+
+	if len(recent) > 1 {
+		fmt.Fprintf(&sb, "\n%s:", recentLabel)
+		for _, name := range recent {
+			fmt.Fprintf(&sb, " `%s`", name)
+		}
+	}
+
+	msgData := discordgo.MessageSend{
+		Content: sb.String(),
+		Flags:   discordgo.MessageFlagsSuppressEmbeds,
+	}
+	return utils.SendSplitMessage(r.discord, channelID, &msgData)
+}
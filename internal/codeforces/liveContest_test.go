@@ -0,0 +1,83 @@
+package codeforces
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_EntrantParticipants(t *testing.T) {
+	start := time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC)
+	end := start.Add(5 * time.Hour)
+	problems := []MashupProblem{{ContestID: 104000, Index: "A"}, {ContestID: 104000, Index: "B"}}
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	teams := []Team{
+		{ID: 1, Name: "Rubber_Ducks", Members: []string{"1", "2", "3"}},
+		{ID: 2, Name: "Solo", Members: []string{"4"}},
+	}
+	// Member 3 has unlinked their account
+	handles := map[string]string{"1": "alice", "2": "bob", "4": "dave"}
+	submissions := map[string][]submission{
+		"alice": {newSubmission(104000, "A", "WRONG_ANSWER", at(15))},
+		"bob": {
+			newSubmission(104000, "B", "OK", at(90)),
+			newSubmission(104000, "A", "OK", at(20)),
+		},
+		"carol": {newSubmission(104000, "A", "OK", at(1))},
+		"dave":  {newSubmission(104000, "A", "OK", at(10))},
+	}
+
+	entrants := make([]entrant, len(teams))
+	for i, team := range teams {
+		entrants[i] = entrant{name: team.Name, members: team.Members}
+	}
+	participants, entrantSubs := entrantParticipants(entrants, handles, submissions)
+	rows := computeScoreboard(problems, start, end, participants, entrantSubs)
+
+	expected := []ScoreboardRow{
+		{Rank: 1, Handle: "Rubber_Ducks", Solved: 2, Penalty: 20 + penaltyMinutes + 90,
+			Problems: []ProblemResult{{Solved: true, Rejected: 1, SolveMinute: 20}, {Solved: true, SolveMinute: 90}}},
+		{Rank: 2, Handle: "Solo", Solved: 1, Penalty: 10,
+			Problems: []ProblemResult{{Solved: true, SolveMinute: 10}, {}}},
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("expected %d rows, got %+v", len(expected), rows)
+	}
+	for i, exp := range expected {
+		got := rows[i]
+		if got.Rank != exp.Rank || got.Handle != exp.Handle || got.Solved != exp.Solved || got.Penalty != exp.Penalty {
+			t.Errorf("row %d: expected %+v, got %+v", i, exp, got)
+			continue
+		}
+		for j := range exp.Problems {
+			if got.Problems[j] != exp.Problems[j] {
+				t.Errorf("row %d problem %s: expected %+v, got %+v", i, problemLetter(j), exp.Problems[j], got.Problems[j])
+			}
+		}
+	}
+}
+
+func Test_EntrantParticipants_Single(t *testing.T) {
+	// User 2 has unlinked their account
+	entrants := []entrant{{members: []string{"1"}}, {members: []string{"2"}}, {members: []string{"3"}}}
+	handles := map[string]string{"1": "alice", "3": "carol"}
+	submissions := map[string][]submission{
+		"alice": {newSubmission(104000, "A", "OK", time.Unix(0, 0))},
+		"carol": {},
+	}
+
+	participants, entrantSubs := entrantParticipants(entrants, handles, submissions)
+
+	expected := []participant{{discordID: "1", handle: "alice"}, {discordID: "3", handle: "carol"}}
+	if len(participants) != len(expected) {
+		t.Fatalf("expected participants %+v, got %+v", expected, participants)
+	}
+	for i := range expected {
+		if participants[i] != expected[i] {
+			t.Errorf("participant %d: expected %+v, got %+v", i, expected[i], participants[i])
+		}
+	}
+	if len(entrantSubs["alice"]) != 1 || len(entrantSubs["carol"]) != 0 {
+		t.Errorf("expected the submissions of alice only, got %+v", entrantSubs)
+	}
+}
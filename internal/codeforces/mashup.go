@@ -0,0 +1,402 @@
+package codeforces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+)
+
+type MashupRepository interface {
+	// Stores the mashup and its participants, and returns its ID.
+	CreateMashup(ctx context.Context, m Mashup) (int, error)
+	AddMashupParticipant(ctx context.Context, mashupID int, discID string) error
+	SetMashupScoreboardMessage(ctx context.Context, mashupID int, messageID string) error
+	// Stores the final scoreboard and marks the mashup as finished.
+	FinishMashup(ctx context.Context, mashupID int, results []ScoreboardRow) error
+	// Returns the mashups that have not finished.
+	GetActiveMashups(ctx context.Context) ([]Mashup, error)
+	// Returns the latest finished mashups of the guild, newest first.
+	GetFinishedMashups(ctx context.Context, guildID string, limit int) ([]Mashup, error)
+}
+
+// A virtual contest of Codeforces problems hosted in a Discord channel.
+type Mashup struct {
+	LiveContest
+	// Discord IDs of the participants
+	Participants []string
+}
+
+type MashupProblem struct {
+	ContestID int    `json:"contestId"`
+	Index     string `json:"index"`
+	Name      string `json:"name"`
+	Rating    uint16 `json:"rating,omitempty"`
+}
+
+// Contest IDs from this and up are gyms.
+const firstGymID int = 100000
+
+func (p *MashupProblem) url() string {
+	if p.ContestID >= firstGymID {
+		return fmt.Sprintf("https://codeforces.com/gym/%d/problem/%s", p.ContestID, p.Index)
+	}
+	return fmt.Sprintf("https://codeforces.com/problemset/problem/%d/%s", p.ContestID, p.Index)
+}
+
+type mashupService struct {
+	liveContestRunner
+
+	// Mashups that have not finished, by guild
+	active map[string]*Mashup
+}
+
+type mashupOption func(*mashupService)
+
+func newMashupService(discord *discordgo.Session, db Repository, client api, opts ...mashupOption) *mashupService {
+	const (
+		defaultPollInterval    time.Duration = 1 * time.Minute
+		defaultSubmissionCount uint16        = 100
+	)
+
+	s := &mashupService{
+		liveContestRunner: liveContestRunner{
+			discord:         discord,
+			db:              db,
+			client:          client,
+			pollInterval:    defaultPollInterval,
+			submissionCount: defaultSubmissionCount,
+		},
+		active: make(map[string]*Mashup),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Sets how often the submissions of the participants are checked during a mashup.
+func WithMashupPollInterval(interval time.Duration) mashupOption {
+	return func(s *mashupService) {
+		s.pollInterval = interval
+	}
+}
+
+// Restarts the mashups that had not finished when the bot stopped.
+func (s *mashupService) resume(ctx context.Context) error {
+	mashups, err := s.db.GetActiveMashups(ctx)
+	if err != nil {
+		return fmt.Errorf("getting active mashups: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range mashups {
+		s.active[m.GuildID] = &m
+		go s.run(&m.LiveContest, &mashupRun{s: s, m: &m})
+	}
+	return nil
+}
+
+func (s *mashupService) handleCommand(args []string, m *discordgo.MessageCreate) error {
+	if len(args) < 3 {
+		return s.sendUsage(m.ChannelID)
+	}
+
+	switch args[2] {
+	case "create":
+		return s.createCommand(args, m)
+	case "join":
+		return s.joinCommand(m)
+	case "results":
+		return s.resultsCommand(args, m)
+	default:
+		return s.sendUsage(m.ChannelID)
+	}
+}
+
+func (s *mashupService) sendUsage(channelID string) error {
+	msg := "Usage:\n" +
+		"`!cf mashup create [name] [duration, e.g. 2h] [problems] [starts in, e.g. 10m (optional)]`\n" +
+		"Problems are separated by commas, and are either a problem like `1850A`, " +
+		"a rating like `1400` or a rating range like `1200-1600`.\n" +
+		"`!cf mashup join`\n" +
+		"`!cf mashup results [name (optional)]`"
+	_, err := s.discord.ChannelMessageSend(channelID, msg)
+	return err
+}
+
+// create <name> <duration> <problems> [starts in]
+func (s *mashupService) createCommand(args []string, m *discordgo.MessageCreate) error {
+	if len(args) < 6 || len(args) > 7 {
+		return s.sendUsage(m.ChannelID)
+	}
+	name := args[3]
+
+	var delayArg string
+	if len(args) == 7 {
+		delayArg = args[6]
+	}
+	start, duration, err := parseSchedule(args[4], delayArg, "2h30m")
+	var inputErr *inputError
+	if errors.As(err, &inputErr) {
+		_, err := s.discord.ChannelMessageSend(m.ChannelID, inputErr.Error())
+		return err
+	}
+
+	s.mu.Lock()
+	existing, hasActive := s.active[m.GuildID]
+	s.mu.Unlock()
+	if hasActive {
+		_, err := s.discord.ChannelMessageSend(m.ChannelID,
+			fmt.Sprintf("**%s** has to finish before a new mashup can be created.", existing.Name))
+		return err
+	}
+
+	problems, err := s.resolveProblems(context.TODO(), args[5])
+	if errors.As(err, &inputErr) {
+		_, err := s.discord.ChannelMessageSend(m.ChannelID, inputErr.Error())
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("resolving mashup problems: %w", err)
+	}
+
+	mashup := Mashup{LiveContest: LiveContest{
+		GuildID:   m.GuildID,
+		ChannelID: m.ChannelID,
+		CreatorID: m.Author.ID,
+		Name:      name,
+		Problems:  problems,
+		Start:     start,
+		Duration:  duration,
+	}}
+	// The creator participates if they have linked an account
+	_, err = s.db.GetLinkedAccount(context.TODO(), judge.Codeforces, m.Author.ID)
+	if err == nil {
+		mashup.Participants = []string{m.Author.ID}
+	} else if !errors.Is(err, judge.ErrAccountNotLinked) {
+		return fmt.Errorf("getting handle of mashup creator: %w", err)
+	}
+
+	s.mu.Lock()
+	if _, ok := s.active[m.GuildID]; ok {
+		s.mu.Unlock()
+		return errors.New("another mashup was created at the same time")
+	}
+	mashup.ID, err = s.db.CreateMashup(context.TODO(), mashup)
+	if err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("storing mashup: %w", err)
+	}
+	s.active[m.GuildID] = &mashup
+	s.mu.Unlock()
+
+	msg := fmt.Sprintf("**%s** starts <t:%d:R> and lasts %s with %d problems. Join with `!cf mashup join`.",
+		mashup.Name, mashup.Start.Unix(), duration, len(problems))
+	if _, err := s.discord.ChannelMessageSend(m.ChannelID, msg); err != nil {
+		log.Printf("Failed to announce mashup %s: %s", mashup.Name, err)
+	}
+
+	go s.run(&mashup.LiveContest, &mashupRun{s: s, m: &mashup})
+	return nil
+}
+
+var (
+	problemIDRegex   = regexp.MustCompile(`^(\d+)([A-Z]\d?)$`)
+	ratingRangeRegex = regexp.MustCompile(`^(\d+)(?:-(\d+))?$`)
+)
+
+// Resolves a comma separated list of problems, ratings and rating ranges to distinct problems.
+// Ratings and rating ranges are replaced by a random problem in the range.
+func (s *mashupService) resolveProblems(ctx context.Context, spec string) ([]MashupProblem, error) {
+	const maxProblems int = 26
+
+	items := strings.Split(strings.ToUpper(spec), ",")
+	if len(items) > maxProblems {
+		return nil, &inputError{fmt.Sprintf("A mashup can have at most %d problems.", maxProblems)}
+	}
+
+	problemset, err := s.client.getProblems(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting problemset: %w", err)
+	}
+	problemset = filterProblems(problemset, func(p *problem) bool {
+		return p.Type == "PROGRAMMING"
+	})
+
+	var result []MashupProblem
+	used := func(p *problem) bool {
+		return slices.ContainsFunc(result, func(m MashupProblem) bool {
+			return m.ContestID == p.ContestID && m.Index == p.Index
+		})
+	}
+
+	for _, item := range items {
+		if match := problemIDRegex.FindStringSubmatch(item); match != nil {
+			contestID, _ := strconv.Atoi(match[1])
+			idx := slices.IndexFunc(problemset, func(p problem) bool {
+				return p.ContestID == contestID && p.Index == match[2]
+			})
+			if idx == -1 {
+				return nil, &inputError{fmt.Sprintf("Could not find problem `%s` on Codeforces.", item)}
+			}
+			if used(&problemset[idx]) {
+				return nil, &inputError{fmt.Sprintf("Problem `%s` is in the mashup twice.", item)}
+			}
+			result = append(result, toMashupProblem(&problemset[idx]))
+			continue
+		}
+
+		match := ratingRangeRegex.FindStringSubmatch(item)
+		if match == nil {
+			return nil, &inputError{fmt.Sprintf("`%s` is neither a problem, a rating nor a rating range.", item)}
+		}
+		low, _ := strconv.Atoi(match[1])
+		high := low
+		if match[2] != "" {
+			high, _ = strconv.Atoi(match[2])
+		}
+
+		candidates := filterProblems(problemset, func(p *problem) bool {
+			return int(p.Rating) >= low && int(p.Rating) <= high && !used(p)
+		})
+		if len(candidates) == 0 {
+			return nil, &inputError{fmt.Sprintf("There are no more problems rated `%s`.", item)}
+		}
+		result = append(result, toMashupProblem(&candidates[rand.Intn(len(candidates))]))
+	}
+
+	return result, nil
+}
+
+func toMashupProblem(p *problem) MashupProblem {
+	return MashupProblem{ContestID: p.ContestID, Index: p.Index, Name: p.Name, Rating: p.Rating}
+}
+
+func (s *mashupService) joinCommand(m *discordgo.MessageCreate) error {
+	s.mu.Lock()
+	mashup, ok := s.active[m.GuildID]
+	s.mu.Unlock()
+	if !ok {
+		_, err := s.discord.ChannelMessageSend(m.ChannelID,
+			"There is no mashup to join. Create one with `!cf mashup create`.")
+		return err
+	}
+
+	_, err := s.db.GetLinkedAccount(context.TODO(), judge.Codeforces, m.Author.ID)
+	if errors.Is(err, judge.ErrAccountNotLinked) {
+		_, err := s.discord.ChannelMessageSend(m.ChannelID,
+			"You have to connect your Codeforces account with `!cf authenticate` to join mashups.")
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("getting handle of %s: %w", m.Author.ID, err)
+	}
+
+	s.mu.Lock()
+	if slices.Contains(mashup.Participants, m.Author.ID) {
+		s.mu.Unlock()
+		_, err := s.discord.ChannelMessageSend(m.ChannelID,
+			fmt.Sprintf("You are already participating in **%s**.", mashup.Name))
+		return err
+	}
+	err = s.db.AddMashupParticipant(context.TODO(), mashup.ID, m.Author.ID)
+	if err == nil {
+		mashup.Participants = append(mashup.Participants, m.Author.ID)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("adding mashup participant: %w", err)
+	}
+
+	_, err = s.discord.ChannelMessageSend(m.ChannelID,
+		fmt.Sprintf("<@%s> joined **%s**.", m.Author.ID, mashup.Name))
+	return err
+}
+
+// Adapts a mashup to the live contest lifecycle.
+type mashupRun struct {
+	s *mashupService
+	m *Mashup
+}
+
+func (r *mashupRun) title() string {
+	return r.m.Name
+}
+
+func (r *mashupRun) kind() string {
+	return "mashup"
+}
+
+func (r *mashupRun) mentions(ctx context.Context) ([]string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return slices.Clone(r.m.Participants), nil
+}
+
+func (r *mashupRun) entrants(ctx context.Context) ([]entrant, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	entrants := make([]entrant, len(r.m.Participants))
+	for i, discID := range r.m.Participants {
+		entrants[i] = entrant{members: []string{discID}}
+	}
+	return entrants, nil
+}
+
+func (r *mashupRun) setScoreboardMessage(ctx context.Context, messageID string) error {
+	return r.s.db.SetMashupScoreboardMessage(ctx, r.m.ID, messageID)
+}
+
+func (r *mashupRun) archive(ctx context.Context, rows []ScoreboardRow) error {
+	return r.s.db.FinishMashup(ctx, r.m.ID, rows)
+}
+
+func (r *mashupRun) deactivate() {
+	delete(r.s.active, r.m.GuildID)
+}
+
+// results [name]
+func (s *mashupService) resultsCommand(args []string, m *discordgo.MessageCreate) error {
+	const listCount int = 10
+
+	mashups, err := s.db.GetFinishedMashups(context.TODO(), m.GuildID, listCount)
+	if err != nil {
+		return fmt.Errorf("getting finished mashups: %w", err)
+	}
+	if len(mashups) == 0 {
+		_, err := s.discord.ChannelMessageSend(m.ChannelID, "No mashups have finished in this server yet.")
+		return err
+	}
+
+	mashup := &mashups[0]
+	if len(args) >= 4 {
+		idx := slices.IndexFunc(mashups, func(mashup Mashup) bool {
+			return strings.EqualFold(mashup.Name, args[3])
+		})
+		if idx == -1 {
+			_, err := s.discord.ChannelMessageSend(m.ChannelID,
+				fmt.Sprintf("Found no recent mashup named **%s**.", args[3]))
+			return err
+		}
+		mashup = &mashups[idx]
+	}
+
+	names := make([]string, len(mashups))
+	for i, other := range mashups {
+		names[i] = other.Name
+	}
+	return s.sendResults(m.ChannelID, &mashup.LiveContest, "Recent mashups", names)
+}
@@ -0,0 +1,194 @@
+package codeforces
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+	"github.com/yuqzii/konkurransetilsynet/internal/utils"
+)
+
+const discussionChannelName string = "cf-discussion"
+
+// Opens a discussion thread in every guild when a contest finishes, and posts the editorial in
+// the threads when it is published.
+type postMortemService struct {
+	discord *discordgo.Session
+	db      judge.AccountRepository
+	client  api
+	guilds  judge.GuildProvider
+
+	editorialInterval time.Duration
+	editorialTimeout  time.Duration
+}
+
+func newPostMortemService(discord *discordgo.Session, db judge.AccountRepository, client api,
+	guilds judge.GuildProvider) *postMortemService {
+
+	const (
+		defaultEditorialInterval time.Duration = 30 * time.Minute
+		defaultEditorialTimeout  time.Duration = 3 * 24 * time.Hour
+	)
+
+	return &postMortemService{
+		discord:           discord,
+		db:                db,
+		client:            client,
+		guilds:            guilds,
+		editorialInterval: defaultEditorialInterval,
+		editorialTimeout:  defaultEditorialTimeout,
+	}
+}
+
+func (s *postMortemService) onContestFinish(c *contest) {
+	guilds := s.guilds.GetGuilds()
+	channels, err := utils.CreateChannelIfNotExist(s.discord, discussionChannelName, guilds)
+	if err != nil {
+		log.Printf("Failed to get discussion channels for %s: %s", c.Name, err)
+		return
+	}
+
+	_, problems, err := s.client.getContestProblems(context.TODO(), int(c.ID))
+	if err != nil {
+		log.Printf("Failed to get problems of %s: %s", c.Name, err)
+		return
+	}
+
+	var threads []string
+	for i, guild := range guilds {
+		thread, err := s.openThread(guild.ID, channels[i], c, problems)
+		if err != nil {
+			log.Printf("Failed to open post-mortem thread for %s in guild %s: %s", c.Name, guild.ID, err)
+			continue
+		}
+		threads = append(threads, thread)
+	}
+	if len(threads) == 0 {
+		return
+	}
+
+	s.postEditorial(c, threads)
+}
+
+// Starts a thread named after the contest with the problems, and tags the members of the guild
+// who participated. Returns the ID of the thread.
+func (s *postMortemService) openThread(guildID, channelID string, c *contest, problems []problem) (string, error) {
+	const (
+		maxThreadNameLength int = 100
+		// Keep the thread open for a week without activity
+		archiveMinutes int = 7 * 24 * 60
+	)
+
+	name := c.Name
+	if len(name) > maxThreadNameLength {
+		name = name[:maxThreadNameLength]
+	}
+	thread, err := s.discord.ThreadStart(channelID, name, discordgo.ChannelTypeGuildPublicThread, archiveMinutes)
+	if err != nil {
+		return "", fmt.Errorf("starting thread: %w", err)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## %s\nDiscuss the problems here. Remember spoiler tags for anyone who has not upsolved yet!", c.Name)
+	for _, p := range problems {
+		mp := toMashupProblem(&p)
+		fmt.Fprintf(&sb, "\n%s. [%s](%s)", p.Index, p.Name, mp.url())
+		if p.Rating != 0 {
+			fmt.Fprintf(&sb, " (%d)", p.Rating)
+		}
+	}
+	msgData := discordgo.MessageSend{
+		Content: sb.String(),
+		// Avoid embedding every problem
+		Flags: discordgo.MessageFlagsSuppressEmbeds,
+	}
+	if err := utils.SendSplitMessage(s.discord, thread.ID, &msgData); err != nil {
+		return thread.ID, fmt.Errorf("sending problem list: %w", err)
+	}
+
+	participants, err := s.guildParticipants(guildID, c)
+	if err != nil {
+		return thread.ID, fmt.Errorf("getting participants: %w", err)
+	}
+	if len(participants) > 0 {
+		mentions := make([]string, len(participants))
+		for i, id := range participants {
+			mentions[i] = fmt.Sprintf("<@%s>", id)
+		}
+		msg := "Participants from this server: " + strings.Join(mentions, " ")
+		if _, err := s.discord.ChannelMessageSend(thread.ID, msg); err != nil {
+			return thread.ID, fmt.Errorf("tagging participants: %w", err)
+		}
+	}
+
+	return thread.ID, nil
+}
+
+// Returns the Discord IDs of the members of the guild who participated in the contest.
+func (s *postMortemService) guildParticipants(guildID string, c *contest) ([]string, error) {
+	accounts, err := judge.GetLinkedAccountsInGuild(context.TODO(), s.db, s.discord, judge.Codeforces, guildID)
+	if err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return nil, nil
+	}
+
+	handles := make([]string, len(accounts))
+	for i, a := range accounts {
+		handles[i] = a.Handle
+	}
+
+	participated, err := s.client.getContestParticipants(context.TODO(), int(c.ID), handles)
+	if err != nil {
+		return nil, fmt.Errorf("getting standings of %s: %w", c.Name, err)
+	}
+	return participantOwners(accounts, participated), nil
+}
+
+// Returns the Discord IDs of the accounts with the participating handles.
+func participantOwners(accounts []judge.Account, participated []string) []string {
+	// Codeforces returns handles in their canonical case, which the stored handle may not have
+	owners := make(map[string]string, len(accounts))
+	for _, a := range accounts {
+		owners[strings.ToLower(a.Handle)] = a.DiscordID
+	}
+
+	var result []string
+	for _, handle := range participated {
+		if id, ok := owners[strings.ToLower(handle)]; ok {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// Checks the contest page until the editorial is published, and posts it in the threads.
+func (s *postMortemService) postEditorial(c *contest, threads []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.editorialTimeout)
+	defer cancel()
+
+	var editorial string
+	found := judge.Poll(ctx, s.editorialInterval, func(ctx context.Context) (bool, error) {
+		var err error
+		editorial, err = s.client.getEditorialURL(ctx, int(c.ID))
+		if err != nil {
+			return false, fmt.Errorf("getting editorial of %s: %w", c.Name, err)
+		}
+		return editorial != "", nil
+	}, nil)
+	if !found {
+		return
+	}
+
+	for _, thread := range threads {
+		_, err := s.discord.ChannelMessageSend(thread, "The editorial is out: "+editorial)
+		if err != nil {
+			log.Printf("Failed to post editorial of %s in thread %s: %s", c.Name, thread, err)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package codeforces
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+)
+
+func Test_ParticipantOwners(t *testing.T) {
+	accounts := []judge.Account{
+		{DiscordID: "1", Handle: "tourist"},
+		// Linked with a different case than the canonical handle
+		{DiscordID: "2", Handle: "jiangly"},
+		{DiscordID: "3", Handle: "Benq"},
+	}
+	participated := []string{"tourist", "Jiangly", "unknown"}
+
+	got := participantOwners(accounts, participated)
+	expected := []string{"1", "2"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,41 @@
+package codeforces
+
+import (
+	"errors"
+	"math/rand"
+)
+
+// Filters problems based on the f function parameter
+func filterProblems(problems []problem, f func(*problem) bool) (result []problem) {
+	for _, problem := range problems {
+		if f(&problem) {
+			result = append(result, problem)
+		}
+	}
+	return result
+}
+
+// Returns a random problem of the problem slice provided
+func getRandomProblem(problems []problem) (*problem, error) {
+	if len(problems) == 0 {
+		return nil, errors.New("cannot get random problem from empty slice")
+	}
+	prob := &problems[rand.Intn(len(problems))]
+	return prob, nil
+}
+
+func checkSubmissions(subs []submission, startTime int64, contID int, problemIdx string) bool {
+	for _, sub := range subs {
+		// Ensure that submission was made after command was initiated
+		if sub.CreationTimeSeconds-startTime < 0 {
+			return false
+		}
+		correctID := sub.Problem.ContestID == contID
+		correctIdx := sub.Problem.Index == problemIdx
+		compilationError := sub.Verdict == "COMPILATION_ERROR"
+		if correctID && correctIdx && compilationError {
+			return true
+		}
+	}
+	return false
+}
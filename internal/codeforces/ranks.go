@@ -0,0 +1,333 @@
+package codeforces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+	"github.com/yuqzii/konkurransetilsynet/internal/utils"
+)
+
+type RankRoleRepository interface {
+	// Returns the settings of every guild that has enabled rank roles.
+	GetRankRoleSettings(ctx context.Context) ([]RankRoleSettings, error)
+	// Enables rank roles in the guild, or updates its settings.
+	SetRankRoleSettings(ctx context.Context, settings RankRoleSettings) error
+	DisableRankRoles(ctx context.Context, guildID string) error
+}
+
+// How rank roles are named, e.g. "Candidate Master", "CM" or "CF Candidate Master".
+type RankRoleNaming string
+
+const (
+	RankRoleTitle    RankRoleNaming = "title"
+	RankRoleShort    RankRoleNaming = "short"
+	RankRolePrefixed RankRoleNaming = "prefixed"
+)
+
+type RankRoleSettings struct {
+	GuildID string
+	Naming  RankRoleNaming
+}
+
+type rank struct {
+	minRating int
+	title     string
+	short     string
+	color     int
+}
+
+// The Codeforces ranks in increasing order, with the colors used on Codeforces.
+var ranks = []rank{
+	{0, "Newbie", "N", 0x808080},
+	{1200, "Pupil", "P", 0x008000},
+	{1400, "Specialist", "S", 0x03a89e},
+	{1600, "Expert", "E", 0x0000ff},
+	{1900, "Candidate Master", "CM", 0xaa00aa},
+	{2100, "Master", "M", 0xff8c00},
+	{2300, "International Master", "IM", 0xff8c00},
+	{2400, "Grandmaster", "GM", 0xff0000},
+	{2600, "International Grandmaster", "IGM", 0xff0000},
+	{3000, "Legendary Grandmaster", "LGM", 0xff0000},
+}
+
+// Returns the index of the rank of the rating in ranks, or -1 for unrated users.
+func rankIndex(rating int) int {
+	if rating <= 0 {
+		return -1
+	}
+	for i := len(ranks) - 1; i > 0; i-- {
+		if rating >= ranks[i].minRating {
+			return i
+		}
+	}
+	return 0
+}
+
+// Returns the color of the rank of the rating, or 0 for unrated users.
+func rankColor(rating int) int {
+	idx := rankIndex(rating)
+	if idx == -1 {
+		return 0
+	}
+	return ranks[idx].color
+}
+
+func (r *rank) roleName(naming RankRoleNaming) string {
+	switch naming {
+	case RankRoleShort:
+		return r.short
+	case RankRolePrefixed:
+		return "CF " + r.title
+	default:
+		return r.title
+	}
+}
+
+// Keeps every connected member in a Discord role matching their Codeforces rank.
+type rankRoleService struct {
+	discord *discordgo.Session
+	db      Repository
+	client  api
+
+	userBatchSize int
+	// Only one sync runs at a time
+	mu sync.Mutex
+}
+
+func newRankRoleService(discord *discordgo.Session, db Repository, client api) *rankRoleService {
+	const defaultUserBatchSize int = 100
+
+	return &rankRoleService{
+		discord:       discord,
+		db:            db,
+		client:        client,
+		userBatchSize: defaultUserBatchSize,
+	}
+}
+
+// Syncs the rank roles of every guild that has enabled them. Errors are logged.
+func (s *rankRoleService) syncAll() {
+	settings, err := s.db.GetRankRoleSettings(context.TODO())
+	if err != nil {
+		log.Println("Failed to get rank role settings:", err)
+		return
+	}
+	for _, guild := range settings {
+		if err := s.syncGuild(context.TODO(), &guild); err != nil {
+			log.Printf("Failed to sync rank roles in guild %s: %s", guild.GuildID, err)
+		}
+	}
+}
+
+// Creates the rank roles of the guild if they do not exist, and gives every member the role of
+// their current rank.
+func (s *rankRoleService) syncGuild(ctx context.Context, settings *RankRoleSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	guild, err := s.discord.Guild(settings.GuildID)
+	if err != nil {
+		return fmt.Errorf("getting guild: %w", err)
+	}
+
+	roleIDs := make([]string, len(ranks))
+	for i, r := range ranks {
+		ids, err := utils.CreateRoleIfNotExists(s.discord, r.roleName(settings.Naming), []*discordgo.Guild{guild},
+			utils.WithRoleColor(r.color))
+		if err != nil {
+			return fmt.Errorf("creating rank roles: %w", err)
+		}
+		roleIDs[i] = ids[0]
+	}
+
+	targets, err := s.targetRoles(ctx, guild.ID, roleIDs)
+	if err != nil {
+		return err
+	}
+
+	members, err := utils.GetGuildMembers(s.discord, guild.ID)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		add, remove := roleChanges(member.Roles, roleIDs, targets[member.User.ID])
+		for _, role := range remove {
+			if err := s.discord.GuildMemberRoleRemove(guild.ID, member.User.ID, role); err != nil {
+				log.Printf("Failed to remove rank role %s from %s: %s", role, member.User.ID, err)
+			}
+		}
+		if add != "" {
+			if err := s.discord.GuildMemberRoleAdd(guild.ID, member.User.ID, add); err != nil {
+				log.Printf("Failed to add rank role %s to %s: %s", add, member.User.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the rank role ID every rated, connected member of the guild should have, by Discord ID.
+func (s *rankRoleService) targetRoles(ctx context.Context, guildID string, roleIDs []string) (map[string]string, error) {
+	accounts, err := judge.GetLinkedAccountsInGuild(ctx, s.db, s.discord, judge.Codeforces, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Codeforces returns handles in their canonical case, which the stored handle may not have
+	owners := make(map[string]string, len(accounts))
+	handles := make([]string, len(accounts))
+	for i, a := range accounts {
+		owners[strings.ToLower(a.Handle)] = a.DiscordID
+		handles[i] = a.Handle
+	}
+
+	users, err := getUsersByHandle(ctx, s.client, handles, s.userBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("getting ratings: %w", err)
+	}
+	targets := make(map[string]string, len(accounts))
+	for handle, u := range users {
+		owner, ok := owners[handle]
+		if idx := rankIndex(u.Rating); ok && idx != -1 {
+			targets[owner] = roleIDs[idx]
+		}
+	}
+	return targets, nil
+}
+
+// Returns the role to add, if any, and the rank roles to remove for a member to only have the
+// target role among the rank roles. The target is empty for members who should have none.
+func roleChanges(memberRoles, rankRoles []string, target string) (add string, remove []string) {
+	for _, role := range memberRoles {
+		if role != target && slices.Contains(rankRoles, role) {
+			remove = append(remove, role)
+		}
+	}
+	if target != "" && !slices.Contains(memberRoles, target) {
+		add = target
+	}
+	return add, remove
+}
+
+// Deletes the rank roles of the naming from the guild.
+func (s *rankRoleService) deleteRoles(guildID string, naming RankRoleNaming) error {
+	roles, err := s.discord.GuildRoles(guildID)
+	if err != nil {
+		return fmt.Errorf("getting roles: %w", err)
+	}
+
+	var errs []error
+	for _, r := range ranks {
+		idx := slices.IndexFunc(roles, func(role *discordgo.Role) bool {
+			return role.Name == r.roleName(naming)
+		})
+		if idx == -1 {
+			continue
+		}
+		if err := s.discord.GuildRoleDelete(guildID, roles[idx].ID); err != nil {
+			errs = append(errs, fmt.Errorf("deleting role %s: %w", roles[idx].Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// enable [naming] | naming <naming> | disable | sync
+func (s *rankRoleService) handleCommand(args []string, m *discordgo.MessageCreate) error {
+	if len(args) < 3 {
+		return s.sendUsage(m.ChannelID)
+	}
+
+	isAdmin, err := utils.IsAdmin(s.discord, m.Author.ID, m.ChannelID)
+	if err != nil {
+		return fmt.Errorf("checking permissions of %s: %w", m.Author.ID, err)
+	}
+	if !isAdmin {
+		return utils.NoPermission(s.discord, m)
+	}
+
+	settings, err := s.db.GetRankRoleSettings(context.TODO())
+	if err != nil {
+		return fmt.Errorf("getting rank role settings: %w", err)
+	}
+	idx := slices.IndexFunc(settings, func(s RankRoleSettings) bool {
+		return s.GuildID == m.GuildID
+	})
+	var current *RankRoleSettings
+	if idx != -1 {
+		current = &settings[idx]
+	}
+
+	switch args[2] {
+	case "enable", "naming":
+		naming := RankRoleTitle
+		if len(args) >= 4 {
+			naming = RankRoleNaming(strings.ToLower(args[3]))
+		} else if args[2] == "naming" {
+			return s.sendUsage(m.ChannelID)
+		}
+		if !slices.Contains([]RankRoleNaming{RankRoleTitle, RankRoleShort, RankRolePrefixed}, naming) {
+			return s.sendUsage(m.ChannelID)
+		}
+		if args[2] == "naming" && current == nil {
+			_, err := s.discord.ChannelMessageSend(m.ChannelID, "Enable rank roles with `!cf ranks enable` first.")
+			return err
+		}
+		if current != nil && current.Naming != naming {
+			if err := s.deleteRoles(m.GuildID, current.Naming); err != nil {
+				return fmt.Errorf("deleting old rank roles: %w", err)
+			}
+		}
+
+		newSettings := RankRoleSettings{GuildID: m.GuildID, Naming: naming}
+		if err := s.db.SetRankRoleSettings(context.TODO(), newSettings); err != nil {
+			return fmt.Errorf("storing rank role settings: %w", err)
+		}
+		return s.syncCommand(&newSettings, m)
+	case "sync":
+		if current == nil {
+			_, err := s.discord.ChannelMessageSend(m.ChannelID, "Enable rank roles with `!cf ranks enable` first.")
+			return err
+		}
+		return s.syncCommand(current, m)
+	case "disable":
+		if current == nil {
+			_, err := s.discord.ChannelMessageSend(m.ChannelID, "Rank roles are not enabled.")
+			return err
+		}
+		if err := s.db.DisableRankRoles(context.TODO(), m.GuildID); err != nil {
+			return fmt.Errorf("disabling rank roles: %w", err)
+		}
+		if err := s.deleteRoles(m.GuildID, current.Naming); err != nil {
+			return fmt.Errorf("deleting rank roles: %w", err)
+		}
+		_, err := s.discord.ChannelMessageSend(m.ChannelID, "Disabled and removed the rank roles.")
+		return err
+	default:
+		return s.sendUsage(m.ChannelID)
+	}
+}
+
+func (s *rankRoleService) syncCommand(settings *RankRoleSettings, m *discordgo.MessageCreate) error {
+	if err := s.syncGuild(context.TODO(), settings); err != nil {
+		return fmt.Errorf("syncing rank roles: %w", err)
+	}
+	_, err := s.discord.ChannelMessageSend(m.ChannelID, "The rank roles are up to date.")
+	return err
+}
+
+func (s *rankRoleService) sendUsage(channelID string) error {
+	msg := "Usage (administrators only):\n" +
+		"`!cf ranks enable [naming (optional)]`\n" +
+		"`!cf ranks naming [naming]`\n" +
+		"`!cf ranks sync`\n" +
+		"`!cf ranks disable`\n" +
+		"Naming is `title` (Candidate Master), `short` (CM) or `prefixed` (CF Candidate Master)."
+	_, err := s.discord.ChannelMessageSend(channelID, msg)
+	return err
+}
@@ -0,0 +1,59 @@
+package codeforces
+
+import (
+	"slices"
+	"testing"
+)
+
+func Test_RankIndex(t *testing.T) {
+	tests := []struct {
+		rating   int
+		expected string
+	}{
+		{0, ""},
+		{350, "Newbie"},
+		{1199, "Newbie"},
+		{1200, "Pupil"},
+		{1899, "Expert"},
+		{1900, "Candidate Master"},
+		{2399, "International Master"},
+		{3000, "Legendary Grandmaster"},
+		{3900, "Legendary Grandmaster"},
+	}
+
+	for _, test := range tests {
+		idx := rankIndex(test.rating)
+		got := ""
+		if idx != -1 {
+			got = ranks[idx].title
+		}
+		if got != test.expected {
+			t.Errorf("rating %d: expected '%s', got '%s'", test.rating, test.expected, got)
+		}
+	}
+}
+
+func Test_RoleChanges(t *testing.T) {
+	rankRoles := []string{"newbie", "pupil", "expert"}
+
+	tests := []struct {
+		name           string
+		memberRoles    []string
+		target         string
+		expectedAdd    string
+		expectedRemove []string
+	}{
+		{"promoted", []string{"other", "pupil"}, "expert", "expert", []string{"pupil"}},
+		{"unchanged", []string{"expert", "other"}, "expert", "", nil},
+		{"unlinked", []string{"newbie", "other"}, "", "", []string{"newbie"}},
+		{"new member", nil, "newbie", "newbie", nil},
+	}
+
+	for _, test := range tests {
+		add, remove := roleChanges(test.memberRoles, rankRoles, test.target)
+		if add != test.expectedAdd || !slices.Equal(remove, test.expectedRemove) {
+			t.Errorf("%s: expected add '%s' and remove %v, got '%s' and %v",
+				test.name, test.expectedAdd, test.expectedRemove, add, remove)
+		}
+	}
+}
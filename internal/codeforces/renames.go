@@ -0,0 +1,70 @@
+package codeforces
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+)
+
+// Keeps linked handles up to date when users change their handle on Codeforces.
+type renameService struct {
+	db     Repository
+	client api
+}
+
+func newRenameService(db Repository, client api) *renameService {
+	return &renameService{db: db, client: client}
+}
+
+// Start goroutine that periodically checks whether any linked handles have been renamed on Codeforces
+func (s *renameService) StartHandleRenameCheck(interval time.Duration) {
+	go func() {
+		for {
+			time.Sleep(interval)
+			err := s.migrateRenamedHandles(context.Background())
+			if err != nil {
+				log.Println("Failed to check for renamed Codeforces handles:", err)
+			}
+		}
+	}()
+}
+
+// Looks up every linked handle with historic handles enabled, and updates the stored
+// handle when Codeforces reports that the user has changed it.
+func (s *renameService) migrateRenamedHandles(ctx context.Context) error {
+	accounts, err := s.db.GetLinkedAccounts(ctx, judge.Codeforces)
+	if err != nil {
+		return fmt.Errorf("getting linked Codeforces accounts: %w", err)
+	}
+
+	for _, a := range accounts {
+		u, err := s.client.getUserInfo(ctx, a.Handle, true)
+		if err != nil {
+			log.Printf("Failed to get Codeforces user info of '%s': %s", a.Handle, err)
+			continue
+		}
+		if u.Handle == a.Handle {
+			continue
+		}
+
+		err = s.db.LinkAccount(ctx, judge.Codeforces, a.DiscordID, u.Handle)
+		if err != nil {
+			log.Printf("Failed to migrate Codeforces handle '%s' to '%s': %s", a.Handle, u.Handle, err)
+			continue
+		}
+		judge.AddAuditEntry(s.db, judge.AuditEntry{
+			Platform:  judge.Codeforces,
+			DiscordID: a.DiscordID,
+			Action:    judge.AuditRename,
+			OldHandle: a.Handle,
+			NewHandle: u.Handle,
+		})
+		log.Printf("Migrated Codeforces handle of Discord user %s from '%s' to '%s'.",
+			a.DiscordID, a.Handle, u.Handle)
+	}
+
+	return nil
+}
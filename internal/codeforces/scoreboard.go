@@ -0,0 +1,150 @@
+package codeforces
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Minutes added to the penalty for every rejected submission before a problem is accepted.
+const penaltyMinutes int = 20
+
+// The result of a participant on one problem.
+type ProblemResult struct {
+	Solved bool `json:"solved"`
+	// Rejected submissions, before the accepted one if solved
+	Rejected int `json:"rejected"`
+	// Minutes from the start to the accepted submission
+	SolveMinute int `json:"solveMinute"`
+}
+
+type ScoreboardRow struct {
+	Rank      int             `json:"rank"`
+	DiscordID string          `json:"discordId"`
+	Handle    string          `json:"handle"`
+	Solved    int             `json:"solved"`
+	Penalty   int             `json:"penalty"`
+	Problems  []ProblemResult `json:"problems"`
+}
+
+type participant struct {
+	discordID string
+	handle    string
+}
+
+// Computes an ICPC style scoreboard from the submissions of the participants, keyed by handle.
+// Only submissions made between start and end to the problems count, and compilation errors are
+// not penalized.
+func computeScoreboard(problems []MashupProblem, start, end time.Time, participants []participant,
+	submissions map[string][]submission) []ScoreboardRow {
+
+	rows := make([]ScoreboardRow, len(participants))
+	for i, p := range participants {
+		row := ScoreboardRow{
+			DiscordID: p.discordID,
+			Handle:    p.handle,
+			Problems:  make([]ProblemResult, len(problems)),
+		}
+
+		// Codeforces returns the newest submissions first
+		subs := slices.Clone(submissions[p.handle])
+		slices.SortFunc(subs, func(a, b submission) int {
+			return cmp.Compare(a.CreationTimeSeconds, b.CreationTimeSeconds)
+		})
+
+		for _, sub := range subs {
+			subTime := time.Unix(sub.CreationTimeSeconds, 0)
+			if subTime.Before(start) || !subTime.Before(end) {
+				continue
+			}
+			idx := slices.IndexFunc(problems, func(prob MashupProblem) bool {
+				return prob.ContestID == sub.Problem.ContestID && prob.Index == sub.Problem.Index
+			})
+			if idx == -1 || row.Problems[idx].Solved {
+				continue
+			}
+
+			result := &row.Problems[idx]
+			switch sub.Verdict {
+			case "OK":
+				result.Solved = true
+				result.SolveMinute = int(subTime.Sub(start).Minutes())
+				row.Solved++
+				row.Penalty += result.SolveMinute + result.Rejected*penaltyMinutes
+			case "COMPILATION_ERROR", "TESTING", "":
+				// Not judged yet, or not penalized
+			default:
+				result.Rejected++
+			}
+		}
+
+		rows[i] = row
+	}
+
+	slices.SortStableFunc(rows, func(a, b ScoreboardRow) int {
+		if a.Solved != b.Solved {
+			return b.Solved - a.Solved
+		}
+		if a.Penalty != b.Penalty {
+			return a.Penalty - b.Penalty
+		}
+		return strings.Compare(strings.ToLower(a.Handle), strings.ToLower(b.Handle))
+	})
+	for i := range rows {
+		// Participants with the same score share the rank
+		if i > 0 && rows[i].Solved == rows[i-1].Solved && rows[i].Penalty == rows[i-1].Penalty {
+			rows[i].Rank = rows[i-1].Rank
+		} else {
+			rows[i].Rank = i + 1
+		}
+	}
+	return rows
+}
+
+// Formats the scoreboard as a table in a code block.
+func formatScoreboard(problems []MashupProblem, rows []ScoreboardRow) string {
+	handleWidth := len("Handle")
+	for _, row := range rows {
+		handleWidth = max(handleWidth, len(row.Handle))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("```\n")
+	fmt.Fprintf(&sb, "%-3s %-*s", "#", handleWidth, "Handle")
+	for i := range problems {
+		fmt.Fprintf(&sb, " %-3s", problemLetter(i))
+	}
+	sb.WriteString("  =  Pen\n")
+
+	for _, row := range rows {
+		fmt.Fprintf(&sb, "%-3d %-*s", row.Rank, handleWidth, row.Handle)
+		for _, result := range row.Problems {
+			fmt.Fprintf(&sb, " %-3s", formatProblemResult(result))
+		}
+		fmt.Fprintf(&sb, " %2d %4d\n", row.Solved, row.Penalty)
+	}
+	sb.WriteString("```")
+	return sb.String()
+}
+
+// "+" when solved on the first try, "+k" when solved after k rejections and "-k" when unsolved
+// after k rejections.
+func formatProblemResult(r ProblemResult) string {
+	switch {
+	case r.Solved && r.Rejected == 0:
+		return "+"
+	case r.Solved:
+		return fmt.Sprintf("+%d", r.Rejected)
+	case r.Rejected > 0:
+		return fmt.Sprintf("-%d", r.Rejected)
+	default:
+		return "."
+	}
+}
+
+// Mashup problems are labeled A, B, C, ... like in a contest.
+func problemLetter(i int) string {
+	return string(rune('A' + i))
+}
@@ -0,0 +1,83 @@
+package codeforces
+
+import (
+	"testing"
+	"time"
+)
+
+func newSubmission(contestID int, index, verdict string, t time.Time) submission {
+	sub := submission{CreationTimeSeconds: t.Unix(), Verdict: verdict}
+	sub.Problem.ContestID = contestID
+	sub.Problem.Index = index
+	return sub
+}
+
+func Test_ComputeScoreboard(t *testing.T) {
+	start := time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	problems := []MashupProblem{{ContestID: 1850, Index: "A"}, {ContestID: 1850, Index: "B"}}
+	participants := []participant{
+		{discordID: "1", handle: "alice"},
+		{discordID: "2", handle: "bob"},
+		{discordID: "3", handle: "carol"},
+		{discordID: "4", handle: "dave"},
+	}
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	// Newest first, like the Codeforces API
+	submissions := map[string][]submission{
+		"alice": {
+			newSubmission(1850, "B", "OK", at(50)),
+			newSubmission(1850, "B", "WRONG_ANSWER", at(40)),
+			newSubmission(1850, "A", "OK", at(10)),
+		},
+		"bob": {
+			// After the end
+			newSubmission(1850, "B", "OK", at(130)),
+			newSubmission(1850, "A", "OK", at(30)),
+			// Compilation errors are not penalized
+			newSubmission(1850, "A", "COMPILATION_ERROR", at(5)),
+			// Before the start
+			newSubmission(1850, "A", "WRONG_ANSWER", at(-5)),
+		},
+		"carol": {
+			// Submissions after the accepted one do not count
+			newSubmission(1850, "A", "WRONG_ANSWER", at(40)),
+			newSubmission(1850, "A", "OK", at(30)),
+			// Not in the mashup
+			newSubmission(1000, "A", "OK", at(20)),
+		},
+		"dave": {
+			newSubmission(1850, "A", "TIME_LIMIT_EXCEEDED", at(20)),
+			newSubmission(1850, "A", "WRONG_ANSWER", at(10)),
+		},
+	}
+
+	rows := computeScoreboard(problems, start, end, participants, submissions)
+
+	expected := []ScoreboardRow{
+		{Rank: 1, Handle: "alice", Solved: 2, Penalty: 10 + 50 + penaltyMinutes,
+			Problems: []ProblemResult{{Solved: true, SolveMinute: 10}, {Solved: true, Rejected: 1, SolveMinute: 50}}},
+		{Rank: 2, Handle: "bob", Solved: 1, Penalty: 30,
+			Problems: []ProblemResult{{Solved: true, SolveMinute: 30}, {}}},
+		{Rank: 2, Handle: "carol", Solved: 1, Penalty: 30,
+			Problems: []ProblemResult{{Solved: true, SolveMinute: 30}, {}}},
+		{Rank: 4, Handle: "dave", Solved: 0, Penalty: 0,
+			Problems: []ProblemResult{{Rejected: 2}, {}}},
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("expected %d rows, got %+v", len(expected), rows)
+	}
+	for i, exp := range expected {
+		got := rows[i]
+		if got.Rank != exp.Rank || got.Handle != exp.Handle || got.Solved != exp.Solved || got.Penalty != exp.Penalty {
+			t.Errorf("row %d: expected %+v, got %+v", i, exp, got)
+			continue
+		}
+		for j := range exp.Problems {
+			if got.Problems[j] != exp.Problems[j] {
+				t.Errorf("row %d problem %s: expected %+v, got %+v", i, problemLetter(j), exp.Problems[j], got.Problems[j])
+			}
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package codeforces
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+	"github.com/yuqzii/konkurransetilsynet/internal/utils"
+	"golang.org/x/time/rate"
+)
+
+type SubmissionFeedRepository interface {
+	// Returns the settings of every guild that has enabled the submission feed.
+	GetSubmissionFeedSettings(ctx context.Context) ([]SubmissionFeedSettings, error)
+	// Enables the submission feed of the guild, or updates its settings.
+	SetSubmissionFeedSettings(ctx context.Context, settings SubmissionFeedSettings) error
+	DisableSubmissionFeed(ctx context.Context, guildID string) error
+	SetSubmissionFeedOptOut(ctx context.Context, guildID, discID string, optOut bool) error
+	// Returns the Discord IDs of the members of the guild who have opted out of the feed.
+	GetSubmissionFeedOptOuts(ctx context.Context, guildID string) ([]string, error)
+}
+
+type SubmissionFeedSettings struct {
+	GuildID   string
+	ChannelID string
+	// Problems rated below this are not posted
+	MinRating int
+	// Posts beyond this in an hour are dropped
+	MaxPostsPerHour int
+}
+
+const (
+	defaultFeedMinRating       int = 0
+	defaultFeedMaxPostsPerHour int = 10
+)
+
+// Why an accepted submission is posted in the feed, or feedReasonNone if it is not.
+type feedReason int
+
+const (
+	feedReasonNone feedReason = iota
+	feedReasonInRound
+	feedReasonAboveRating
+)
+
+// Posts accepted submissions of connected members that are solved in-round or rated above the
+// rating of the member.
+type submissionFeedService struct {
+	discord *discordgo.Session
+	db      Repository
+	client  api
+
+	submissionCount uint16
+	userBatchSize   int
+
+	// The newest submission ID seen for each handle
+	lastSeen map[string]int
+	// Post rate limits by guild
+	limiters map[string]*rate.Limiter
+	mu       sync.Mutex
+}
+
+func newSubmissionFeedService(discord *discordgo.Session, db Repository, client api) *submissionFeedService {
+	const (
+		defaultSubmissionCount uint16 = 20
+		defaultUserBatchSize   int    = 100
+	)
+
+	return &submissionFeedService{
+		discord:         discord,
+		db:              db,
+		client:          client,
+		submissionCount: defaultSubmissionCount,
+		userBatchSize:   defaultUserBatchSize,
+		lastSeen:        make(map[string]int),
+		limiters:        make(map[string]*rate.Limiter),
+	}
+}
+
+// Starts a goroutine that checks the submissions of connected members every interval.
+func (s *submissionFeedService) StartSubmissionFeed(interval time.Duration) {
+	go func() {
+		for {
+			if err := s.poll(context.Background()); err != nil {
+				log.Println("Failed to update Codeforces submission feed:", err)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// A guild that a handle's accepted submissions may be posted in.
+type feedTarget struct {
+	settings  SubmissionFeedSettings
+	discordID string
+}
+
+func (s *submissionFeedService) poll(ctx context.Context) error {
+	settings, err := s.db.GetSubmissionFeedSettings(ctx)
+	if err != nil {
+		return fmt.Errorf("getting submission feed settings: %w", err)
+	}
+
+	targets := make(map[string][]feedTarget)
+	for _, guild := range settings {
+		accounts, err := judge.GetLinkedAccountsInGuild(ctx, s.db, s.discord, judge.Codeforces, guild.GuildID)
+		if err != nil {
+			log.Printf("Failed to get Codeforces accounts in guild %s: %s", guild.GuildID, err)
+			continue
+		}
+		optOuts, err := s.db.GetSubmissionFeedOptOuts(ctx, guild.GuildID)
+		if err != nil {
+			log.Printf("Failed to get submission feed opt-outs in guild %s: %s", guild.GuildID, err)
+			continue
+		}
+		for _, a := range accounts {
+			if !slices.Contains(optOuts, a.DiscordID) {
+				targets[a.Handle] = append(targets[a.Handle], feedTarget{settings: guild, discordID: a.DiscordID})
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	ratings, err := s.getRatings(ctx, targets)
+	if err != nil {
+		return err
+	}
+
+	for handle, handleTargets := range targets {
+		subs, err := s.client.getSubmissions(ctx, handle, s.submissionCount)
+		if err != nil {
+			log.Printf("Failed to get submissions of %s for the feed: %s", handle, err)
+			continue
+		}
+
+		s.mu.Lock()
+		lastSeen, seen := s.lastSeen[handle]
+		accepted, newest := newAccepted(subs, lastSeen)
+		s.lastSeen[handle] = newest
+		s.mu.Unlock()
+		// Only submissions made after the handle was first checked are posted
+		if !seen {
+			continue
+		}
+
+		rating := ratings[strings.ToLower(handle)]
+		for _, sub := range accepted {
+			reason := getFeedReason(&sub, rating)
+			if reason == feedReasonNone {
+				continue
+			}
+			for _, target := range handleTargets {
+				s.post(&target, handle, &sub, reason, rating)
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the current ratings of the handles by lowercase handle, fetched in batches. Unrated
+// handles are left out.
+func (s *submissionFeedService) getRatings(ctx context.Context, targets map[string][]feedTarget) (map[string]int, error) {
+	handles := make([]string, 0, len(targets))
+	for handle := range targets {
+		handles = append(handles, handle)
+	}
+
+	users, err := getUsersByHandle(ctx, s.client, handles, s.userBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("getting ratings: %w", err)
+	}
+	ratings := make(map[string]int, len(users))
+	for handle, u := range users {
+		if u.Rating > 0 {
+			ratings[handle] = u.Rating
+		}
+	}
+	return ratings, nil
+}
+
+// Returns the accepted submissions newer than lastSeen, oldest first, and the newest submission ID.
+func newAccepted(subs []submission, lastSeen int) ([]submission, int) {
+	newest := lastSeen
+	var accepted []submission
+	for _, sub := range subs {
+		newest = max(newest, sub.ID)
+		if sub.ID > lastSeen && sub.Verdict == "OK" {
+			accepted = append(accepted, sub)
+		}
+	}
+	slices.SortFunc(accepted, func(a, b submission) int {
+		return a.ID - b.ID
+	})
+	return accepted, newest
+}
+
+// In-round solves are always posted, and other solves only when the problem is rated above the
+// user. rating is 0 for unrated users.
+func getFeedReason(sub *submission, rating int) feedReason {
+	switch {
+	case sub.Author.ParticipantType == "CONTESTANT":
+		return feedReasonInRound
+	case rating > 0 && int(sub.Problem.Rating) > rating:
+		return feedReasonAboveRating
+	default:
+		return feedReasonNone
+	}
+}
+
+// The minimum rating of the guild only applies to solves above the rating of the user, as problems
+// are unrated while their round is running.
+func passesMinRating(settings *SubmissionFeedSettings, sub *submission, reason feedReason) bool {
+	return reason != feedReasonAboveRating || int(sub.Problem.Rating) >= settings.MinRating
+}
+
+func (s *submissionFeedService) post(target *feedTarget, handle string, sub *submission, reason feedReason,
+	rating int) {
+
+	settings := &target.settings
+	if !passesMinRating(settings, sub, reason) {
+		return
+	}
+	if !s.limiter(settings).Allow() {
+		log.Printf("Dropped submission feed post of %s in guild %s due to the rate limit.", handle, settings.GuildID)
+		return
+	}
+
+	p := MashupProblem{ContestID: sub.Problem.ContestID, Index: sub.Problem.Index}
+	msg := fmt.Sprintf("<@%s> (`%s`) solved [%d%s - %s](%s)", target.discordID, handle,
+		sub.Problem.ContestID, sub.Problem.Index, sub.Problem.Name, p.url())
+	if sub.Problem.Rating != 0 {
+		msg += fmt.Sprintf(" rated %d", sub.Problem.Rating)
+	}
+	switch reason {
+	case feedReasonInRound:
+		msg += " during the contest!"
+	case feedReasonAboveRating:
+		msg += fmt.Sprintf(", above their rating of %d!", rating)
+	}
+
+	msgData := discordgo.MessageSend{
+		Content: msg,
+		Flags:   discordgo.MessageFlagsSuppressEmbeds | discordgo.MessageFlagsSuppressNotifications,
+		// Mention the member without pinging them
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}
+	if _, err := s.discord.ChannelMessageSendComplex(settings.ChannelID, &msgData); err != nil {
+		log.Printf("Failed to post submission of %s in guild %s: %s", handle, settings.GuildID, err)
+	}
+}
+
+// Returns the rate limiter of the guild, replacing it if the limit has changed.
+func (s *submissionFeedService) limiter(settings *SubmissionFeedSettings) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.limiters[settings.GuildID]
+	if !ok || l.Burst() != settings.MaxPostsPerHour {
+		l = rate.NewLimiter(rate.Every(time.Hour/time.Duration(settings.MaxPostsPerHour)), settings.MaxPostsPerHour)
+		s.limiters[settings.GuildID] = l
+	}
+	return l
+}
+
+func (s *submissionFeedService) handleCommand(args []string, m *discordgo.MessageCreate) error {
+	if len(args) < 3 {
+		return s.sendUsage(m.ChannelID)
+	}
+
+	switch args[2] {
+	case "optout":
+		return s.setOptOut(m, true)
+	case "optin":
+		return s.setOptOut(m, false)
+	case "settings":
+		return s.settingsCommand(m)
+	case "enable", "disable", "minrating", "ratelimit":
+		return s.adminCommand(args, m)
+	default:
+		return s.sendUsage(m.ChannelID)
+	}
+}
+
+func (s *submissionFeedService) sendUsage(channelID string) error {
+	msg := "Usage:\n" +
+		"`!cf feed optout` or `!cf feed optin`\n" +
+		"`!cf feed settings`\n" +
+		"Administrators:\n" +
+		"`!cf feed enable` posts the feed in this channel\n" +
+		"`!cf feed disable`\n" +
+		"`!cf feed minrating [rating]`\n" +
+		"`!cf feed ratelimit [posts per hour]`"
+	_, err := s.discord.ChannelMessageSend(channelID, msg)
+	return err
+}
+
+func (s *submissionFeedService) setOptOut(m *discordgo.MessageCreate, optOut bool) error {
+	if err := s.db.SetSubmissionFeedOptOut(context.TODO(), m.GuildID, m.Author.ID, optOut); err != nil {
+		return fmt.Errorf("setting submission feed opt-out of %s: %w", m.Author.ID, err)
+	}
+
+	msg := "Your solves will be posted in the submission feed again."
+	if optOut {
+		msg = "Your solves will no longer be posted in the submission feed."
+	}
+	_, err := s.discord.ChannelMessageSend(m.ChannelID, msg)
+	return err
+}
+
+// Returns the submission feed settings of the guild, or nil if the feed is disabled.
+func (s *submissionFeedService) guildSettings(ctx context.Context, guildID string) (*SubmissionFeedSettings, error) {
+	settings, err := s.db.GetSubmissionFeedSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting submission feed settings: %w", err)
+	}
+	idx := slices.IndexFunc(settings, func(s SubmissionFeedSettings) bool {
+		return s.GuildID == guildID
+	})
+	if idx == -1 {
+		return nil, nil
+	}
+	return &settings[idx], nil
+}
+
+func (s *submissionFeedService) settingsCommand(m *discordgo.MessageCreate) error {
+	settings, err := s.guildSettings(context.TODO(), m.GuildID)
+	if err != nil {
+		return err
+	}
+
+	msg := "The submission feed is disabled in this server."
+	if settings != nil {
+		msg = fmt.Sprintf("The submission feed is posted in <#%s>, with problems rated at least %d "+
+			"and at most %d posts per hour.", settings.ChannelID, settings.MinRating, settings.MaxPostsPerHour)
+	}
+	_, err = s.discord.ChannelMessageSend(m.ChannelID, msg)
+	return err
+}
+
+// enable | disable | minrating <rating> | ratelimit <posts per hour>
+func (s *submissionFeedService) adminCommand(args []string, m *discordgo.MessageCreate) error {
+	const maxPostsPerHour int = 60
+
+	isAdmin, err := utils.IsAdmin(s.discord, m.Author.ID, m.ChannelID)
+	if err != nil {
+		return fmt.Errorf("checking permissions of %s: %w", m.Author.ID, err)
+	}
+	if !isAdmin {
+		return utils.NoPermission(s.discord, m)
+	}
+
+	if args[2] == "disable" {
+		if err := s.db.DisableSubmissionFeed(context.TODO(), m.GuildID); err != nil {
+			return fmt.Errorf("disabling submission feed: %w", err)
+		}
+		_, err := s.discord.ChannelMessageSend(m.ChannelID, "Disabled the submission feed.")
+		return err
+	}
+
+	current, err := s.guildSettings(context.TODO(), m.GuildID)
+	if err != nil {
+		return err
+	}
+	settings := SubmissionFeedSettings{
+		GuildID:         m.GuildID,
+		ChannelID:       m.ChannelID,
+		MinRating:       defaultFeedMinRating,
+		MaxPostsPerHour: defaultFeedMaxPostsPerHour,
+	}
+	if current != nil {
+		settings = *current
+	}
+
+	switch args[2] {
+	case "enable":
+		settings.ChannelID = m.ChannelID
+	case "minrating", "ratelimit":
+		if current == nil {
+			_, err := s.discord.ChannelMessageSend(m.ChannelID,
+				"Enable the submission feed with `!cf feed enable` first.")
+			return err
+		}
+		if len(args) != 4 {
+			return s.sendUsage(m.ChannelID)
+		}
+		value, err := strconv.Atoi(args[3])
+		if args[2] == "minrating" && (err != nil || value < 0) {
+			_, err := s.discord.ChannelMessageSend(m.ChannelID, "The minimum rating cannot be negative.")
+			return err
+		}
+		if args[2] == "ratelimit" && (err != nil || value < 1 || value > maxPostsPerHour) {
+			_, err := s.discord.ChannelMessageSend(m.ChannelID,
+				fmt.Sprintf("The rate limit must be between 1 and %d posts per hour.", maxPostsPerHour))
+			return err
+		}
+
+		if args[2] == "minrating" {
+			settings.MinRating = value
+		} else {
+			settings.MaxPostsPerHour = value
+		}
+	}
+
+	if err := s.db.SetSubmissionFeedSettings(context.TODO(), settings); err != nil {
+		return fmt.Errorf("storing submission feed settings: %w", err)
+	}
+	return s.settingsCommand(m)
+}
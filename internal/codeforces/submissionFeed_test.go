@@ -0,0 +1,79 @@
+package codeforces
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_NewAccepted(t *testing.T) {
+	now := time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC)
+	newSub := func(id int, verdict string) submission {
+		sub := newSubmission(1850, "A", verdict, now)
+		sub.ID = id
+		return sub
+	}
+	// Newest first, like the Codeforces API
+	subs := []submission{newSub(105, "OK"), newSub(104, "WRONG_ANSWER"), newSub(103, "OK"), newSub(101, "OK")}
+
+	accepted, newest := newAccepted(subs, 102)
+	if newest != 105 {
+		t.Errorf("expected newest 105, got %d", newest)
+	}
+	if len(accepted) != 2 || accepted[0].ID != 103 || accepted[1].ID != 105 {
+		t.Errorf("expected submissions 103 and 105, got %+v", accepted)
+	}
+
+	accepted, newest = newAccepted(nil, 102)
+	if newest != 102 || len(accepted) != 0 {
+		t.Errorf("expected nothing new, got %+v and newest %d", accepted, newest)
+	}
+}
+
+func Test_GetFeedReason(t *testing.T) {
+	tests := []struct {
+		name            string
+		participantType string
+		problemRating   uint16
+		userRating      int
+		expected        feedReason
+	}{
+		{"in round", "CONTESTANT", 800, 1900, feedReasonInRound},
+		{"above rating", "PRACTICE", 1600, 1500, feedReasonAboveRating},
+		{"at rating", "PRACTICE", 1500, 1500, feedReasonNone},
+		{"virtual", "VIRTUAL", 2000, 1500, feedReasonAboveRating},
+		{"unrated user", "PRACTICE", 1600, 0, feedReasonNone},
+		{"unrated problem", "PRACTICE", 0, 1500, feedReasonNone},
+	}
+
+	for _, test := range tests {
+		sub := newSubmission(1850, "A", "OK", time.Now())
+		sub.Author.ParticipantType = test.participantType
+		sub.Problem.Rating = test.problemRating
+		if got := getFeedReason(&sub, test.userRating); got != test.expected {
+			t.Errorf("%s: expected %d, got %d", test.name, test.expected, got)
+		}
+	}
+}
+
+func Test_PassesMinRating(t *testing.T) {
+	settings := SubmissionFeedSettings{MinRating: 1600}
+	tests := []struct {
+		name          string
+		problemRating uint16
+		reason        feedReason
+		expected      bool
+	}{
+		{"in round unrated", 0, feedReasonInRound, true},
+		{"in round below", 800, feedReasonInRound, true},
+		{"above rating below minimum", 1500, feedReasonAboveRating, false},
+		{"above rating at minimum", 1600, feedReasonAboveRating, true},
+	}
+
+	for _, test := range tests {
+		sub := newSubmission(1850, "A", "OK", time.Now())
+		sub.Problem.Rating = test.problemRating
+		if got := passesMinRating(&settings, &sub, test.reason); got != test.expected {
+			t.Errorf("%s: expected %t, got %t", test.name, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,512 @@
+package codeforces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+	"github.com/yuqzii/konkurransetilsynet/internal/utils"
+)
+
+var (
+	ErrTeamNameTaken = errors.New("team name is taken")
+	ErrAlreadyInTeam = errors.New("user is already in a team")
+)
+
+type TeamRepository interface {
+	// Stores the team and its members, and returns its ID. Returns ErrTeamNameTaken or
+	// ErrAlreadyInTeam if the name or one of the members is taken in the guild.
+	CreateTeam(ctx context.Context, t Team) (int, error)
+	DeleteTeam(ctx context.Context, teamID int) error
+	GetTeams(ctx context.Context, guildID string) ([]Team, error)
+
+	// Stores the training and its teams, and returns its ID.
+	CreateTeamTraining(ctx context.Context, t TeamTraining) (int, error)
+	AddTeamTrainingTeam(ctx context.Context, trainingID, teamID int) error
+	SetTeamTrainingScoreboardMessage(ctx context.Context, trainingID int, messageID string) error
+	// Stores the final scoreboard and marks the training as finished.
+	FinishTeamTraining(ctx context.Context, trainingID int, results []ScoreboardRow) error
+	// Returns the trainings that have not finished.
+	GetActiveTeamTrainings(ctx context.Context) ([]TeamTraining, error)
+	// Returns the latest finished trainings of the guild, newest first.
+	GetFinishedTeamTrainings(ctx context.Context, guildID string, limit int) ([]TeamTraining, error)
+}
+
+// An ICPC style team of up to three members with linked Codeforces accounts.
+type Team struct {
+	ID      int
+	GuildID string
+	Name    string
+	// Discord IDs of the members
+	Members []string
+}
+
+// A gym contest or mashup solved in teams, where every accepted submission of a member counts
+// for the team.
+type TeamTraining struct {
+	LiveContest
+	// IDs of the participating teams
+	Teams []int
+}
+
+const maxTeamSize int = 3
+
+var teamNameRegex = regexp.MustCompile(`^[\w-]{1,32}$`)
+
+type teamService struct {
+	liveContestRunner
+
+	// Trainings that have not finished, by guild
+	active map[string]*TeamTraining
+}
+
+func newTeamService(discord *discordgo.Session, db Repository, client api) *teamService {
+	const (
+		defaultPollInterval    time.Duration = 1 * time.Minute
+		defaultSubmissionCount uint16        = 100
+	)
+
+	return &teamService{
+		liveContestRunner: liveContestRunner{
+			discord:         discord,
+			db:              db,
+			client:          client,
+			pollInterval:    defaultPollInterval,
+			submissionCount: defaultSubmissionCount,
+		},
+		active: make(map[string]*TeamTraining),
+	}
+}
+
+// Restarts the trainings that had not finished when the bot stopped.
+func (s *teamService) resume(ctx context.Context) error {
+	trainings, err := s.db.GetActiveTeamTrainings(ctx)
+	if err != nil {
+		return fmt.Errorf("getting active team trainings: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range trainings {
+		s.active[t.GuildID] = &t
+		go s.run(&t.LiveContest, &teamTrainingRun{s: s, t: &t})
+	}
+	return nil
+}
+
+func (s *teamService) handleCommand(args []string, m *discordgo.MessageCreate) error {
+	if len(args) < 3 {
+		return s.sendUsage(m.ChannelID)
+	}
+
+	switch args[2] {
+	case "create":
+		return s.createCommand(args, m)
+	case "disband":
+		return s.disbandCommand(m)
+	case "list":
+		return s.listCommand(m)
+	case "train":
+		return s.trainCommand(args, m)
+	case "join":
+		return s.joinCommand(m)
+	case "results":
+		return s.resultsCommand(args, m)
+	default:
+		return s.sendUsage(m.ChannelID)
+	}
+}
+
+func (s *teamService) sendUsage(channelID string) error {
+	msg := "Usage:\n" +
+		"`!cf team create [name] [@teammates (up to two)]`\n" +
+		"`!cf team disband`\n" +
+		"`!cf team list`\n" +
+		"`!cf team train [gym contest ID or mashup name] [duration, e.g. 5h] [starts in, e.g. 10m (optional)]`\n" +
+		"`!cf team join`\n" +
+		"`!cf team results [name (optional)]`"
+	_, err := s.discord.ChannelMessageSend(channelID, msg)
+	return err
+}
+
+// create <name> [@members]
+func (s *teamService) createCommand(args []string, m *discordgo.MessageCreate) error {
+	if len(args) < 4 {
+		return s.sendUsage(m.ChannelID)
+	}
+	name := args[3]
+	if !teamNameRegex.MatchString(name) {
+		_, err := s.discord.ChannelMessageSend(m.ChannelID,
+			"Team names can only contain letters, digits, `_` and `-`, and be at most 32 characters long.")
+		return err
+	}
+
+	members := []string{m.Author.ID}
+	for _, user := range m.Mentions {
+		if !user.Bot && !slices.Contains(members, user.ID) {
+			members = append(members, user.ID)
+		}
+	}
+	if len(members) > maxTeamSize {
+		_, err := s.discord.ChannelMessageSend(m.ChannelID,
+			fmt.Sprintf("A team can have at most %d members.", maxTeamSize))
+		return err
+	}
+
+	for _, discID := range members {
+		_, err := s.db.GetLinkedAccount(context.TODO(), judge.Codeforces, discID)
+		if errors.Is(err, judge.ErrAccountNotLinked) {
+			_, err := s.discord.ChannelMessageSend(m.ChannelID,
+				fmt.Sprintf("<@%s> has to connect their Codeforces account with `!cf authenticate` first.", discID))
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("getting handle of %s: %w", discID, err)
+		}
+	}
+
+	team := Team{GuildID: m.GuildID, Name: name, Members: members}
+	_, err := s.db.CreateTeam(context.TODO(), team)
+	if errors.Is(err, ErrTeamNameTaken) {
+		_, err := s.discord.ChannelMessageSend(m.ChannelID, fmt.Sprintf("There is already a team named **%s**.", name))
+		return err
+	}
+	if errors.Is(err, ErrAlreadyInTeam) {
+		_, err := s.discord.ChannelMessageSend(m.ChannelID,
+			"Everyone has to leave their current team before joining a new one.")
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("storing team %s: %w", name, err)
+	}
+
+	_, err = s.discord.ChannelMessageSend(m.ChannelID,
+		fmt.Sprintf("Created team **%s** with %s.", name, formatMembers(members)))
+	return err
+}
+
+func (s *teamService) disbandCommand(m *discordgo.MessageCreate) error {
+	team, err := s.getUserTeam(context.TODO(), m.GuildID, m.Author.ID)
+	if err != nil {
+		return err
+	}
+	if team == nil {
+		_, err := s.discord.ChannelMessageSend(m.ChannelID, "You are not in a team.")
+		return err
+	}
+
+	if err := s.db.DeleteTeam(context.TODO(), team.ID); err != nil {
+		return fmt.Errorf("deleting team %s: %w", team.Name, err)
+	}
+	_, err = s.discord.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Team **%s** has been disbanded.", team.Name))
+	return err
+}
+
+func (s *teamService) listCommand(m *discordgo.MessageCreate) error {
+	teams, err := s.db.GetTeams(context.TODO(), m.GuildID)
+	if err != nil {
+		return fmt.Errorf("getting teams: %w", err)
+	}
+	if len(teams) == 0 {
+		_, err := s.discord.ChannelMessageSend(m.ChannelID, "There are no teams in this server yet.")
+		return err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Teams")
+	for _, t := range teams {
+		fmt.Fprintf(&sb, "\n**%s**: %s", t.Name, formatMembers(t.Members))
+	}
+	msgData := discordgo.MessageSend{
+		Content: sb.String(),
+		// Mentions are only used to show the members
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}
+	return utils.SendSplitMessage(s.discord, m.ChannelID, &msgData)
+}
+
+// Returns the team of the user in the guild, or nil if the user is not in a team.
+func (s *teamService) getUserTeam(ctx context.Context, guildID, discID string) (*Team, error) {
+	teams, err := s.db.GetTeams(ctx, guildID)
+	if err != nil {
+		return nil, fmt.Errorf("getting teams: %w", err)
+	}
+	idx := slices.IndexFunc(teams, func(t Team) bool {
+		return slices.Contains(t.Members, discID)
+	})
+	if idx == -1 {
+		return nil, nil
+	}
+	return &teams[idx], nil
+}
+
+// train <gym contest ID | mashup name> <duration> [starts in]
+func (s *teamService) trainCommand(args []string, m *discordgo.MessageCreate) error {
+	if len(args) < 5 || len(args) > 6 {
+		return s.sendUsage(m.ChannelID)
+	}
+
+	var delayArg string
+	if len(args) == 6 {
+		delayArg = args[5]
+	}
+	start, duration, err := parseSchedule(args[4], delayArg, "5h")
+	var inputErr *inputError
+	if errors.As(err, &inputErr) {
+		_, err := s.discord.ChannelMessageSend(m.ChannelID, inputErr.Error())
+		return err
+	}
+
+	s.mu.Lock()
+	existing, hasActive := s.active[m.GuildID]
+	s.mu.Unlock()
+	if hasActive {
+		_, err := s.discord.ChannelMessageSend(m.ChannelID,
+			fmt.Sprintf("**%s** has to finish before a new team training can be started.", existing.Name))
+		return err
+	}
+
+	name, problems, err := s.trainingProblems(context.TODO(), m.GuildID, args[3])
+	if errors.As(err, &inputErr) {
+		_, err := s.discord.ChannelMessageSend(m.ChannelID, inputErr.Error())
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("getting training problems: %w", err)
+	}
+
+	training := TeamTraining{LiveContest: LiveContest{
+		GuildID:   m.GuildID,
+		ChannelID: m.ChannelID,
+		CreatorID: m.Author.ID,
+		Name:      name,
+		Problems:  problems,
+		Start:     start,
+		Duration:  duration,
+	}}
+	// The team of the creator participates
+	team, err := s.getUserTeam(context.TODO(), m.GuildID, m.Author.ID)
+	if err != nil {
+		return err
+	}
+	if team != nil {
+		training.Teams = []int{team.ID}
+	}
+
+	s.mu.Lock()
+	if _, ok := s.active[m.GuildID]; ok {
+		s.mu.Unlock()
+		return errors.New("another team training was started at the same time")
+	}
+	training.ID, err = s.db.CreateTeamTraining(context.TODO(), training)
+	if err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("storing team training: %w", err)
+	}
+	s.active[m.GuildID] = &training
+	s.mu.Unlock()
+
+	msg := fmt.Sprintf("Team training **%s** starts <t:%d:R> and lasts %s with %d problems. "+
+		"Register your team with `!cf team join`.", training.Name, training.Start.Unix(), duration, len(problems))
+	if _, err := s.discord.ChannelMessageSend(m.ChannelID, msg); err != nil {
+		log.Printf("Failed to announce team training %s: %s", training.Name, err)
+	}
+
+	go s.run(&training.LiveContest, &teamTrainingRun{s: s, t: &training})
+	return nil
+}
+
+// Returns the name and problems of the gym or contest with the given ID, or of the finished mashup
+// in the guild with the given name.
+func (s *teamService) trainingProblems(ctx context.Context, guildID, source string) (string, []MashupProblem, error) {
+	const mashupSearchCount int = 50
+
+	if contestID, err := strconv.Atoi(source); err == nil {
+		con, problems, err := s.client.getContestProblems(ctx, contestID)
+		if errors.Is(err, ErrClientIssue) {
+			return "", nil, &inputError{fmt.Sprintf("Could not find a contest or gym with ID `%d`.", contestID)}
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("getting problems of contest %d: %w", contestID, err)
+		}
+		if len(problems) == 0 {
+			return "", nil, &inputError{fmt.Sprintf("**%s** has no problems.", con.Name)}
+		}
+
+		result := make([]MashupProblem, len(problems))
+		for i := range problems {
+			result[i] = toMashupProblem(&problems[i])
+		}
+		return con.Name, result, nil
+	}
+
+	// Problems of mashups that have not finished are still secret
+	mashups, err := s.db.GetFinishedMashups(ctx, guildID, mashupSearchCount)
+	if err != nil {
+		return "", nil, fmt.Errorf("getting finished mashups: %w", err)
+	}
+	idx := slices.IndexFunc(mashups, func(mashup Mashup) bool {
+		return strings.EqualFold(mashup.Name, source)
+	})
+	if idx == -1 {
+		return "", nil, &inputError{fmt.Sprintf("Found no finished mashup named **%s**.", source)}
+	}
+	return mashups[idx].Name, mashups[idx].Problems, nil
+}
+
+func (s *teamService) joinCommand(m *discordgo.MessageCreate) error {
+	s.mu.Lock()
+	training, ok := s.active[m.GuildID]
+	s.mu.Unlock()
+	if !ok {
+		_, err := s.discord.ChannelMessageSend(m.ChannelID,
+			"There is no team training to join. Start one with `!cf team train`.")
+		return err
+	}
+
+	team, err := s.getUserTeam(context.TODO(), m.GuildID, m.Author.ID)
+	if err != nil {
+		return err
+	}
+	if team == nil {
+		_, err := s.discord.ChannelMessageSend(m.ChannelID,
+			"You have to be in a team to join team trainings. Create one with `!cf team create`.")
+		return err
+	}
+
+	s.mu.Lock()
+	if slices.Contains(training.Teams, team.ID) {
+		s.mu.Unlock()
+		_, err := s.discord.ChannelMessageSend(m.ChannelID,
+			fmt.Sprintf("**%s** is already participating in **%s**.", team.Name, training.Name))
+		return err
+	}
+	err = s.db.AddTeamTrainingTeam(context.TODO(), training.ID, team.ID)
+	if err == nil {
+		training.Teams = append(training.Teams, team.ID)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("adding team to training: %w", err)
+	}
+
+	_, err = s.discord.ChannelMessageSend(m.ChannelID,
+		fmt.Sprintf("**%s** joined **%s**.", team.Name, training.Name))
+	return err
+}
+
+// Adapts a team training to the live contest lifecycle.
+type teamTrainingRun struct {
+	s *teamService
+	t *TeamTraining
+}
+
+func (r *teamTrainingRun) title() string {
+	return "Team training " + r.t.Name
+}
+
+func (r *teamTrainingRun) kind() string {
+	return "team training"
+}
+
+func (r *teamTrainingRun) mentions(ctx context.Context) ([]string, error) {
+	teams, err := r.s.participatingTeams(ctx, r.t)
+	if err != nil {
+		return nil, err
+	}
+	var members []string
+	for _, team := range teams {
+		members = append(members, team.Members...)
+	}
+	return members, nil
+}
+
+// Every team is one entrant named after the team, with the submissions of all its members.
+func (r *teamTrainingRun) entrants(ctx context.Context) ([]entrant, error) {
+	teams, err := r.s.participatingTeams(ctx, r.t)
+	if err != nil {
+		return nil, err
+	}
+	entrants := make([]entrant, len(teams))
+	for i, team := range teams {
+		entrants[i] = entrant{name: team.Name, members: team.Members}
+	}
+	return entrants, nil
+}
+
+func (r *teamTrainingRun) setScoreboardMessage(ctx context.Context, messageID string) error {
+	return r.s.db.SetTeamTrainingScoreboardMessage(ctx, r.t.ID, messageID)
+}
+
+func (r *teamTrainingRun) archive(ctx context.Context, rows []ScoreboardRow) error {
+	return r.s.db.FinishTeamTraining(ctx, r.t.ID, rows)
+}
+
+func (r *teamTrainingRun) deactivate() {
+	delete(r.s.active, r.t.GuildID)
+}
+
+// Returns the teams registered for the training that have not been disbanded.
+func (s *teamService) participatingTeams(ctx context.Context, t *TeamTraining) ([]Team, error) {
+	s.mu.Lock()
+	ids := slices.Clone(t.Teams)
+	s.mu.Unlock()
+
+	teams, err := s.db.GetTeams(ctx, t.GuildID)
+	if err != nil {
+		return nil, fmt.Errorf("getting teams: %w", err)
+	}
+	return slices.DeleteFunc(teams, func(team Team) bool {
+		return !slices.Contains(ids, team.ID)
+	}), nil
+}
+
+// results [name]
+func (s *teamService) resultsCommand(args []string, m *discordgo.MessageCreate) error {
+	const listCount int = 10
+
+	trainings, err := s.db.GetFinishedTeamTrainings(context.TODO(), m.GuildID, listCount)
+	if err != nil {
+		return fmt.Errorf("getting finished team trainings: %w", err)
+	}
+	if len(trainings) == 0 {
+		_, err := s.discord.ChannelMessageSend(m.ChannelID, "No team trainings have finished in this server yet.")
+		return err
+	}
+
+	training := &trainings[0]
+	if len(args) >= 4 {
+		name := strings.Join(args[3:], " ")
+		idx := slices.IndexFunc(trainings, func(t TeamTraining) bool {
+			return strings.EqualFold(t.Name, name)
+		})
+		if idx == -1 {
+			_, err := s.discord.ChannelMessageSend(m.ChannelID,
+				fmt.Sprintf("Found no recent team training named **%s**.", name))
+			return err
+		}
+		training = &trainings[idx]
+	}
+
+	names := make([]string, len(trainings))
+	for i, other := range trainings {
+		names[i] = other.Name
+	}
+	return s.sendResults(m.ChannelID, &training.LiveContest, "Recent team trainings", names)
+}
+
+func formatMembers(members []string) string {
+	mentions := make([]string, len(members))
+	for i, id := range members {
+		mentions[i] = fmt.Sprintf("<@%s>", id)
+	}
+	return strings.Join(mentions, ", ")
+}
@@ -0,0 +1,145 @@
+package codeforces
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+)
+
+const (
+	verifierCompilationError = "ce"
+	verifierProfile          = "profile"
+)
+
+func (c *client) DefaultVerifier() string {
+	return verifierCompilationError
+}
+
+func (c *client) NewVerifier(method string) (judge.Verifier, bool) {
+	switch method {
+	case verifierCompilationError:
+		return &compilationErrorVerifier{
+			client:           c,
+			maxProblemRating: c.maxAuthProblemRating,
+			submissionCount:  c.authSubmissionCount,
+			timeout:          c.compilationErrorTimeout,
+		}, true
+	case verifierProfile:
+		return &profileTokenVerifier{client: c, timeout: c.profileTimeout}, true
+	default:
+		return nil, false
+	}
+}
+
+// Verifies by having the user submit a compilation error to a random problem.
+type compilationErrorVerifier struct {
+	client           api
+	maxProblemRating uint16
+	submissionCount  uint16
+	timeout          time.Duration
+
+	prob      *problem
+	startTime int64
+}
+
+func (v *compilationErrorVerifier) Prepare(ctx context.Context, handle string) (string, error) {
+	// Get random problem with a rating <= maxProblemRating
+	problems, err := v.client.getProblems(ctx)
+	if err != nil {
+		return "", fmt.Errorf("getting problems from Codeforces API: %w", err)
+	}
+	problems = filterProblems(problems, func(prob *problem) bool {
+		return prob.Rating <= v.maxProblemRating
+	})
+	prob, err := getRandomProblem(problems)
+	if err != nil {
+		return "", err
+	}
+
+	debug := os.Getenv("DEBUG")
+	if debug == "true" {
+		var testProblem = &problem{
+			ContestID: 1627,
+			Index:     "C",
+			Name:      "Not Assigning",
+			Rating:    1400,
+		}
+		prob = testProblem
+	}
+
+	v.prob = prob
+	v.startTime = time.Now().Unix()
+
+	probLink := fmt.Sprintf("https://codeforces.com/problemset/problem/%d/%s", prob.ContestID, prob.Index)
+	return fmt.Sprintf("Submit a compilation error to [%s - %d%s](%s)",
+		prob.Name, prob.ContestID, prob.Index, probLink), nil
+}
+
+func (v *compilationErrorVerifier) Check(ctx context.Context, handle string) (bool, error) {
+	// Get submissions and check if any of them match the criteria
+	subs, err := v.client.getSubmissions(ctx, handle, v.submissionCount)
+	if err != nil {
+		return false, fmt.Errorf("getting submissions from user '%s': %w", handle, err)
+	}
+	return checkSubmissions(subs, v.startTime, v.prob.ContestID, v.prob.Index), nil
+}
+
+func (v *compilationErrorVerifier) FailReason() string {
+	probLink := fmt.Sprintf("https://codeforces.com/problemset/problem/%d/%s", v.prob.ContestID, v.prob.Index)
+	return fmt.Sprintf("Did not find a compilation error submitted to [%s - %d%s](%s).",
+		v.prob.Name, v.prob.ContestID, v.prob.Index, probLink)
+}
+
+func (v *compilationErrorVerifier) Timeout() time.Duration {
+	return v.timeout
+}
+
+// Verifies by having the user put a random token in the first name or organization field of
+// their Codeforces profile.
+type profileTokenVerifier struct {
+	client  api
+	timeout time.Duration
+
+	token string
+}
+
+func (v *profileTokenVerifier) Prepare(ctx context.Context, handle string) (string, error) {
+	token, err := generateAuthToken()
+	if err != nil {
+		return "", fmt.Errorf("generating authentication token: %w", err)
+	}
+	v.token = token
+
+	return fmt.Sprintf("Put `%s` in the first name or organization field of your "+
+		"[Codeforces profile](https://codeforces.com/settings/social)", v.token), nil
+}
+
+func (v *profileTokenVerifier) Check(ctx context.Context, handle string) (bool, error) {
+	u, err := v.client.getUserInfo(ctx, handle, false)
+	if err != nil {
+		return false, fmt.Errorf("getting user info of '%s': %w", handle, err)
+	}
+	return strings.Contains(u.FirstName, v.token) || strings.Contains(u.Organization, v.token), nil
+}
+
+func (v *profileTokenVerifier) FailReason() string {
+	return fmt.Sprintf("Did not find `%s` in the first name or organization field of the profile.", v.token)
+}
+
+func (v *profileTokenVerifier) Timeout() time.Duration {
+	return v.timeout
+}
+
+func generateAuthToken() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "konk-" + hex.EncodeToString(b), nil
+}
@@ -6,8 +6,9 @@ import (
 	"fmt"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/yuqzii/konkurransetilsynet/internal/codeforces"
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
 )
 
 type db struct {
@@ -46,22 +47,7 @@ func connectToDatabase(ctx context.Context, host, user, password, dbName string,
 	return dbpool, nil
 }
 
-func (db *db) DiscordIDExists(ctx context.Context, discID string) (bool, error) {
-	var dbDiscID string
-	err := db.conn.QueryRow(ctx,
-		"SELECT discord_id FROM user_data WHERE discord_id=$1;", discID).Scan(&dbDiscID)
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			return false, nil
-		} else {
-			return false, err
-		}
-	}
-
-	return true, nil
-}
-
-func (db *db) AddCodeforcesUser(ctx context.Context, discID, handle string) error {
+func (db *db) LinkAccount(ctx context.Context, platform, discID, handle string) error {
 	tx, err := db.conn.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to start transaction: %w", err)
@@ -69,49 +55,125 @@ func (db *db) AddCodeforcesUser(ctx context.Context, discID, handle string) erro
 	defer tx.Rollback(ctx) // nolint: errcheck
 
 	_, err = tx.Exec(ctx,
-		"INSERT INTO user_data (discord_id, codeforces_handle) VALUES ($1, $2);", discID, handle)
+		"INSERT INTO user_data (discord_id) VALUES ($1) ON CONFLICT DO NOTHING;", discID)
+	if err != nil {
+		return fmt.Errorf("failed to insert discord id %s into user_data: %w", discID, err)
+	}
+
+	_, err = tx.Exec(ctx,
+		"INSERT INTO linked_accounts (discord_id, platform, handle) VALUES ($1, $2, $3) "+
+			"ON CONFLICT (discord_id, platform) DO UPDATE SET handle=EXCLUDED.handle;",
+		discID, platform, handle)
+	if isUniqueViolation(err) {
+		return judge.ErrHandleTaken
+	}
 	if err != nil {
-		return fmt.Errorf("failed to insert discord id %s and codeforces name '%s' into user_data: %w",
-			discID, handle, err)
+		return fmt.Errorf("failed to link discord id %s to %s handle '%s': %w", discID, platform, handle, err)
 	}
 
 	err = tx.Commit(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to commit insertion to user_data: %w", err)
+		return fmt.Errorf("failed to commit linked account: %w", err)
 	}
 	return nil
 }
 
-func (db *db) UpdateCodeforcesUser(ctx context.Context, discID, handle string) error {
-	tx, err := db.conn.Begin(ctx)
+func (db *db) UnlinkAccount(ctx context.Context, platform, discID string) error {
+	tag, err := db.conn.Exec(ctx,
+		"DELETE FROM linked_accounts WHERE discord_id=$1 AND platform=$2;", discID, platform)
 	if err != nil {
-		return fmt.Errorf("failed to start transaction: %w", err)
+		return fmt.Errorf("failed to unlink %s account of discord id %s: %w", platform, discID, err)
 	}
-	defer tx.Rollback(ctx) // nolint: errcheck
-
-	_, err = tx.Exec(ctx,
-		"UPDATE user_data SET codeforces_handle=$1 WHERE discord_id=$2;", handle, discID)
-	if err != nil {
-		return fmt.Errorf("failed to update the codeforces handle belonging to discord id %s to '%s': %w",
-			discID, handle, err)
+	if tag.RowsAffected() == 0 {
+		return judge.ErrAccountNotLinked
 	}
+	return nil
+}
 
-	err = tx.Commit(ctx)
+func (db *db) GetLinkedAccount(ctx context.Context, platform, discID string) (handle string, err error) {
+	err = db.conn.QueryRow(ctx,
+		"SELECT handle FROM linked_accounts WHERE discord_id=$1 AND platform=$2;", discID, platform).Scan(&handle)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", judge.ErrAccountNotLinked
+	}
 	if err != nil {
-		return fmt.Errorf("failed to commit update to user_data: %w", err)
+		return "", err
 	}
-	return nil
+
+	return handle, nil
 }
 
-func (db *db) GetConnectedCodeforces(ctx context.Context, discID string) (connectedHandle string, err error) {
+// Returns the Discord ID of the user the handle is linked to. Handles are compared
+// case-insensitively, since the platforms treat them that way.
+func (db *db) GetAccountOwner(ctx context.Context, platform, handle string) (discID string, err error) {
 	err = db.conn.QueryRow(ctx,
-		"SELECT codeforces_handle FROM user_data WHERE discord_id=$1", discID).Scan(&connectedHandle)
+		"SELECT discord_id::TEXT FROM linked_accounts WHERE platform=$1 AND LOWER(handle)=LOWER($2);",
+		platform, handle).Scan(&discID)
 	if errors.Is(err, pgx.ErrNoRows) {
-		return "", codeforces.ErrUserNotConnected
+		return "", judge.ErrAccountNotLinked
 	}
 	if err != nil {
 		return "", err
 	}
 
-	return connectedHandle, nil
+	return discID, nil
+}
+
+func (db *db) GetLinkedAccounts(ctx context.Context, platform string) ([]judge.Account, error) {
+	rows, err := db.conn.Query(ctx,
+		"SELECT discord_id::TEXT, platform, handle FROM linked_accounts WHERE platform=$1;", platform)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query linked %s accounts: %w", platform, err)
+	}
+
+	accounts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (judge.Account, error) {
+		var a judge.Account
+		err := row.Scan(&a.DiscordID, &a.Platform, &a.Handle)
+		return a, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read linked %s accounts: %w", platform, err)
+	}
+	return accounts, nil
+}
+
+func (db *db) AddAuditEntry(ctx context.Context, entry judge.AuditEntry) error {
+	_, err := db.conn.Exec(ctx,
+		"INSERT INTO connection_audit_log (platform, guild_id, discord_id, actor_id, action, old_handle, new_handle) "+
+			"VALUES ($1, NULLIF($2, '')::NUMERIC, $3, NULLIF($4, '')::NUMERIC, $5, NULLIF($6, ''), NULLIF($7, ''));",
+		entry.Platform, entry.GuildID, entry.DiscordID, entry.ActorID, entry.Action, entry.OldHandle, entry.NewHandle)
+	if err != nil {
+		return fmt.Errorf("failed to insert %s audit entry for discord id %s: %w", entry.Action, entry.DiscordID, err)
+	}
+	return nil
+}
+
+func (db *db) GetAuditEntries(ctx context.Context, platform, guildID string, memberIDs []string,
+	limit int) ([]judge.AuditEntry, error) {
+
+	rows, err := db.conn.Query(ctx,
+		"SELECT platform, COALESCE(guild_id::TEXT, ''), discord_id::TEXT, COALESCE(actor_id::TEXT, ''), action, "+
+			"COALESCE(old_handle, ''), COALESCE(new_handle, ''), created_at "+
+			"FROM connection_audit_log WHERE platform=$1 "+
+			"AND (guild_id=$2::NUMERIC OR (guild_id IS NULL AND discord_id::TEXT = ANY($3))) "+
+			"ORDER BY created_at DESC LIMIT $4;", platform, guildID, memberIDs, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit entries: %w", err)
+	}
+
+	entries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (judge.AuditEntry, error) {
+		var e judge.AuditEntry
+		err := row.Scan(&e.Platform, &e.GuildID, &e.DiscordID, &e.ActorID, &e.Action, &e.OldHandle, &e.NewHandle,
+			&e.Time)
+		return e, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit entries: %w", err)
+	}
+	return entries, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
 }
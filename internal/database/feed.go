@@ -0,0 +1,33 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (db *db) GetDisabledPlatforms(ctx context.Context, guildID string) ([]string, error) {
+	rows, err := db.conn.Query(ctx,
+		"SELECT platform FROM guild_platform_settings WHERE guild_id=$1 AND NOT enabled;", guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query disabled platforms of guild %s: %w", guildID, err)
+	}
+
+	platforms, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read disabled platforms of guild %s: %w", guildID, err)
+	}
+	return platforms, nil
+}
+
+func (db *db) SetPlatformEnabled(ctx context.Context, guildID, platform string, enabled bool) error {
+	_, err := db.conn.Exec(ctx,
+		"INSERT INTO guild_platform_settings (guild_id, platform, enabled) VALUES ($1, $2, $3) "+
+			"ON CONFLICT (guild_id, platform) DO UPDATE SET enabled=EXCLUDED.enabled;",
+		guildID, platform, enabled)
+	if err != nil {
+		return fmt.Errorf("failed to set %s enabled=%t in guild %s: %w", platform, enabled, guildID, err)
+	}
+	return nil
+}
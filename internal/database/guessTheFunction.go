@@ -0,0 +1,195 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yuqzii/konkurransetilsynet/internal/guessTheFunction"
+)
+
+func (db *db) AddScore(ctx context.Context, s guessTheFunction.Score) error {
+	_, err := db.conn.Exec(ctx,
+		"INSERT INTO gtf_scores (guild_id, discord_id, username, points, queries, duration_seconds, scored_at) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7);",
+		s.GuildID, s.DiscordID, s.Username, s.Points, s.Queries, s.Duration.Seconds(), s.Time)
+	if err != nil {
+		return fmt.Errorf("failed to insert score of %s in guild %s: %w", s.DiscordID, s.GuildID, err)
+	}
+	return nil
+}
+
+func (db *db) GetLeaderboard(ctx context.Context, guildID string) ([]guessTheFunction.PlayerTotal, error) {
+	rows, err := db.conn.Query(ctx,
+		"SELECT discord_id::TEXT, (ARRAY_AGG(username ORDER BY scored_at DESC))[1], SUM(points), COUNT(*) "+
+			"FROM gtf_scores WHERE guild_id=$1 GROUP BY discord_id ORDER BY SUM(points) DESC;", guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scores of guild %s: %w", guildID, err)
+	}
+
+	totals, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (guessTheFunction.PlayerTotal, error) {
+		var t guessTheFunction.PlayerTotal
+		err := row.Scan(&t.DiscordID, &t.Username, &t.Points, &t.Rounds)
+		return t, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scores of guild %s: %w", guildID, err)
+	}
+	return totals, nil
+}
+
+func (db *db) CreateRound(ctx context.Context, r *guessTheFunction.Round) error {
+	err := db.conn.QueryRow(ctx,
+		"INSERT INTO gtf_rounds (guild_id, channel_id, author_id, definition, function, lower_bound, upper_bound, "+
+			"start_time) VALUES (NULLIF($1, '')::NUMERIC, $2, $3, $4, $5, $6, $7, $8) RETURNING id;",
+		r.GuildID, r.ChannelID, r.AuthorID, r.Definition, r.Function, r.LowerBound, r.UpperBound, r.Start).Scan(&r.ID)
+	if isUniqueViolation(err) {
+		return guessTheFunction.ErrActiveRound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert round in channel %s: %w", r.ChannelID, err)
+	}
+	return nil
+}
+
+func (db *db) GetActiveRound(ctx context.Context, channelID string) (*guessTheFunction.Round, error) {
+	rounds, err := db.queryRounds(ctx, "WHERE r.channel_id=$1 AND r.end_time IS NULL", channelID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rounds) == 0 {
+		return nil, guessTheFunction.ErrNoRound
+	}
+	return &rounds[0], nil
+}
+
+func (db *db) GetFinishedRounds(ctx context.Context, channelID string, limit int) ([]guessTheFunction.Round, error) {
+	return db.queryRounds(ctx, "WHERE r.channel_id=$1 AND r.end_time IS NOT NULL ORDER BY r.end_time DESC LIMIT $2",
+		channelID, limit)
+}
+
+func (db *db) AddQuery(ctx context.Context, roundID int, q guessTheFunction.Query) error {
+	_, err := db.conn.Exec(ctx,
+		"INSERT INTO gtf_queries (round_id, discord_id, x, y, derivative, cost, query_time) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7);",
+		roundID, q.UserID, q.X, q.Y, q.Derivative, q.Cost, q.Time)
+	if err != nil {
+		return fmt.Errorf("failed to insert query of round %d: %w", roundID, err)
+	}
+	return nil
+}
+
+func (db *db) AddGuess(ctx context.Context, roundID int, g guessTheFunction.Guess) error {
+	_, err := db.conn.Exec(ctx,
+		"INSERT INTO gtf_guesses (round_id, discord_id, definition, correct, guess_time) VALUES ($1, $2, $3, $4, $5);",
+		roundID, g.UserID, g.Definition, g.Correct, g.Time)
+	if err != nil {
+		return fmt.Errorf("failed to insert guess of round %d: %w", roundID, err)
+	}
+	return nil
+}
+
+func (db *db) FinishRound(ctx context.Context, roundID int, winnerID string, end time.Time) error {
+	_, err := db.conn.Exec(ctx,
+		"UPDATE gtf_rounds SET end_time=$2, winner_id=NULLIF($3, '')::NUMERIC WHERE id=$1;", roundID, end, winnerID)
+	if err != nil {
+		return fmt.Errorf("failed to finish round %d: %w", roundID, err)
+	}
+	return nil
+}
+
+// Queries rounds with their queries and guesses. The condition is appended to the query, and may
+// refer to the rounds table as r.
+func (db *db) queryRounds(ctx context.Context, condition string, args ...any) ([]guessTheFunction.Round, error) {
+	rows, err := db.conn.Query(ctx,
+		"SELECT r.id, COALESCE(r.guild_id::TEXT, ''), r.channel_id::TEXT, r.author_id::TEXT, r.definition, "+
+			"r.function, r.lower_bound, r.upper_bound, r.start_time, r.end_time, COALESCE(r.winner_id::TEXT, '') "+
+			"FROM gtf_rounds r "+condition+";", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rounds: %w", err)
+	}
+
+	rounds, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (guessTheFunction.Round, error) {
+		var (
+			r   guessTheFunction.Round
+			end *time.Time
+		)
+		err := row.Scan(&r.ID, &r.GuildID, &r.ChannelID, &r.AuthorID, &r.Definition, &r.Function,
+			&r.LowerBound, &r.UpperBound, &r.Start, &end, &r.WinnerID)
+		if end != nil {
+			r.End = *end
+		}
+		return r, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rounds: %w", err)
+	}
+	if len(rounds) == 0 {
+		return rounds, nil
+	}
+
+	ids := make([]int, len(rounds))
+	index := make(map[int]int, len(rounds))
+	for i, r := range rounds {
+		ids[i] = r.ID
+		index[r.ID] = i
+	}
+	if err := db.loadQueries(ctx, ids, rounds, index); err != nil {
+		return nil, err
+	}
+	if err := db.loadGuesses(ctx, ids, rounds, index); err != nil {
+		return nil, err
+	}
+	return rounds, nil
+}
+
+// Adds the queries of the rounds with the IDs to the rounds, where index gives the position of
+// every round.
+func (db *db) loadQueries(ctx context.Context, ids []int, rounds []guessTheFunction.Round, index map[int]int) error {
+	rows, err := db.conn.Query(ctx,
+		"SELECT round_id, discord_id::TEXT, x, y, derivative, cost, query_time FROM gtf_queries "+
+			"WHERE round_id=ANY($1) ORDER BY query_time;", ids)
+	if err != nil {
+		return fmt.Errorf("failed to query queries of rounds: %w", err)
+	}
+
+	var (
+		roundID int
+		q       guessTheFunction.Query
+	)
+	_, err = pgx.ForEachRow(rows, []any{&roundID, &q.UserID, &q.X, &q.Y, &q.Derivative, &q.Cost, &q.Time}, func() error {
+		r := &rounds[index[roundID]]
+		r.Queries = append(r.Queries, q)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read queries of rounds: %w", err)
+	}
+	return nil
+}
+
+// Adds the guesses of the rounds with the IDs to the rounds, where index gives the position of
+// every round.
+func (db *db) loadGuesses(ctx context.Context, ids []int, rounds []guessTheFunction.Round, index map[int]int) error {
+	rows, err := db.conn.Query(ctx,
+		"SELECT round_id, discord_id::TEXT, definition, correct, guess_time FROM gtf_guesses "+
+			"WHERE round_id=ANY($1) ORDER BY guess_time;", ids)
+	if err != nil {
+		return fmt.Errorf("failed to query guesses of rounds: %w", err)
+	}
+
+	var (
+		roundID int
+		g       guessTheFunction.Guess
+	)
+	_, err = pgx.ForEachRow(rows, []any{&roundID, &g.UserID, &g.Definition, &g.Correct, &g.Time}, func() error {
+		r := &rounds[index[roundID]]
+		r.Guesses = append(r.Guesses, g)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read guesses of rounds: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yuqzii/konkurransetilsynet/internal/codeforces"
+)
+
+func (db *db) AddRatingChanges(ctx context.Context, changes []codeforces.RatingChange) error {
+	batch := &pgx.Batch{}
+	for _, c := range changes {
+		batch.Queue("INSERT INTO cf_rating_changes (handle, contest_id, old_rating, new_rating, update_time) "+
+			"VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING;",
+			c.Handle, c.ContestID, c.OldRating, c.NewRating, c.Time)
+	}
+	if err := db.conn.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to insert %d rating changes: %w", len(changes), err)
+	}
+	return nil
+}
+
+func (db *db) GetRatingChanges(ctx context.Context, handles []string) ([]codeforces.RatingChange, error) {
+	rows, err := db.conn.Query(ctx,
+		"SELECT handle, contest_id, old_rating, new_rating, update_time FROM cf_rating_changes "+
+			"WHERE handle=ANY($1) ORDER BY update_time;", handles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rating changes: %w", err)
+	}
+
+	changes, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (codeforces.RatingChange, error) {
+		var c codeforces.RatingChange
+		err := row.Scan(&c.Handle, &c.ContestID, &c.OldRating, &c.NewRating, &c.Time)
+		return c, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rating changes: %w", err)
+	}
+	return changes, nil
+}
+
+func (db *db) AddSolves(ctx context.Context, solves []codeforces.Solve) error {
+	batch := &pgx.Batch{}
+	for _, s := range solves {
+		batch.Queue("INSERT INTO cf_solves (handle, problem_id, solved_at) VALUES ($1, $2, $3) "+
+			"ON CONFLICT (handle, problem_id) DO UPDATE SET solved_at=LEAST(cf_solves.solved_at, EXCLUDED.solved_at);",
+			s.Handle, s.ProblemID, s.Time)
+	}
+	if err := db.conn.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to insert %d solves: %w", len(solves), err)
+	}
+	return nil
+}
+
+func (db *db) CountSolves(ctx context.Context, handles []string, from, to time.Time) (map[string]int, error) {
+	rows, err := db.conn.Query(ctx,
+		"SELECT handle, COUNT(*) FROM cf_solves WHERE handle=ANY($1) AND solved_at >= $2 AND solved_at < $3 "+
+			"GROUP BY handle;", handles, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count solves: %w", err)
+	}
+
+	counts := make(map[string]int)
+	var (
+		handle string
+		count  int
+	)
+	_, err = pgx.ForEachRow(rows, []any{&handle, &count}, func() error {
+		counts[handle] = count
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read solve counts: %w", err)
+	}
+	return counts, nil
+}
+
+func (db *db) HasSolves(ctx context.Context, handle string) (bool, error) {
+	var exists bool
+	err := db.conn.QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM cf_solves WHERE handle=$1);", handle).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check solves of %s: %w", handle, err)
+	}
+	return exists, nil
+}
+
+func (db *db) StartSeason(ctx context.Context, s codeforces.Season) error {
+	_, err := db.conn.Exec(ctx,
+		"INSERT INTO guild_seasons (guild_id, name, start_time) VALUES ($1, $2, $3);", s.GuildID, s.Name, s.Start)
+	if err != nil {
+		return fmt.Errorf("failed to start season %s in guild %s: %w", s.Name, s.GuildID, err)
+	}
+	return nil
+}
+
+func (db *db) GetSeasons(ctx context.Context, guildID string) ([]codeforces.Season, error) {
+	rows, err := db.conn.Query(ctx,
+		"SELECT guild_id::TEXT, name, start_time FROM guild_seasons WHERE guild_id=$1 ORDER BY start_time;", guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query seasons of guild %s: %w", guildID, err)
+	}
+
+	seasons, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (codeforces.Season, error) {
+		var s codeforces.Season
+		err := row.Scan(&s.GuildID, &s.Name, &s.Start)
+		return s, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seasons of guild %s: %w", guildID, err)
+	}
+	return seasons, nil
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yuqzii/konkurransetilsynet/internal/codeforces"
+)
+
+func (db *db) CreateMashup(ctx context.Context, m codeforces.Mashup) (int, error) {
+	tx, err := db.conn.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) // nolint: errcheck
+
+	var id int
+	err = tx.QueryRow(ctx,
+		"INSERT INTO mashups (guild_id, channel_id, creator_id, name, problems, start_time, duration_seconds) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id;",
+		m.GuildID, m.ChannelID, m.CreatorID, m.Name, m.Problems, m.Start, int(m.Duration.Seconds())).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert mashup %s: %w", m.Name, err)
+	}
+
+	for _, discID := range m.Participants {
+		_, err = tx.Exec(ctx,
+			"INSERT INTO mashup_participants (mashup_id, discord_id) VALUES ($1, $2);", id, discID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert participant %s of mashup %s: %w", discID, m.Name, err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit mashup: %w", err)
+	}
+	return id, nil
+}
+
+func (db *db) AddMashupParticipant(ctx context.Context, mashupID int, discID string) error {
+	_, err := db.conn.Exec(ctx,
+		"INSERT INTO mashup_participants (mashup_id, discord_id) VALUES ($1, $2) ON CONFLICT DO NOTHING;",
+		mashupID, discID)
+	if err != nil {
+		return fmt.Errorf("failed to add participant %s to mashup %d: %w", discID, mashupID, err)
+	}
+	return nil
+}
+
+func (db *db) SetMashupScoreboardMessage(ctx context.Context, mashupID int, messageID string) error {
+	_, err := db.conn.Exec(ctx,
+		"UPDATE mashups SET scoreboard_message_id=$2 WHERE id=$1;", mashupID, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to set scoreboard message of mashup %d: %w", mashupID, err)
+	}
+	return nil
+}
+
+func (db *db) FinishMashup(ctx context.Context, mashupID int, results []codeforces.ScoreboardRow) error {
+	_, err := db.conn.Exec(ctx,
+		"UPDATE mashups SET results=$2, finished=TRUE WHERE id=$1;", mashupID, results)
+	if err != nil {
+		return fmt.Errorf("failed to finish mashup %d: %w", mashupID, err)
+	}
+	return nil
+}
+
+func (db *db) GetActiveMashups(ctx context.Context) ([]codeforces.Mashup, error) {
+	return db.queryMashups(ctx, "WHERE NOT m.finished ORDER BY m.start_time")
+}
+
+func (db *db) GetFinishedMashups(ctx context.Context, guildID string, limit int) ([]codeforces.Mashup, error) {
+	return db.queryMashups(ctx, "WHERE m.finished AND m.guild_id=$1 ORDER BY m.start_time DESC LIMIT $2",
+		guildID, limit)
+}
+
+// Queries mashups with their participants. The condition is appended to the query, and may
+// refer to the mashups table as m.
+func (db *db) queryMashups(ctx context.Context, condition string, args ...any) ([]codeforces.Mashup, error) {
+	rows, err := db.conn.Query(ctx,
+		"SELECT m.id, m.guild_id::TEXT, m.channel_id::TEXT, m.creator_id::TEXT, m.name, m.problems, "+
+			"m.start_time, m.duration_seconds, COALESCE(m.scoreboard_message_id::TEXT, ''), m.results, "+
+			"ARRAY(SELECT p.discord_id::TEXT FROM mashup_participants p WHERE p.mashup_id=m.id) "+
+			"FROM mashups m "+condition+";", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query mashups: %w", err)
+	}
+
+	mashups, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (codeforces.Mashup, error) {
+		var (
+			m               codeforces.Mashup
+			durationSeconds int
+		)
+		err := row.Scan(&m.ID, &m.GuildID, &m.ChannelID, &m.CreatorID, &m.Name, &m.Problems,
+			&m.Start, &durationSeconds, &m.ScoreboardMessageID, &m.Results, &m.Participants)
+		m.Duration = time.Duration(durationSeconds) * time.Second
+		return m, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mashups: %w", err)
+	}
+	return mashups, nil
+}
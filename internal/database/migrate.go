@@ -0,0 +1,34 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Runs the SQL files in dir in the order of their names. Postgres only runs them itself when the
+// database is created, so they are written to be run again on every startup, which brings
+// databases created before a file was added up to date.
+func (db *db) Migrate(ctx context.Context, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("reading migration directory: %w", err)
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		sql, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", e.Name(), err)
+		}
+		// Without arguments, the statements of the file run in one implicit transaction
+		if _, err := db.conn.Exec(ctx, string(sql)); err != nil {
+			return fmt.Errorf("running %s: %w", e.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yuqzii/konkurransetilsynet/internal/olympiad"
+)
+
+func (db *db) GetOlympiadEvents(ctx context.Context) ([]olympiad.StoredEvent, error) {
+	rows, err := db.conn.Query(ctx, "SELECT id, event, removed FROM olympiad_events;")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query olympiad events: %w", err)
+	}
+
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (olympiad.StoredEvent, error) {
+		var (
+			s     olympiad.StoredEvent
+			id    string
+			event *olympiad.Event
+		)
+		if err := row.Scan(&id, &event, &s.Removed); err != nil {
+			return s, err
+		}
+		if event != nil {
+			s.Event = *event
+		}
+		s.Event.ID = id
+		return s, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read olympiad events: %w", err)
+	}
+	return events, nil
+}
+
+func (db *db) SaveOlympiadEvent(ctx context.Context, e olympiad.Event) error {
+	_, err := db.conn.Exec(ctx,
+		"INSERT INTO olympiad_events (id, event, removed) VALUES ($1, $2, FALSE) "+
+			"ON CONFLICT (id) DO UPDATE SET event=EXCLUDED.event, removed=FALSE, updated_at=NOW();",
+		e.ID, e)
+	if err != nil {
+		return fmt.Errorf("failed to save olympiad event %s: %w", e.ID, err)
+	}
+	return nil
+}
+
+func (db *db) RemoveOlympiadEvent(ctx context.Context, id string) error {
+	_, err := db.conn.Exec(ctx,
+		"INSERT INTO olympiad_events (id, event, removed) VALUES ($1, NULL, TRUE) "+
+			"ON CONFLICT (id) DO UPDATE SET event=NULL, removed=TRUE, updated_at=NOW();", id)
+	if err != nil {
+		return fmt.Errorf("failed to remove olympiad event %s: %w", id, err)
+	}
+	return nil
+}
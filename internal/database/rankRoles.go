@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yuqzii/konkurransetilsynet/internal/codeforces"
+)
+
+func (db *db) GetRankRoleSettings(ctx context.Context) ([]codeforces.RankRoleSettings, error) {
+	rows, err := db.conn.Query(ctx, "SELECT guild_id::TEXT, naming FROM rank_role_settings;")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rank role settings: %w", err)
+	}
+
+	settings, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (codeforces.RankRoleSettings, error) {
+		var s codeforces.RankRoleSettings
+		err := row.Scan(&s.GuildID, &s.Naming)
+		return s, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rank role settings: %w", err)
+	}
+	return settings, nil
+}
+
+func (db *db) SetRankRoleSettings(ctx context.Context, s codeforces.RankRoleSettings) error {
+	_, err := db.conn.Exec(ctx,
+		"INSERT INTO rank_role_settings (guild_id, naming) VALUES ($1, $2) "+
+			"ON CONFLICT (guild_id) DO UPDATE SET naming=EXCLUDED.naming;",
+		s.GuildID, string(s.Naming))
+	if err != nil {
+		return fmt.Errorf("failed to set rank role settings of guild %s: %w", s.GuildID, err)
+	}
+	return nil
+}
+
+func (db *db) DisableRankRoles(ctx context.Context, guildID string) error {
+	_, err := db.conn.Exec(ctx, "DELETE FROM rank_role_settings WHERE guild_id=$1;", guildID)
+	if err != nil {
+		return fmt.Errorf("failed to disable rank roles of guild %s: %w", guildID, err)
+	}
+	return nil
+}
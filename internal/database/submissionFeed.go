@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yuqzii/konkurransetilsynet/internal/codeforces"
+)
+
+func (db *db) GetSubmissionFeedSettings(ctx context.Context) ([]codeforces.SubmissionFeedSettings, error) {
+	rows, err := db.conn.Query(ctx,
+		"SELECT guild_id::TEXT, channel_id::TEXT, min_rating, max_posts_per_hour FROM submission_feed_settings;")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query submission feed settings: %w", err)
+	}
+
+	settings, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (codeforces.SubmissionFeedSettings, error) {
+		var s codeforces.SubmissionFeedSettings
+		err := row.Scan(&s.GuildID, &s.ChannelID, &s.MinRating, &s.MaxPostsPerHour)
+		return s, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read submission feed settings: %w", err)
+	}
+	return settings, nil
+}
+
+func (db *db) SetSubmissionFeedSettings(ctx context.Context, s codeforces.SubmissionFeedSettings) error {
+	_, err := db.conn.Exec(ctx,
+		"INSERT INTO submission_feed_settings (guild_id, channel_id, min_rating, max_posts_per_hour) "+
+			"VALUES ($1, $2, $3, $4) ON CONFLICT (guild_id) DO UPDATE SET channel_id=EXCLUDED.channel_id, "+
+			"min_rating=EXCLUDED.min_rating, max_posts_per_hour=EXCLUDED.max_posts_per_hour;",
+		s.GuildID, s.ChannelID, s.MinRating, s.MaxPostsPerHour)
+	if err != nil {
+		return fmt.Errorf("failed to set submission feed settings of guild %s: %w", s.GuildID, err)
+	}
+	return nil
+}
+
+func (db *db) DisableSubmissionFeed(ctx context.Context, guildID string) error {
+	_, err := db.conn.Exec(ctx, "DELETE FROM submission_feed_settings WHERE guild_id=$1;", guildID)
+	if err != nil {
+		return fmt.Errorf("failed to disable submission feed of guild %s: %w", guildID, err)
+	}
+	return nil
+}
+
+func (db *db) SetSubmissionFeedOptOut(ctx context.Context, guildID, discID string, optOut bool) error {
+	query := "DELETE FROM submission_feed_opt_outs WHERE guild_id=$1 AND discord_id=$2;"
+	if optOut {
+		query = "INSERT INTO submission_feed_opt_outs (guild_id, discord_id) VALUES ($1, $2) ON CONFLICT DO NOTHING;"
+	}
+	_, err := db.conn.Exec(ctx, query, guildID, discID)
+	if err != nil {
+		return fmt.Errorf("failed to set submission feed opt-out=%t of %s in guild %s: %w", optOut, discID, guildID, err)
+	}
+	return nil
+}
+
+func (db *db) GetSubmissionFeedOptOuts(ctx context.Context, guildID string) ([]string, error) {
+	rows, err := db.conn.Query(ctx,
+		"SELECT discord_id::TEXT FROM submission_feed_opt_outs WHERE guild_id=$1;", guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query submission feed opt-outs of guild %s: %w", guildID, err)
+	}
+
+	optOuts, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read submission feed opt-outs of guild %s: %w", guildID, err)
+	}
+	return optOuts, nil
+}
@@ -0,0 +1,178 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/yuqzii/konkurransetilsynet/internal/codeforces"
+)
+
+func (db *db) CreateTeam(ctx context.Context, t codeforces.Team) (int, error) {
+	tx, err := db.conn.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) // nolint: errcheck
+
+	var id int
+	err = tx.QueryRow(ctx,
+		"INSERT INTO teams (guild_id, name) VALUES ($1, $2) RETURNING id;", t.GuildID, t.Name).Scan(&id)
+	if violatesConstraint(err, "teams_guild_name_key") {
+		return 0, codeforces.ErrTeamNameTaken
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert team %s: %w", t.Name, err)
+	}
+
+	for _, discID := range t.Members {
+		_, err = tx.Exec(ctx,
+			"INSERT INTO team_members (team_id, guild_id, discord_id) VALUES ($1, $2, $3);", id, t.GuildID, discID)
+		if violatesConstraint(err, "team_members_guild_member_key") {
+			return 0, codeforces.ErrAlreadyInTeam
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert member %s of team %s: %w", discID, t.Name, err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit team: %w", err)
+	}
+	return id, nil
+}
+
+func (db *db) DeleteTeam(ctx context.Context, teamID int) error {
+	_, err := db.conn.Exec(ctx, "DELETE FROM teams WHERE id=$1;", teamID)
+	if err != nil {
+		return fmt.Errorf("failed to delete team %d: %w", teamID, err)
+	}
+	return nil
+}
+
+func (db *db) GetTeams(ctx context.Context, guildID string) ([]codeforces.Team, error) {
+	rows, err := db.conn.Query(ctx,
+		"SELECT t.id, t.guild_id::TEXT, t.name, "+
+			"ARRAY(SELECT m.discord_id::TEXT FROM team_members m WHERE m.team_id=t.id) "+
+			"FROM teams t WHERE t.guild_id=$1 ORDER BY LOWER(t.name);", guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query teams of guild %s: %w", guildID, err)
+	}
+
+	teams, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (codeforces.Team, error) {
+		var t codeforces.Team
+		err := row.Scan(&t.ID, &t.GuildID, &t.Name, &t.Members)
+		return t, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read teams of guild %s: %w", guildID, err)
+	}
+	return teams, nil
+}
+
+func (db *db) CreateTeamTraining(ctx context.Context, t codeforces.TeamTraining) (int, error) {
+	tx, err := db.conn.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) // nolint: errcheck
+
+	var id int
+	err = tx.QueryRow(ctx,
+		"INSERT INTO team_trainings (guild_id, channel_id, creator_id, name, problems, start_time, duration_seconds) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id;",
+		t.GuildID, t.ChannelID, t.CreatorID, t.Name, t.Problems, t.Start, int(t.Duration.Seconds())).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert team training %s: %w", t.Name, err)
+	}
+
+	for _, teamID := range t.Teams {
+		_, err = tx.Exec(ctx,
+			"INSERT INTO team_training_teams (training_id, team_id) VALUES ($1, $2);", id, teamID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert team %d of training %s: %w", teamID, t.Name, err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit team training: %w", err)
+	}
+	return id, nil
+}
+
+func (db *db) AddTeamTrainingTeam(ctx context.Context, trainingID, teamID int) error {
+	_, err := db.conn.Exec(ctx,
+		"INSERT INTO team_training_teams (training_id, team_id) VALUES ($1, $2) ON CONFLICT DO NOTHING;",
+		trainingID, teamID)
+	if err != nil {
+		return fmt.Errorf("failed to add team %d to training %d: %w", teamID, trainingID, err)
+	}
+	return nil
+}
+
+func (db *db) SetTeamTrainingScoreboardMessage(ctx context.Context, trainingID int, messageID string) error {
+	_, err := db.conn.Exec(ctx,
+		"UPDATE team_trainings SET scoreboard_message_id=$2 WHERE id=$1;", trainingID, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to set scoreboard message of team training %d: %w", trainingID, err)
+	}
+	return nil
+}
+
+func (db *db) FinishTeamTraining(ctx context.Context, trainingID int, results []codeforces.ScoreboardRow) error {
+	_, err := db.conn.Exec(ctx,
+		"UPDATE team_trainings SET results=$2, finished=TRUE WHERE id=$1;", trainingID, results)
+	if err != nil {
+		return fmt.Errorf("failed to finish team training %d: %w", trainingID, err)
+	}
+	return nil
+}
+
+func (db *db) GetActiveTeamTrainings(ctx context.Context) ([]codeforces.TeamTraining, error) {
+	return db.queryTeamTrainings(ctx, "WHERE NOT t.finished ORDER BY t.start_time")
+}
+
+func (db *db) GetFinishedTeamTrainings(ctx context.Context, guildID string,
+	limit int) ([]codeforces.TeamTraining, error) {
+
+	return db.queryTeamTrainings(ctx, "WHERE t.finished AND t.guild_id=$1 ORDER BY t.start_time DESC LIMIT $2",
+		guildID, limit)
+}
+
+// Queries team trainings with their teams. The condition is appended to the query, and may refer
+// to the team_trainings table as t.
+func (db *db) queryTeamTrainings(ctx context.Context, condition string,
+	args ...any) ([]codeforces.TeamTraining, error) {
+
+	rows, err := db.conn.Query(ctx,
+		"SELECT t.id, t.guild_id::TEXT, t.channel_id::TEXT, t.creator_id::TEXT, t.name, t.problems, "+
+			"t.start_time, t.duration_seconds, COALESCE(t.scoreboard_message_id::TEXT, ''), t.results, "+
+			"ARRAY(SELECT tt.team_id FROM team_training_teams tt WHERE tt.training_id=t.id) "+
+			"FROM team_trainings t "+condition+";", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query team trainings: %w", err)
+	}
+
+	trainings, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (codeforces.TeamTraining, error) {
+		var (
+			t               codeforces.TeamTraining
+			durationSeconds int
+		)
+		err := row.Scan(&t.ID, &t.GuildID, &t.ChannelID, &t.CreatorID, &t.Name, &t.Problems,
+			&t.Start, &durationSeconds, &t.ScoreboardMessageID, &t.Results, &t.Teams)
+		t.Duration = time.Duration(durationSeconds) * time.Second
+		return t, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read team trainings: %w", err)
+	}
+	return trainings, nil
+}
+
+func violatesConstraint(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.ConstraintName == constraint
+}
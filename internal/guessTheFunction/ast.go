@@ -10,11 +10,22 @@ var (
 	ErrBuildingAST = errors.New("error generating AST from tokens")
 )
 
-// Order of precedence, lowest to highest
-var precedenceTokenTypes = [3][]TokenType{
-	{ADDITION_TOKEN, SUBTRACTION_TOKEN},
-	{MULTIPLICATION_TOKEN, DIVISION_TOKEN},
-	{POWER_TOKEN},
+// Binding powers of the operators, lowest to highest
+const (
+	lowestBindingPower int = iota
+	sumBindingPower
+	productBindingPower
+	// Unary minus and functions without parentheses, which bind weaker than powers so -x^2 is -(x^2)
+	prefixBindingPower
+	powerBindingPower
+)
+
+var infixBindingPowers = map[TokenType]int{
+	ADDITION_TOKEN:       sumBindingPower,
+	SUBTRACTION_TOKEN:    sumBindingPower,
+	MULTIPLICATION_TOKEN: productBindingPower,
+	DIVISION_TOKEN:       productBindingPower,
+	POWER_TOKEN:          powerBindingPower,
 }
 
 type exprConstructor func(left, right expr) expr
@@ -27,165 +38,173 @@ var tokenTypeToExprConstructMap = map[TokenType]exprConstructor{
 	POWER_TOKEN:          func(l expr, r expr) expr { return power{Left: l, Right: r} },
 }
 
-func canPrecedeBinaryOperator(t Token) bool {
-	switch t.Type {
-	case NUMBER_TOKEN, VARIABLE_TOKEN, RIGHT_PAREN_TOKEN:
-		return true
-	default:
-		return false
-	}
+// Builds the AST by precedence climbing, reading every token once.
+type astParser struct {
+	tokens []Token
+	next   int
+	// Position of the end of the function definition, where errors about missing tokens point
+	end int
 }
 
-// Finds first index that matches one of the given operators
-func findFirstBinaryOperatorOfType(operators []TokenType, tokens []Token) (int, bool) {
-	depth := 0
-	for i := len(tokens) - 1; i >= 0; i-- {
-		// Track parentheses depth
-		switch tokens[i].Type {
-		case RIGHT_PAREN_TOKEN:
-			depth++
-			continue
-		case LEFT_PAREN_TOKEN:
-			depth--
-			continue
-		}
-
-		// Skip parentheses content
-		if depth != 0 {
-			continue
-		}
-
-		for _, operator := range operators {
-			// Skip non-operators and operators not currently searched for
-			if tokens[i].Type != operator {
-				continue
-			}
-
-			// Ensure subtraction is binary. Ex: 2-x valid, -(x+3) invalid
-			if operator == SUBTRACTION_TOKEN {
-				if i == 0 {
-					continue // Can't be binary at pos 0
-				}
+func (p *astParser) peek() (Token, bool) {
+	if p.next >= len(p.tokens) {
+		return Token{}, false
+	}
+	return p.tokens[p.next], true
+}
 
-				if !canPrecedeBinaryOperator(tokens[i-1]) {
-					continue
-				}
-			}
+// Returns an error at the next token, or at the end if there are no more tokens.
+func (p *astParser) errorAtNext(format string, a ...any) error {
+	pos := p.end
+	if t, ok := p.peek(); ok {
+		pos = t.Pos
+	}
+	return &PositionError{Pos: pos, Msg: fmt.Sprintf(format, a...)}
+}
 
-			return i, true
-		}
+func (p *astParser) expect(tokenType TokenType) error {
+	if t, ok := p.peek(); !ok || t.Type != tokenType {
+		return p.errorAtNext("expected '%s'", tokenType)
 	}
-	return 0, false
+	p.next++
+	return nil
 }
 
-func buildASTBinaryOperatorExpr(tokens []Token, idx int) (expr, error) {
-	// Build left and right
-	left, err := buildASTRecursive(tokens[:idx])
+// Parses an expression until an operator that binds weaker than or as strong as minBindingPower.
+func (p *astParser) parseExpr(minBindingPower int) (expr, error) {
+	left, err := p.parsePrefix()
 	if err != nil {
 		return nil, err
 	}
-	right, err := buildASTRecursive(tokens[idx+1:])
-	if err != nil {
-		return nil, err
+
+	for {
+		t, ok := p.peek()
+		if !ok {
+			return left, nil
+		}
+		bindingPower, isOperator := infixBindingPowers[t.Type]
+		if !isOperator || bindingPower <= minBindingPower {
+			return left, nil
+		}
+		p.next++
+
+		// Powers are right-associative, so the right side continues at the same binding power
+		rightMin := bindingPower
+		if t.Type == POWER_TOKEN {
+			rightMin--
+		}
+		right, err := p.parseExpr(rightMin)
+		if err != nil {
+			return nil, err
+		}
+		left = tokenTypeToExprConstructMap[t.Type](left, right)
 	}
+}
 
-	// Get constructor for type
-	exprConstruct, ok := tokenTypeToExprConstructMap[tokens[idx].Type]
+func (p *astParser) parsePrefix() (expr, error) {
+	t, ok := p.peek()
 	if !ok {
-		return nil, fmt.Errorf("unexpected token %v when building precedence tokens", tokens[idx])
+		return nil, p.errorAtNext("expected an expression")
 	}
 
-	return exprConstruct(left, right), nil
-}
-
-func buildSingleToken(t Token) (expr, error) {
 	switch t.Type {
 	case NUMBER_TOKEN:
+		p.next++
 		val, err := strconv.ParseFloat(t.Value, 64)
 		if err != nil {
-			return nil, fmt.Errorf("invalid number %q", t.Value)
+			return nil, &PositionError{Pos: t.Pos, Msg: fmt.Sprintf("invalid number %q", t.Value)}
 		}
 		return number{Value: val}, nil
 	case VARIABLE_TOKEN:
+		p.next++
 		return variable{}, nil
-	default:
-		return nil, fmt.Errorf("unexpected token %v", t)
-	}
-}
-
-func isValidEnclosedParen(tokens []Token) bool {
-	// Check if enclosed in parentheses
-	startParen := tokens[0].Type == LEFT_PAREN_TOKEN
-	endParen := tokens[len(tokens)-1].Type == RIGHT_PAREN_TOKEN
-	isEnclosed := startParen && endParen
-
-	if !isEnclosed {
-		return false
-	}
-
-	// Check all parentheses are closed after opened
-	depth := 0
-	validWrap := true
-	for i, token := range tokens {
-		switch token.Type {
-		case LEFT_PAREN_TOKEN:
-			depth++
-		case RIGHT_PAREN_TOKEN:
-			depth--
+	case CONSTANT_TOKEN:
+		p.next++
+		return constant{Name: t.Value}, nil
+	case SUBTRACTION_TOKEN:
+		p.next++
+		right, err := p.parseExpr(prefixBindingPower)
+		if err != nil {
+			return nil, err
 		}
-
-		if depth == 0 && i != len(tokens)-1 {
-			validWrap = false
-			break
+		return multiply{
+			Left:  number{Value: -1},
+			Right: right,
+		}, nil
+	case LEFT_PAREN_TOKEN:
+		p.next++
+		inner, err := p.parseExpr(lowestBindingPower)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(RIGHT_PAREN_TOKEN); err != nil {
+			return nil, err
 		}
+		return inner, nil
+	case FUNCTION_TOKEN:
+		p.next++
+		return p.parseCall(t)
+	default:
+		return nil, p.errorAtNext("unexpected '%s'", t.Value)
 	}
-
-	return validWrap && depth == 0
 }
 
-func buildASTRecursive(tokens []Token) (expr, error) {
-	if len(tokens) == 0 {
-		return nil, errors.New("no tokens to parse")
-	}
-
-	// Strip a full ( ... )
-	if isValidEnclosedParen(tokens) {
-		return buildASTRecursive(tokens[1 : len(tokens)-1])
-	}
+// Parses the arguments of the function, which are either separated by commas in parentheses or
+// the factor after the function name. Ex: "max(x, 2)" or "sin x^2"
+func (p *astParser) parseCall(function Token) (expr, error) {
+	var args []expr
+	if t, ok := p.peek(); ok && t.Type == LEFT_PAREN_TOKEN {
+		p.next++
+		for {
+			arg, err := p.parseExpr(lowestBindingPower)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
 
-	// Build expr for all binary operators
-	for _, search_tokens := range precedenceTokenTypes {
-		idx, found := findFirstBinaryOperatorOfType(search_tokens, tokens)
-		if found {
-			return buildASTBinaryOperatorExpr(tokens, idx)
+			if t, ok := p.peek(); !ok || t.Type != COMMA_TOKEN {
+				break
+			}
+			p.next++
 		}
-	}
-
-	// Leading unary minus
-	if tokens[0].Type == SUBTRACTION_TOKEN {
-		right, err := buildASTRecursive(tokens[1:])
+		if err := p.expect(RIGHT_PAREN_TOKEN); err != nil {
+			return nil, err
+		}
+	} else {
+		arg, err := p.parseExpr(prefixBindingPower)
 		if err != nil {
 			return nil, err
 		}
-		return multiply{
-			Left:  number{Value: -1},
-			Right: right,
-		}, nil
+		// "sin 2x" could mean sin(2x) or sin(2)*x, so constant arguments can't be followed by an
+		// implicit multiplication
+		if t, ok := p.peek(); ok && t.Implicit && !containsVariable(arg) {
+			return nil, &PositionError{
+				Pos: function.Pos,
+				Msg: fmt.Sprintf("ambiguous argument of %s, use parentheses", function.Value),
+			}
+		}
+		args = append(args, arg)
 	}
 
-	// Single token
-	if len(tokens) == 1 {
-		return buildSingleToken(tokens[0])
+	if arity := functions[function.Value].arity; len(args) != arity {
+		return nil, &PositionError{
+			Pos: function.Pos,
+			Msg: fmt.Sprintf("%s takes %d arguments, got %d", function.Value, arity, len(args)),
+		}
 	}
-
-	return nil, errors.New("could not parse tokens into AST")
+	return call{Name: function.Value, Args: args}, nil
 }
 
-func buildAST(tokens []Token) (expr, error) {
-	AST, astErr := buildASTRecursive(tokens)
+// Builds the AST of the tokens of a function definition of length end.
+func buildAST(tokens []Token, end int) (expr, error) {
+	p := astParser{tokens: tokens, end: end}
+	AST, astErr := p.parseExpr(lowestBindingPower)
+	if astErr == nil && p.next < len(tokens) {
+		astErr = p.errorAtNext("unexpected '%s'", tokens[p.next].Value)
+	}
 
 	if astErr != nil {
-		return nil, fmt.Errorf("%w %v: %w", ErrBuildingAST, tokens, astErr)
+		return nil, fmt.Errorf("%w: %w", ErrBuildingAST, astErr)
 	}
 
 	return AST, nil
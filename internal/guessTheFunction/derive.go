@@ -0,0 +1,153 @@
+package guessTheFunction
+
+// Derivatives of the expressions with respect to x, simplified. Functions that are not
+// differentiable at some points, like abs and floor, get the derivative they have almost
+// everywhere, which is undefined or wrong only at those points.
+
+// Reports whether the expression depends on x.
+func containsVariable(e expr) bool {
+	switch v := e.(type) {
+	case variable:
+		return true
+	case add:
+		return containsVariable(v.Left) || containsVariable(v.Right)
+	case subtract:
+		return containsVariable(v.Left) || containsVariable(v.Right)
+	case multiply:
+		return containsVariable(v.Left) || containsVariable(v.Right)
+	case divide:
+		return containsVariable(v.Left) || containsVariable(v.Right)
+	case power:
+		return containsVariable(v.Left) || containsVariable(v.Right)
+	case call:
+		for _, arg := range v.Args {
+			if containsVariable(arg) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func (n number) Derive() expr {
+	return number{Value: 0}
+}
+
+func (v variable) Derive() expr {
+	return number{Value: 1}
+}
+
+func (c constant) Derive() expr {
+	return number{Value: 0}
+}
+
+func (a add) Derive() expr {
+	return simplify(add{Left: a.Left.Derive(), Right: a.Right.Derive()})
+}
+
+func (s subtract) Derive() expr {
+	return simplify(subtract{Left: s.Left.Derive(), Right: s.Right.Derive()})
+}
+
+// (fg)' = f'g + fg'
+func (m multiply) Derive() expr {
+	return simplify(add{
+		Left:  multiply{Left: m.Left.Derive(), Right: m.Right},
+		Right: multiply{Left: m.Left, Right: m.Right.Derive()},
+	})
+}
+
+// (f/g)' = (f'g - fg')/g^2
+func (d divide) Derive() expr {
+	return simplify(divide{
+		Left: subtract{
+			Left:  multiply{Left: d.Left.Derive(), Right: d.Right},
+			Right: multiply{Left: d.Left, Right: d.Right.Derive()},
+		},
+		Right: power{Left: d.Right, Right: number{Value: 2}},
+	})
+}
+
+func (p power) Derive() expr {
+	switch {
+	// (f^c)' = c f^(c-1) f'
+	case !containsVariable(p.Right):
+		return simplify(multiply{
+			Left: multiply{
+				Left:  p.Right,
+				Right: power{Left: p.Left, Right: subtract{Left: p.Right, Right: number{Value: 1}}},
+			},
+			Right: p.Left.Derive(),
+		})
+	// (e^g)' = e^g g'
+	case p.Left == constant{Name: "e"}:
+		return simplify(multiply{Left: p, Right: p.Right.Derive()})
+	// (c^g)' = c^g ln(c) g'
+	case !containsVariable(p.Left):
+		return simplify(multiply{
+			Left:  multiply{Left: p, Right: call{Name: "ln", Args: []expr{p.Left}}},
+			Right: p.Right.Derive(),
+		})
+	// (f^g)' = f^g (g' ln(f) + g f'/f)
+	default:
+		return simplify(multiply{
+			Left: p,
+			Right: add{
+				Left:  multiply{Left: p.Right.Derive(), Right: call{Name: "ln", Args: []expr{p.Left}}},
+				Right: divide{Left: multiply{Left: p.Right, Right: p.Left.Derive()}, Right: p.Left},
+			},
+		})
+	}
+}
+
+func (c call) Derive() expr {
+	if c.Name == "min" || c.Name == "max" {
+		return simplify(c.deriveMinMax())
+	}
+
+	u := c.Args[0]
+	var outer expr
+	switch c.Name {
+	case "sin":
+		outer = call{Name: "cos", Args: []expr{u}}
+	case "cos":
+		outer = multiply{Left: number{Value: -1}, Right: call{Name: "sin", Args: []expr{u}}}
+	case "tan":
+		outer = divide{Left: number{Value: 1}, Right: power{Left: call{Name: "cos", Args: []expr{u}}, Right: number{Value: 2}}}
+	case "exp":
+		outer = c
+	case "ln":
+		outer = divide{Left: number{Value: 1}, Right: u}
+	case "log":
+		outer = divide{Left: number{Value: 1}, Right: multiply{Left: u, Right: call{Name: "ln", Args: []expr{number{Value: 10}}}}}
+	case "sqrt":
+		outer = divide{Left: number{Value: 1}, Right: multiply{Left: number{Value: 2}, Right: c}}
+	case "abs":
+		outer = divide{Left: u, Right: c}
+	default:
+		// floor and ceil are constant between whole numbers
+		return number{Value: 0}
+	}
+	// Chain rule
+	return simplify(multiply{Left: outer, Right: u.Derive()})
+}
+
+// Uses min(f, g) = (f + g - |f - g|)/2 and max(f, g) = (f + g + |f - g|)/2, where the derivative
+// of |f - g| is (f' - g') (f - g)/|f - g|.
+func (c call) deriveMinMax() expr {
+	f, g := c.Args[0], c.Args[1]
+	diff := subtract{Left: f, Right: g}
+	absDerivative := multiply{
+		Left:  subtract{Left: f.Derive(), Right: g.Derive()},
+		Right: divide{Left: diff, Right: call{Name: "abs", Args: []expr{diff}}},
+	}
+
+	sum := add{Left: f.Derive(), Right: g.Derive()}
+	var numerator expr = add{Left: sum, Right: absDerivative}
+	if c.Name == "min" {
+		numerator = subtract{Left: sum, Right: absDerivative}
+	}
+	return divide{Left: numerator, Right: number{Value: 2}}
+}
@@ -0,0 +1,76 @@
+package guessTheFunction
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+const (
+	absTolerance float64 = 1e-9
+	relTolerance float64 = 1e-5
+	// Fraction of the samples where only one of the functions may be undefined, so functions like
+	// x/x and 1 that only differ at single points are still equivalent
+	maxUndefinedMismatch float64 = 0.02
+)
+
+// The result of comparing two functions at sampled points.
+type equivalence struct {
+	equal bool
+	// A point where the functions differ, and their values there, if they are not equal
+	counterexample float64
+	expected       float64
+	got            float64
+}
+
+// Undefined values are NaN or infinite, e.g. ln(-1) or 1/0.
+func isUndefined(y float64) bool {
+	return math.IsNaN(y) || math.IsInf(y, 0)
+}
+
+// Reports whether two defined values are equal within the combined absolute and relative tolerance.
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) <= absTolerance+relTolerance*max(math.Abs(a), math.Abs(b))
+}
+
+// Returns the points to compare functions at on [lb, ub]: the bounds, 0 if it is in the interval,
+// and random points in between.
+func samplePoints(lb, ub float64, samples int) []float64 {
+	points := []float64{lb, ub}
+	if lb < 0 && ub > 0 {
+		points = append(points, 0)
+	}
+	for len(points) < samples {
+		points = append(points, lb+rand.Float64()*(ub-lb))
+	}
+	return points
+}
+
+// Compares the functions at samples points on [lb, ub]. Points where both are undefined agree,
+// while points where only one is undefined make the functions differ when there are too many of
+// them.
+func checkEquivalence(correct, guessed expr, lb, ub float64, samples int) equivalence {
+	var (
+		mismatches    int
+		firstMismatch *equivalence
+	)
+	for _, x := range samplePoints(lb, ub, samples) {
+		expected, got := correct.Eval(x), guessed.Eval(x)
+
+		switch {
+		case isUndefined(expected) && isUndefined(got):
+			continue
+		case isUndefined(expected) || isUndefined(got):
+			mismatches++
+			if firstMismatch == nil {
+				firstMismatch = &equivalence{counterexample: x, expected: expected, got: got}
+			}
+		case !approxEqual(expected, got):
+			return equivalence{counterexample: x, expected: expected, got: got}
+		}
+	}
+
+	if float64(mismatches) > maxUndefinedMismatch*float64(samples) {
+		return *firstMismatch
+	}
+	return equivalence{equal: true}
+}
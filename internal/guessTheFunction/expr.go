@@ -6,6 +6,10 @@ import (
 
 type expr interface {
 	Eval(x float64) float64
+	// Prints the expression with as few parentheses as possible, in a form the parser accepts.
+	String() string
+	// Returns the simplified derivative with respect to x.
+	Derive() expr
 }
 
 type number struct {
@@ -14,6 +18,42 @@ type number struct {
 
 type variable struct{}
 
+// A named constant, e.g. pi.
+type constant struct {
+	Name string `json:"name"`
+}
+
+// A call to a named function, e.g. sin(x) or max(x, 2).
+type call struct {
+	Name string `json:"name"`
+	Args []expr `json:"args"`
+}
+
+type function struct {
+	arity int
+	eval  func(args []float64) float64
+}
+
+var functions = map[string]function{
+	"sin":   {1, func(a []float64) float64 { return math.Sin(a[0]) }},
+	"cos":   {1, func(a []float64) float64 { return math.Cos(a[0]) }},
+	"tan":   {1, func(a []float64) float64 { return math.Tan(a[0]) }},
+	"exp":   {1, func(a []float64) float64 { return math.Exp(a[0]) }},
+	"ln":    {1, func(a []float64) float64 { return math.Log(a[0]) }},
+	"log":   {1, func(a []float64) float64 { return math.Log10(a[0]) }},
+	"sqrt":  {1, func(a []float64) float64 { return math.Sqrt(a[0]) }},
+	"abs":   {1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"floor": {1, func(a []float64) float64 { return math.Floor(a[0]) }},
+	"ceil":  {1, func(a []float64) float64 { return math.Ceil(a[0]) }},
+	"min":   {2, func(a []float64) float64 { return math.Min(a[0], a[1]) }},
+	"max":   {2, func(a []float64) float64 { return math.Max(a[0], a[1]) }},
+}
+
+var constants = map[string]float64{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
 type add struct {
 	Left  expr `json:"left"`
 	Right expr `json:"right"`
@@ -47,6 +87,18 @@ func (v variable) Eval(x float64) float64 {
 	return x
 }
 
+func (c constant) Eval(x float64) float64 {
+	return constants[c.Name]
+}
+
+func (c call) Eval(x float64) float64 {
+	args := make([]float64, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = arg.Eval(x)
+	}
+	return functions[c.Name].eval(args)
+}
+
 func (a add) Eval(x float64) float64 {
 	return a.Left.Eval(x) + a.Right.Eval(x)
 }
@@ -10,6 +10,8 @@ type exprWrapper struct {
 	Value json.RawMessage `json:"value,omitempty"`
 	Left  *exprWrapper    `json:"left,omitempty"`
 	Right *exprWrapper    `json:"right,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Args  []*exprWrapper  `json:"args,omitempty"`
 }
 
 func marshalExpr(expr expr) (*exprWrapper, error) {
@@ -28,6 +30,25 @@ func marshalExpr(expr expr) (*exprWrapper, error) {
 		return &exprWrapper{
 			Type: "Variable",
 		}, nil
+	case constant:
+		return &exprWrapper{
+			Type: "Constant",
+			Name: v.Name,
+		}, nil
+	case call:
+		args := make([]*exprWrapper, len(v.Args))
+		for i, arg := range v.Args {
+			var err error
+			args[i], err = marshalExpr(arg)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &exprWrapper{
+			Type: "Call",
+			Name: v.Name,
+			Args: args,
+		}, nil
 	case add:
 		left, err := marshalExpr(v.Left)
 		if err != nil {
@@ -124,6 +145,28 @@ func unmarshalExprWrapper(wrapper *exprWrapper) (expr, error) {
 	case "Variable":
 		var variable variable
 		return variable, nil
+	case "Constant":
+		if _, ok := constants[wrapper.Name]; !ok {
+			return nil, fmt.Errorf("unknown constant %q", wrapper.Name)
+		}
+		return constant{Name: wrapper.Name}, nil
+	case "Call":
+		f, ok := functions[wrapper.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown function %q", wrapper.Name)
+		}
+		if len(wrapper.Args) != f.arity {
+			return nil, fmt.Errorf("%s takes %d arguments, got %d", wrapper.Name, f.arity, len(wrapper.Args))
+		}
+		args := make([]expr, len(wrapper.Args))
+		for i, argWrapper := range wrapper.Args {
+			arg, err := unmarshalExprWrapper(argWrapper)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = arg
+		}
+		return call{Name: wrapper.Name, Args: args}, nil
 	case "Add":
 		left, err := unmarshalExprWrapper(wrapper.Left)
 		if err != nil {
@@ -0,0 +1,141 @@
+package guessTheFunction
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Precedence of printed expressions, lowest to highest. Parentheses are only added around
+// operands with lower precedence than their position requires.
+const (
+	sumPrecedence int = iota
+	productPrecedence
+	// Expressions starting with a minus, e.g. -x or -2
+	prefixPrecedence
+	powerPrecedence
+	atomPrecedence
+)
+
+// Numbers are printed exactly, in the shortest form that parses back to the same number, which
+// uses scientific notation for very large and small numbers.
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func precedence(e expr) int {
+	switch v := e.(type) {
+	case number:
+		if v.Value < 0 {
+			return prefixPrecedence
+		}
+		return atomPrecedence
+	case add, subtract:
+		return sumPrecedence
+	case multiply:
+		if n, ok := v.Left.(number); ok && n.Value < 0 {
+			return prefixPrecedence
+		}
+		return productPrecedence
+	case divide:
+		return productPrecedence
+	case power:
+		return powerPrecedence
+	default:
+		return atomPrecedence
+	}
+}
+
+// Prints the expression, in parentheses if its precedence is lower than minPrecedence.
+func wrap(e expr, minPrecedence int) string {
+	if precedence(e) < minPrecedence {
+		return "(" + e.String() + ")"
+	}
+	return e.String()
+}
+
+// Returns the expression without its leading minus if it starts with one. Ex: -2x gives 2x
+func negated(e expr) (expr, bool) {
+	switch v := e.(type) {
+	case number:
+		if v.Value < 0 {
+			return number{Value: -v.Value}, true
+		}
+	case multiply:
+		if n, ok := v.Left.(number); ok && n.Value < 0 {
+			if n.Value == -1 {
+				return v.Right, true
+			}
+			return multiply{Left: number{Value: -n.Value}, Right: v.Right}, true
+		}
+	}
+	return nil, false
+}
+
+// Reports whether a coefficient can be written right before the expression, as in 2x, 3x^2,
+// 2sin(x) or 2x*sin(x).
+func allowsImplicitCoefficient(e expr) bool {
+	switch v := e.(type) {
+	case variable, constant, call:
+		return true
+	case power:
+		return allowsImplicitCoefficient(v.Left)
+	case multiply:
+		return allowsImplicitCoefficient(v.Left)
+	default:
+		return false
+	}
+}
+
+func (n number) String() string {
+	return formatNumber(n.Value)
+}
+
+func (v variable) String() string {
+	return "x"
+}
+
+func (c constant) String() string {
+	return c.Name
+}
+
+func (c call) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = arg.String()
+	}
+	return c.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+func (a add) String() string {
+	if right, ok := negated(a.Right); ok {
+		return wrap(a.Left, sumPrecedence) + " - " + wrap(right, productPrecedence)
+	}
+	return wrap(a.Left, sumPrecedence) + " + " + wrap(a.Right, sumPrecedence)
+}
+
+func (s subtract) String() string {
+	return wrap(s.Left, sumPrecedence) + " - " + wrap(s.Right, productPrecedence)
+}
+
+func (m multiply) String() string {
+	if n, ok := m.Left.(number); ok {
+		if n.Value == -1 {
+			return "-" + wrap(m.Right, prefixPrecedence)
+		}
+		if allowsImplicitCoefficient(m.Right) {
+			return n.String() + m.Right.String()
+		}
+	}
+	return wrap(m.Left, productPrecedence) + "*" + wrap(m.Right, productPrecedence)
+}
+
+// The right operands of divisions and powers are in parentheses if they start with a minus, since
+// the minus only applies to the factor after it, so x/-2x would be (x/-2)*x.
+func (d divide) String() string {
+	return wrap(d.Left, productPrecedence) + "/" + wrap(d.Right, powerPrecedence)
+}
+
+func (p power) String() string {
+	// Powers are right-associative, so only the base needs parentheses around powers
+	return wrap(p.Left, atomPrecedence) + "^" + wrap(p.Right, powerPrecedence)
+}
@@ -10,7 +10,7 @@ func makeNewFunction(functionDefinition string) (expr, error) {
 		return nil, fmt.Errorf("tokenizing: %w", err)
 	}
 
-	expr, err := buildAST(tokens)
+	expr, err := buildAST(tokens, len(functionDefinition))
 	if err != nil {
 		return nil, err
 	}
@@ -2,52 +2,59 @@ package guessTheFunction
 
 import (
 	"fmt"
-	"math"
-	"math/rand/v2"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
-const (
-	maxErr  float64 = 1e-5
-	samples uint16  = 100
-)
+const samples int = 100
 
-func guess(def string, round gtfRound) (bool, error) {
-	expr, err := makeNewFunction(def)
+// Parses the guess and compares it to the function of the round, which is returned with the
+// guess. Guesses that simplify to the same expression as the function are correct without
+// sampling.
+func guess(def string, round *Round) (expr, expr, equivalence, error) {
+	correct, err := round.function()
 	if err != nil {
-		return false, fmt.Errorf("parsing function [%s]: %w", def, err)
+		return nil, nil, equivalence{}, err
 	}
-
-	correct := true
-	for range samples {
-		x := rand.Float64()*(round.ub+math.Abs(round.lb)) - round.lb
-
-		y := expr.Eval(x)
-		correctY := round.expr.Eval(x)
-
-		absDiff := math.Abs(y - correctY)
-		avg := (y + correctY) / 2
-		relDiff := absDiff / avg
-
-		if relDiff > maxErr {
-			correct = false
-			break
-		}
+	guessed, err := makeNewFunction(def)
+	if err != nil {
+		return nil, nil, equivalence{}, fmt.Errorf("parsing function [%s]: %w", def, err)
 	}
 
-	return correct, nil
+	if identical(correct, guessed) {
+		return correct, guessed, equivalence{equal: true}, nil
+	}
+	return correct, guessed, checkEquivalence(correct, guessed, round.LowerBound, round.UpperBound, samples), nil
 }
 
-func sendCorrectGuessMsg(channelID, guessedFunc string, s *discordgo.Session) error {
+func sendCorrectGuessMsg(channelID string, correct, guessed expr, score Score, scored bool,
+	s *discordgo.Session) error {
+
 	msgStr := fmt.Sprintf("Congratulations! You guessed the function!\n"+
-		"Submitted function: `%s`\nYour function: `%s`", activeRounds[channelID].def, guessedFunc)
+		"Submitted function: `%s`\nYour function: `%s`\n", simplify(correct), simplify(guessed))
+	if scored {
+		msgStr += fmt.Sprintf("You get %d points for guessing it after %d queries in %s.",
+			score.Points, score.Queries, score.Duration.Round(time.Second))
+	} else {
+		msgStr += fmt.Sprintf("You guessed it after %d queries in %s. Practice rounds are unscored.",
+			score.Queries, score.Duration.Round(time.Second))
+	}
 	_, err := s.ChannelMessageSend(channelID, msgStr)
 	return err
 }
 
-func sendWrongGuessMsg(channelID string, s *discordgo.Session) error {
-	msgStr := "Your guess was incorrect :( (skill issue tbh)."
+func formatValue(y float64) string {
+	if isUndefined(y) {
+		return "undefined"
+	}
+	return fmt.Sprintf("%g", y)
+}
+
+func sendWrongGuessMsg(channelID string, result equivalence, s *discordgo.Session) error {
+	msgStr := fmt.Sprintf("Your guess was incorrect :( (skill issue tbh).\n"+
+		"At x = %g the function is %s, but your guess is %s.",
+		result.counterexample, formatValue(result.expected), formatValue(result.got))
 	_, err := s.ChannelMessageSend(channelID, msgStr)
 	return err
 }
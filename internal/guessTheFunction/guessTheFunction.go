@@ -1,24 +1,65 @@
 package guessTheFunction
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"math"
+	"math/rand/v2"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/yuqzii/konkurransetilsynet/internal/utils"
 )
 
-type gtfRound struct {
-	def       string
-	expr      expr
-	channelID string
-	lb        float64
-	ub        float64
+const (
+	historyColor   int = 0x5865f2
+	historyCount   int = 25
+	historyPerPage int = 5
+)
+
+type Handler struct {
+	discord *discordgo.Session
+	db      ScoreRepository
+	rounds  RoundStore
+
+	// Guards channelLocks and rng
+	mu sync.Mutex
+	// Commands changing the round of a channel are handled one at a time, so concurrent queries
+	// and guesses can't exceed the budgets or finish a round twice
+	channelLocks map[string]*sync.Mutex
+	// Seeds the generators of random functions
+	rng *rand.Rand
 }
 
-var activeRounds = make(map[string]gtfRound)
+type handlerOption func(*Handler)
+
+// Keeps the rounds in the store instead of the database.
+func WithRoundStore(store RoundStore) handlerOption {
+	return func(h *Handler) {
+		h.rounds = store
+	}
+}
+
+func NewHandler(db Repository, discord *discordgo.Session, opts ...handlerOption) *Handler {
+	h := &Handler{
+		discord: discord,
+		db:      db,
+		rounds:  db,
+
+		channelLocks: make(map[string]*sync.Mutex),
+		rng:          rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
 
 func parseGTFStartRoundArgs(args []string) (def string, lb float64, ub float64, err error) {
 	if len(args) < 4 {
@@ -31,26 +72,28 @@ func parseGTFStartRoundArgs(args []string) (def string, lb float64, ub float64,
 	if ub, err = strconv.ParseFloat(args[3], 64); err != nil {
 		return "", 0, 0, fmt.Errorf("float parsing error, %w", err)
 	}
+	if !(lb < ub) || math.IsInf(lb, 0) || math.IsInf(ub, 0) {
+		return "", 0, 0, fmt.Errorf("invalid bounds, the lower bound must be finite and less than the upper bound")
+	}
 
-	def = strings.Join(args[4:], "")
+	def = strings.Join(args[4:], " ")
 	def = strings.TrimPrefix(def, "||")
 	def = strings.TrimSuffix(def, "||")
 
 	return def, lb, ub, nil
 }
 
-func startGTFRound(args []string, s *discordgo.Session, m *discordgo.MessageCreate) error {
+func (h *Handler) startRound(args []string, m *discordgo.MessageCreate) error {
+	if len(args) >= 3 && strings.EqualFold(args[2], "random") {
+		return h.startRandomRound(args, m)
+	}
+
 	// Delete start message so other users can't see the function
-	err := s.ChannelMessageDelete(m.ChannelID, m.ID)
+	err := h.discord.ChannelMessageDelete(m.ChannelID, m.ID)
 	if err != nil {
 		return fmt.Errorf("deleting start message: %w", err)
 	}
 
-	_, active := activeRounds[m.ChannelID]
-	if active {
-		return sendActiveRoundMsg(m.ChannelID, s)
-	}
-
 	// Parse args
 	funcDef, lwrBound, uprBound, err := parseGTFStartRoundArgs(args)
 	if err != nil {
@@ -63,18 +106,62 @@ func startGTFRound(args []string, s *discordgo.Session, m *discordgo.MessageCrea
 		return fmt.Errorf("parsing function [%s]: %w", funcDef, err)
 	}
 
-	// Add to active rounds
-	newRound := gtfRound{
-		def:       funcDef,
-		expr:      funcExpr,
-		channelID: m.ChannelID,
-		lb:        lwrBound,
-		ub:        uprBound,
+	r, err := newRound(funcDef, funcExpr, lwrBound, uprBound)
+	if err != nil {
+		return err
+	}
+	r.AuthorID = m.Author.ID
+	return h.createRound(r, m)
+}
+
+// Starts a round of a random function of the difficulty, defaulting to easy. The bot is the author
+// of the round, so the player who started it can take part, and the round is unscored practice.
+func (h *Handler) startRandomRound(args []string, m *discordgo.MessageCreate) error {
+	name := difficulties[0].name
+	if len(args) >= 4 {
+		name = args[3]
+	}
+	d, ok := getDifficulty(name)
+	if !ok {
+		names := make([]string, len(difficulties))
+		for i, d := range difficulties {
+			names[i] = d.name
+		}
+		msgStr := fmt.Sprintf("Unknown difficulty `%s`, use one of %s.", name, strings.Join(names, ", "))
+		_, err := h.discord.ChannelMessageSend(m.ChannelID, msgStr)
+		return err
+	}
+
+	h.mu.Lock()
+	rng := rand.New(rand.NewPCG(h.rng.Uint64(), h.rng.Uint64()))
+	h.mu.Unlock()
+
+	f, lb, ub, ok := generateFunction(d, rng)
+	if !ok {
+		return fmt.Errorf("found no fit %s function", d.name)
+	}
+	r, err := newRound(f.String(), f, lb, ub)
+	if err != nil {
+		return err
+	}
+	r.AuthorID = h.discord.State.User.ID
+	return h.createRound(r, m)
+}
+
+// Stores the new round in the channel of the message and announces it.
+func (h *Handler) createRound(r *Round, m *discordgo.MessageCreate) error {
+	r.GuildID, r.ChannelID, r.Start = m.GuildID, m.ChannelID, time.Now()
+	err := h.rounds.CreateRound(context.TODO(), r)
+	if errors.Is(err, ErrActiveRound) {
+		return sendActiveRoundMsg(m.ChannelID, h.discord)
+	} else if err != nil {
+		return fmt.Errorf("storing round: %w", err)
 	}
-	activeRounds[m.ChannelID] = newRound
 
 	// Confirmation message
-	_, err = s.ChannelMessageSend(m.ChannelID, "GTF Round started!")
+	msgStr := fmt.Sprintf("GTF Round started on [%g, %g]! Every round has %d queries, and everyone can use %d of them.",
+		r.LowerBound, r.UpperBound, maxQueriesPerRound, maxQueriesPerUser)
+	_, err = h.discord.ChannelMessageSend(m.ChannelID, msgStr)
 	if err != nil {
 		return fmt.Errorf("failed to send confirmation message, %w", err)
 	}
@@ -82,62 +169,294 @@ func startGTFRound(args []string, s *discordgo.Session, m *discordgo.MessageCrea
 	return nil
 }
 
-func HandleGuessTheFunctionCommands(args []string, s *discordgo.Session, m *discordgo.MessageCreate) error {
+// Returns the lock of the round of the channel.
+func (h *Handler) channelLock(channelID string) *sync.Mutex {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	lock, ok := h.channelLocks[channelID]
+	if !ok {
+		lock = &sync.Mutex{}
+		h.channelLocks[channelID] = lock
+	}
+	return lock
+}
+
+func (h *Handler) HandleCommand(args []string, m *discordgo.MessageCreate) error {
+	if len(args) < 2 {
+		return utils.UnknownCommand(h.discord, m)
+	}
+
+	switch args[1] {
+	case "start", "query", "hint", "guess", "end":
+		lock := h.channelLock(m.ChannelID)
+		lock.Lock()
+		defer lock.Unlock()
+	}
+
 	switch args[1] {
 	case "start":
-		err := startGTFRound(args, s, m)
+		err := h.startRound(args, m)
 		if err != nil {
 			return err
 		}
 	case "query":
-		// TESTING PURPOSES
-		x, err := strconv.ParseFloat(args[2], 64)
-		if err != nil {
+		if len(args) < 3 {
+			_, err := h.discord.ChannelMessageSend(m.ChannelID, "Usage: `!gtf query [value]`")
 			return err
 		}
-
-		r, ok := activeRounds[m.ChannelID]
-		if !ok {
-			return sendNoActiveRoundMsg(m.ChannelID, s)
+		return h.query(args[2], false, m)
+	case "hint":
+		if len(args) < 4 || args[2] != "derivative" {
+			_, err := h.discord.ChannelMessageSend(m.ChannelID, "Usage: `!gtf hint derivative [value]`")
+			return err
 		}
-		y := r.expr.Eval(x)
-
-		msgStr := fmt.Sprintf("f(%f) = %f", x, y)
-		_, err = s.ChannelMessageSend(m.ChannelID, msgStr)
+		return h.query(args[3], true, m)
+	case "plot":
+		return h.sendPlot(m)
+	case "guess":
+		return h.guess(strings.Join(args[2:], " "), m)
+	case "end":
+		return h.endRound(m)
+	case "history":
+		err := h.sendHistory(m.ChannelID)
 		if err != nil {
+			return fmt.Errorf("history command failed: %w", err)
+		}
+	case "leaderboard":
+		if m.GuildID == "" {
+			_, err := h.discord.ChannelMessageSend(m.ChannelID, "The leaderboard is only available in servers.")
 			return err
 		}
-	case "guess":
-		guessFunc := strings.Join(args[2:], "")
-		correct, err := guess(guessFunc, activeRounds[m.ChannelID])
+		withImage := len(args) >= 3 && strings.EqualFold(args[2], "png")
+		err := h.sendLeaderboard(m.GuildID, m.ChannelID, withImage)
 		if err != nil {
-			if errors.Is(err, ErrLex) {
-				err = errors.Join(err, sendLexErrMsg(m.ChannelID, s))
-			} else if errors.Is(err, ErrBuildingAST) {
-				err = errors.Join(err, sendASTErrMsg(m.ChannelID, s))
-			}
-			return fmt.Errorf("guessing function: %w", err)
+			return fmt.Errorf("leaderboard command failed: %w", err)
+		}
+	default:
+		err := utils.UnknownCommand(h.discord, m)
+		return err
+	}
+
+	return nil
+}
+
+// Returns the active round of the channel, and otherwise tells the user there is none.
+func (h *Handler) activeRound(channelID string) (*Round, bool, error) {
+	r, err := h.rounds.GetActiveRound(context.TODO(), channelID)
+	if errors.Is(err, ErrNoRound) {
+		return nil, false, sendNoActiveRoundMsg(channelID, h.discord)
+	} else if err != nil {
+		return nil, false, fmt.Errorf("getting active round of channel %s: %w", channelID, err)
+	}
+	return r, true, nil
+}
+
+// Returns the active round of the channel if the user may take part in it, and otherwise tells
+// the user why not.
+func (h *Handler) playableRound(m *discordgo.MessageCreate) (*Round, bool, error) {
+	r, ok, err := h.activeRound(m.ChannelID)
+	if !ok {
+		return nil, false, err
+	}
+	if r.AuthorID == m.Author.ID {
+		_, err := h.discord.ChannelMessageSend(m.ChannelID, "You started this round, so you already know the function.")
+		return nil, false, err
+	}
+	return r, true, nil
+}
+
+// Reveals the value of the function at the point, or its derivative for a higher cost.
+func (h *Handler) query(arg string, derivative bool, m *discordgo.MessageCreate) error {
+	x, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return fmt.Errorf("float parsing error, %w", err)
+	}
+
+	r, ok, err := h.playableRound(m)
+	if !ok {
+		return err
+	}
+
+	q, err := r.query(m.Author.ID, x, derivative, time.Now())
+	if err != nil {
+		var msgStr string
+		switch {
+		case errors.Is(err, ErrOutsideBounds):
+			msgStr = fmt.Sprintf("You can only query points in [%g, %g].", r.LowerBound, r.UpperBound)
+		case errors.Is(err, ErrRoundBudget):
+			msgStr = fmt.Sprintf("There are not enough queries left in this round, %d/%d are used.",
+				r.queriesUsed(), maxQueriesPerRound)
+		case errors.Is(err, ErrUserBudget):
+			msgStr = fmt.Sprintf("You don't have enough queries left, you have used %d/%d.",
+				r.queriesUsedBy(m.Author.ID), maxQueriesPerUser)
+		default:
+			return fmt.Errorf("querying function: %w", err)
+		}
+		_, err = h.discord.ChannelMessageSend(m.ChannelID, msgStr)
+		return err
+	}
+	if err := h.rounds.AddQuery(context.TODO(), r.ID, q); err != nil {
+		return fmt.Errorf("storing query of round %d: %w", r.ID, err)
+	}
+
+	name := "f"
+	if derivative {
+		name = "f'"
+	}
+	msgStr := fmt.Sprintf("%s(%g) = %s\n%d/%d queries used this round, %d/%d by you.", name, x, formatValue(q.Y),
+		r.queriesUsed(), maxQueriesPerRound, r.queriesUsedBy(m.Author.ID), maxQueriesPerUser)
+	_, err = h.discord.ChannelMessageSend(m.ChannelID, msgStr)
+	return err
+}
+
+func (h *Handler) guess(def string, m *discordgo.MessageCreate) error {
+	r, ok, err := h.playableRound(m)
+	if !ok {
+		return err
+	}
+
+	correct, guessed, result, err := guess(def, r)
+	if err != nil {
+		if errors.Is(err, ErrLex) {
+			err = errors.Join(err, sendLexErrMsg(m.ChannelID, def, err, h.discord))
+		} else if errors.Is(err, ErrBuildingAST) {
+			err = errors.Join(err, sendASTErrMsg(m.ChannelID, def, err, h.discord))
 		}
+		return fmt.Errorf("guessing function: %w", err)
+	}
 
-		if correct {
-			err = sendCorrectGuessMsg(m.ChannelID, guessFunc, s)
-			delete(activeRounds, m.ChannelID)
+	now := time.Now()
+	g := Guess{UserID: m.Author.ID, Definition: def, Correct: result.equal, Time: now}
+	if err := h.rounds.AddGuess(context.TODO(), r.ID, g); err != nil {
+		return fmt.Errorf("storing guess of round %d: %w", r.ID, err)
+	}
+	if !result.equal {
+		return sendWrongGuessMsg(m.ChannelID, result, h.discord)
+	}
+
+	if err := h.rounds.FinishRound(context.TODO(), r.ID, m.Author.ID, now); err != nil {
+		return fmt.Errorf("finishing round %d: %w", r.ID, err)
+	}
+
+	score, scored := scoreGuess(r, m.Author, h.discord.State.User.ID, now)
+	// Rounds outside of guilds have no leaderboard
+	if scored && score.GuildID != "" {
+		if err := h.db.AddScore(context.TODO(), score); err != nil {
+			err = fmt.Errorf("storing score of %s: %w", m.Author.ID, err)
+			return errors.Join(err, sendCorrectGuessMsg(m.ChannelID, correct, guessed, score, scored, h.discord))
+		}
+	}
+	return sendCorrectGuessMsg(m.ChannelID, correct, guessed, score, scored, h.discord)
+}
+
+// Lets the author end the round without a winner, revealing the function.
+func (h *Handler) endRound(m *discordgo.MessageCreate) error {
+	r, ok, err := h.activeRound(m.ChannelID)
+	if !ok {
+		return err
+	}
+	// Random rounds can be ended by anyone
+	if r.AuthorID != m.Author.ID && r.AuthorID != h.discord.State.User.ID {
+		_, err := h.discord.ChannelMessageSend(m.ChannelID, "Only the player who started the round can end it.")
+		return err
+	}
+
+	f, err := r.function()
+	if err != nil {
+		return err
+	}
+	if err := h.rounds.FinishRound(context.TODO(), r.ID, "", time.Now()); err != nil {
+		return fmt.Errorf("finishing round %d: %w", r.ID, err)
+	}
+
+	msgStr := fmt.Sprintf("The round has ended without a winner. The function was `%s`.", simplify(f))
+	_, err = h.discord.ChannelMessageSend(m.ChannelID, msgStr)
+	return err
+}
+
+// Plots the points queried so far in the active round, or the function of the last finished round.
+func (h *Handler) sendPlot(m *discordgo.MessageCreate) error {
+	r, err := h.rounds.GetActiveRound(context.TODO(), m.ChannelID)
+	// The function is only plotted once the round has finished
+	var f expr
+	if errors.Is(err, ErrNoRound) {
+		finished, err := h.rounds.GetFinishedRounds(context.TODO(), m.ChannelID, 1)
+		if err != nil {
+			return fmt.Errorf("getting finished rounds of channel %s: %w", m.ChannelID, err)
+		}
+		if len(finished) == 0 {
+			return sendNoActiveRoundMsg(m.ChannelID, h.discord)
+		}
+		r = &finished[0]
+		if f, err = r.function(); err != nil {
 			return err
-		} else {
-			return sendWrongGuessMsg(m.ChannelID, s)
 		}
+	} else if err != nil {
+		return fmt.Errorf("getting active round of channel %s: %w", m.ChannelID, err)
+	}
 
-	default:
-		err := utils.UnknownCommand(s, m)
+	content := fmt.Sprintf("Queried points so far, %d queries used. The function is shown when the round ends.",
+		r.queriesUsed())
+	if f != nil {
+		content = fmt.Sprintf("Last round: `f(x) = %s` on [%g, %g]", simplify(f), r.LowerBound, r.UpperBound)
+	}
+	img, err := renderPlot(f, r.LowerBound, r.UpperBound, r.points())
+	if err != nil {
+		return fmt.Errorf("rendering plot: %w", err)
+	}
+
+	_, err = h.discord.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content: content,
+		Files: []*discordgo.File{{
+			Name:        "plot.png",
+			ContentType: "image/png",
+			Reader:      bytes.NewReader(img),
+		}},
+	})
+	return err
+}
+
+// Formats a finished round as a line of the history.
+func formatHistoryEntry(r *Round) (string, error) {
+	f, err := r.function()
+	if err != nil {
+		return "", err
+	}
+
+	outcome := "not guessed"
+	if r.WinnerID != "" {
+		outcome = "guessed by <@" + r.WinnerID + ">"
+	}
+	return fmt.Sprintf("**#%d** `f(x) = %s` on [%g, %g] by <@%s>, <t:%d:R>\n%s after %d queries and %d guesses",
+		r.ID, simplify(f), r.LowerBound, r.UpperBound, r.AuthorID, r.Start.Unix(),
+		outcome, r.queriesUsed(), len(r.Guesses)), nil
+}
+
+func (h *Handler) sendHistory(channelID string) error {
+	rounds, err := h.rounds.GetFinishedRounds(context.TODO(), channelID, historyCount)
+	if err != nil {
+		return fmt.Errorf("getting finished rounds of channel %s: %w", channelID, err)
+	}
+	if len(rounds) == 0 {
+		_, err := h.discord.ChannelMessageSend(channelID, "No Guess the Function rounds have finished in this channel yet.")
 		return err
 	}
 
-	return nil
+	lines := make([]string, len(rounds))
+	for i := range rounds {
+		if lines[i], err = formatHistoryEntry(&rounds[i]); err != nil {
+			return err
+		}
+	}
+	pages := utils.EmbedPages("Guess the Function history", historyColor, lines, historyPerPage, "")
+	return utils.SendPages(h.discord, channelID, pages)
 }
 
 func sendNoActiveRoundMsg(channelID string, s *discordgo.Session) error {
 	msgStr := "There is not an active Guess the Function round in this channel.\n" +
-		"Start a new one with `!gtf start [lower bound] [upper bound] [function definition]`."
+		"Start a new one with `!gtf start [lower bound] [upper bound] [function definition]`, " +
+		"or practice on a random function with `!gtf start random [easy|medium|hard]`."
 	_, err := s.ChannelMessageSend(channelID, msgStr)
 	return err
 }
@@ -149,14 +468,27 @@ func sendActiveRoundMsg(channelID string, s *discordgo.Session) error {
 	return err
 }
 
-func sendLexErrMsg(channelID string, s *discordgo.Session) error {
-	msgStr := "Could not perform lexical analysis on your guess. Make sure it only contains valid characters."
+// Shows the definition with a caret under the position of the error, if the error has one.
+func formatErrorPosition(def string, parseErr error) string {
+	var posErr *PositionError
+	if !errors.As(parseErr, &posErr) {
+		return ""
+	}
+	pos := min(posErr.Pos, len(def))
+	caret := strings.Repeat(" ", utf8.RuneCountInString(def[:pos])) + "^ " + posErr.Msg
+	return fmt.Sprintf("\n```\n%s\n%s\n```", def, caret)
+}
+
+func sendLexErrMsg(channelID, def string, lexErr error, s *discordgo.Session) error {
+	msgStr := "Could not perform lexical analysis on your guess. Make sure it only contains valid characters." +
+		formatErrorPosition(def, lexErr)
 	_, err := s.ChannelMessageSend(channelID, msgStr)
 	return err
 }
 
-func sendASTErrMsg(channelID string, s *discordgo.Session) error {
-	msgStr := "Could not build an AST from your guess. (your function doesn't make sense, git gud)."
+func sendASTErrMsg(channelID, def string, astErr error, s *discordgo.Session) error {
+	msgStr := "Could not build an AST from your guess. (your function doesn't make sense, git gud)." +
+		formatErrorPosition(def, astErr)
 	_, err := s.ChannelMessageSend(channelID, msgStr)
 	return err
 }
@@ -1,11 +1,19 @@
 package guessTheFunction
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"image/png"
 	"math"
-	"math/rand"
+	"math/rand/v2"
+	"slices"
 	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
 )
 
 type TestCase struct {
@@ -191,6 +199,122 @@ var TestCases_FunctionParsing = [...]TestCase{
 			},
 		},
 	}},
+
+	{"sin(x)", call{"sin", []expr{variable{}}}},
+
+	{"sin(x)^2+cos(x)^2", number{1}},
+
+	{"-cos(2*x)+1", add{
+		multiply{number{-1}, call{"cos", []expr{multiply{number{2}, variable{}}}}},
+		number{1},
+	}},
+
+	{"sqrt(x)*ln(x)", multiply{
+		power{variable{}, number{0.5}},
+		call{"ln", []expr{variable{}}},
+	}},
+
+	{"log(x^2)", multiply{number{2}, call{"log", []expr{call{"abs", []expr{variable{}}}}}}},
+
+	{"e^(x/1000)", call{"exp", []expr{divide{variable{}, number{1000}}}}},
+
+	{"pi*x+e", add{
+		multiply{number{math.Pi}, variable{}},
+		number{math.E},
+	}},
+
+	{"tan(x)", divide{
+		call{"sin", []expr{variable{}}},
+		call{"cos", []expr{variable{}}},
+	}},
+
+	{"floor(x/7) + ceil(x/7) + abs(-x)", add{
+		add{
+			call{"floor", []expr{divide{variable{}, number{7}}}},
+			call{"ceil", []expr{divide{variable{}, number{7}}}},
+		},
+		call{"abs", []expr{variable{}}},
+	}},
+
+	{"max(min(x, 10), 2*(1+1))", call{"min", []expr{
+		call{"max", []expr{variable{}, number{4}}},
+		number{10},
+	}}},
+
+	{"max(x,-x)-min(x,2000)", subtract{
+		call{"abs", []expr{variable{}}},
+		call{"min", []expr{variable{}, number{2000}}},
+	}},
+
+	// Implicit multiplication
+	{"2x", multiply{number{2}, variable{}}},
+
+	{"3(x+1)", add{
+		multiply{number{3}, variable{}},
+		number{3},
+	}},
+
+	{"(x+1)(x-1)", subtract{
+		power{variable{}, number{2}},
+		number{1},
+	}},
+
+	{"x sin x", multiply{variable{}, call{"sin", []expr{variable{}}}}},
+
+	{"sin x cos x", multiply{
+		call{"sin", []expr{variable{}}},
+		call{"cos", []expr{variable{}}},
+	}},
+
+	{"sin x^2", call{"sin", []expr{power{variable{}, number{2}}}}},
+
+	{"sin cos 2", call{"sin", []expr{call{"cos", []expr{number{2}}}}}},
+
+	{"1e5 + 2.5e-3x", add{number{100000}, multiply{number{0.0025}, variable{}}}},
+
+	{"1E+2x", multiply{number{100}, variable{}}},
+
+	{"2ex - 3e-x", subtract{
+		subtract{
+			multiply{multiply{number{2}, constant{"e"}}, variable{}},
+			multiply{number{3}, constant{"e"}}},
+		variable{},
+	}},
+
+	{"2x^2-3x", subtract{
+		multiply{number{2}, power{variable{}, number{2}}},
+		multiply{number{3}, variable{}},
+	}},
+
+	{"-2x(x+1)^2", multiply{
+		multiply{number{-2}, variable{}},
+		power{add{variable{}, number{1}}, number{2}},
+	}},
+
+	{"2pi x + xx", add{
+		multiply{number{2 * math.Pi}, variable{}},
+		power{variable{}, number{2}},
+	}},
+
+	{"3sqrt(x)ln(x)", multiply{
+		multiply{number{3}, call{"sqrt", []expr{variable{}}}},
+		call{"ln", []expr{variable{}}},
+	}},
+
+	{"x/2x", multiply{divide{variable{}, number{2}}, variable{}}},
+
+	// Precedence and associativity
+	{"-x^2", multiply{number{-1}, power{variable{}, number{2}}}},
+
+	{"2^3^0.5", power{number{2}, power{number{3}, number{0.5}}}},
+
+	{"x^-1", divide{number{1}, variable{}}},
+
+	{"x^2^-1/3", divide{call{"sqrt", []expr{variable{}}}, number{3}}},
+
+	{"x-1-2-3", subtract{variable{}, number{6}}},
+
+	{"x/2/4", divide{variable{}, number{8}}},
 }
 
 var TestCases_SavingLoading = [...]expr{
@@ -254,11 +378,42 @@ var TestCases_SavingLoading = [...]expr{
 		number{Value: 1},
 		multiply{number{Value: -1}, variable{}},
 	},
+
+	multiply{constant{Name: "pi"}, call{Name: "sin", Args: []expr{variable{}}}},
+
+	call{Name: "max", Args: []expr{
+		call{Name: "sqrt", Args: []expr{variable{}}},
+		constant{Name: "e"},
+	}},
+}
+
+var TestCases_InvalidFunctions = [...]struct {
+	input string
+	err   error
+	pos   int
+}{
+	{"y+1", ErrLex, 0},
+	{"x + sinh(x)", ErrLex, 7},
+	{"x + 1.2.3", ErrLex, 7},
+	{"2 # x", ErrLex, 2},
+	{"", ErrBuildingAST, 0},
+	{"sin()", ErrBuildingAST, 4},
+	{"sin", ErrBuildingAST, 3},
+	{"x(", ErrBuildingAST, 2},
+	{"(x+1", ErrBuildingAST, 4},
+	{"x + 1)", ErrBuildingAST, 5},
+	{"x * / 2", ErrBuildingAST, 4},
+	{"min(x)", ErrBuildingAST, 0},
+	{"x + cos(x, 2)", ErrBuildingAST, 4},
+	{"max(x,,2)", ErrBuildingAST, 6},
+	{"x,1", ErrBuildingAST, 1},
+	{"sin 2x", ErrBuildingAST, 0},
+	{"x + cos pi x", ErrBuildingAST, 4},
 }
 
 const xValuesLowerBound float64 = -1000
 const xValuesUpperBound float64 = 1000
-const numberSamplesPerFunctionTest uint16 = 100
+const numberSamplesPerFunctionTest int = 100
 const maxTolerableError float64 = 1e-5
 
 func logFunctionDefinition(fn expr, t *testing.T) {
@@ -271,23 +426,35 @@ func logFunctionDefinition(fn expr, t *testing.T) {
 }
 
 func assertFunctionsApproxEqual(parsedFunc expr, correctFunc expr, t *testing.T) {
-	for range numberSamplesPerFunctionTest {
-		// Sample in interval given
-		x := rand.Float64()*(xValuesUpperBound+math.Abs(xValuesLowerBound)) - xValuesLowerBound
+	assertFunctionsApproxEqualOn(parsedFunc, correctFunc, xValuesLowerBound, xValuesUpperBound, t)
+}
+
+// Samples the functions on [lb, ub] independently of the equivalence checker used for guesses.
+// Every sample must agree within maxTolerableError, or be undefined in both functions.
+func assertFunctionsApproxEqualOn(parsedFunc expr, correctFunc expr, lb, ub float64, t *testing.T) {
+	undefined := func(y float64) bool {
+		return math.IsNaN(y) || math.IsInf(y, 0)
+	}
 
+	for range numberSamplesPerFunctionTest {
+		x := lb + rand.Float64()*(ub-lb)
 		y_correct := correctFunc.Eval(x)
 		y_parsed := parsedFunc.Eval(x)
 
-		// Find relative difference
-		absolute_difference := math.Abs(y_parsed - y_correct)
-		y_average := (y_parsed + y_correct) / 2.0
-		relative_difference := absolute_difference / y_average
+		var equal bool
+		if undefined(y_correct) || undefined(y_parsed) {
+			equal = undefined(y_correct) && undefined(y_parsed)
+		} else {
+			absolute_difference := math.Abs(y_parsed - y_correct)
+			scale := max(1, math.Abs(y_correct), math.Abs(y_parsed))
+			equal = absolute_difference/scale <= maxTolerableError
+		}
 
-		if relative_difference > maxTolerableError {
+		if !equal {
 			logFunctionDefinition(parsedFunc, t)
 			logFunctionDefinition(correctFunc, t)
 
-			t.Fatalf("functions did not produce same value, x: %f y: %f y_pred: %f", x, correctFunc.Eval(x), parsedFunc.Eval(x))
+			t.Fatalf("functions did not produce same value, x: %f y: %f y_pred: %f", x, y_correct, y_parsed)
 		}
 	}
 }
@@ -328,3 +495,556 @@ func Test_MarshalAndUnMarshal(t *testing.T) {
 		})
 	}
 }
+
+func Test_MakeNewFunctionInvalid(t *testing.T) {
+	for _, tc := range TestCases_InvalidFunctions {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := makeNewFunction(tc.input)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %q, got %v", tc.err, err)
+			}
+
+			var posErr *PositionError
+			if !errors.As(err, &posErr) {
+				t.Fatalf("expected error with position, got %v", err)
+			}
+			if posErr.Pos != tc.pos {
+				t.Errorf("expected error at position %d, got %d (%s)", tc.pos, posErr.Pos, posErr.Msg)
+			}
+		})
+	}
+}
+
+func Test_FormatErrorPosition(t *testing.T) {
+	def := "x + sin()"
+	_, err := makeNewFunction(def)
+
+	expected := "\n```\nx + sin()\n        ^ unexpected ')'\n```"
+	if got := formatErrorPosition(def, err); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func Test_CheckEquivalence(t *testing.T) {
+	tests := []struct {
+		correct string
+		guessed string
+		lb, ub  float64
+		equal   bool
+	}{
+		{"x/x", "1", -10, 10, true},
+		{"sqrt(x)", "sqrt(abs(x))", -10, 10, false},
+		{"sqrt(x)", "sqrt(abs(x))", 0, 10, true},
+		{"ln(x)", "ln(x)+0.000000000001", 1, 100, true},
+		{"x^2", "x^2+0.001", -1, 1, false},
+		{"1/x", "1/x", -1, 1, true},
+		{"x", "abs(x)", 5, 10, true},
+		{"x", "abs(x)", -10, -5, false},
+		{"0", "0.0000000001", -1, 1, true},
+	}
+
+	for _, test := range tests {
+		correct, err := makeNewFunction(test.correct)
+		if err != nil {
+			t.Fatalf("unexpected error parsing %s: %s", test.correct, err)
+		}
+		guessed, err := makeNewFunction(test.guessed)
+		if err != nil {
+			t.Fatalf("unexpected error parsing %s: %s", test.guessed, err)
+		}
+
+		result := checkEquivalence(correct, guessed, test.lb, test.ub, numberSamplesPerFunctionTest)
+		if result.equal != test.equal {
+			t.Errorf("%s and %s on [%g, %g]: expected equal %t, got %t", test.correct, test.guessed,
+				test.lb, test.ub, test.equal, result.equal)
+		}
+		if !result.equal && (result.counterexample < test.lb || result.counterexample > test.ub) {
+			t.Errorf("%s and %s: counterexample %g outside [%g, %g]", test.correct, test.guessed,
+				result.counterexample, test.lb, test.ub)
+		}
+	}
+}
+
+var TestCases_String = [...]struct {
+	expr     expr
+	expected string
+}{
+	{add{variable{}, number{1}}, "x + 1"},
+	{subtract{variable{}, add{variable{}, number{1}}}, "x - (x + 1)"},
+	{add{variable{}, number{-2}}, "x - 2"},
+	{multiply{number{2}, power{variable{}, number{3}}}, "2x^3"},
+	{multiply{number{-1}, power{variable{}, number{2}}}, "-x^2"},
+	{power{multiply{number{-1}, variable{}}, number{2}}, "(-x)^2"},
+	{power{number{-2}, variable{}}, "(-2)^x"},
+	{power{variable{}, power{number{2}, number{3}}}, "x^2^3"},
+	{power{power{variable{}, number{2}}, number{3}}, "(x^2)^3"},
+	{power{variable{}, divide{number{1}, number{2}}}, "x^(1/2)"},
+	{divide{number{1}, multiply{number{2}, variable{}}}, "1/(2x)"},
+	{multiply{add{variable{}, number{1}}, subtract{variable{}, number{1}}}, "(x + 1)*(x - 1)"},
+	{multiply{number{3}, call{"max", []expr{variable{}, constant{"pi"}}}}, "3max(x, pi)"},
+	{power{constant{"e"}, multiply{number{-2}, variable{}}}, "e^(-2x)"},
+	{divide{variable{}, multiply{number{-2}, variable{}}}, "x/(-2x)"},
+	{divide{number{1}, multiply{number{-2}, call{"sin", []expr{variable{}}}}}, "1/(-2sin(x))"},
+	{power{variable{}, number{-1}}, "x^(-1)"},
+	{number{1.0 / 3}, "0.3333333333333333"},
+	{number{1e21}, "1e+21"},
+	{number{-2.5e-7}, "-2.5e-07"},
+}
+
+func Test_String(t *testing.T) {
+	for _, tc := range TestCases_String {
+		if got := tc.expr.String(); got != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, got)
+		}
+	}
+
+	// Printed functions parse back to the same function
+	var functions []expr
+	for _, tc := range TestCases_FunctionParsing {
+		functions = append(functions, tc.Expected)
+	}
+	for _, tc := range TestCases_String {
+		functions = append(functions, tc.expr)
+	}
+	for _, f := range functions {
+		t.Run(f.String(), func(t *testing.T) {
+			parsedFunc, err := makeNewFunction(f.String())
+			if err != nil {
+				t.Fatalf("unexpected error parsing %q: %s", f.String(), err)
+			}
+			assertFunctionsApproxEqual(parsedFunc, f, t)
+		})
+	}
+}
+
+var TestCases_Simplify = [...]struct {
+	input    string
+	expected string
+}{
+	{"x*1+0", "x"},
+	{"x^1", "x"},
+	{"2+3*4", "14"},
+	{"x+x+x", "3x"},
+	{"(x+1)(x-1)", "x^2 - 1"},
+	{"-(x+1)", "-x - 1"},
+	{"10(x+1)-(x+3)^3", "-x^3 - 9x^2 - 17x - 17"},
+	{"sin(x)+sin(x)", "2sin(x)"},
+	{"sin x * x", "x*sin(x)"},
+	{"2sin x cos x + sin x cos x", "3cos(x)*sin(x)"},
+	{"x*sin(x)^2*sin(x)", "x*sin(x)^3"},
+	{"1 + sin x + x^2 + 2x", "x^2 + 2x + sin(x) + 1"},
+	{"sqrt(4)x + sin(2)", "2x + sin(2)"},
+	{"x^2^(1/2)", "x^1.4142135623730951"},
+	{"(x+1)^2", "(x + 1)^2"},
+	{"x/1 + 0/x", "x + 0/x"},
+	{"x^0.5*x^0.5", "x^0.5*x^0.5"},
+	{"0*ln(x)", "0ln(x)"},
+	{"0*x*sin(x)", "0sin(x)"},
+	{"ln(x)*ln(x)^-1", "ln(x)^(-1)*ln(x)"},
+	{"ln(x)^2*ln(x)", "ln(x)^3"},
+	{"ln(x) - ln(x) + x", "x + 0ln(x)"},
+}
+
+func Test_Simplify(t *testing.T) {
+	for _, tc := range TestCases_Simplify {
+		t.Run(tc.input, func(t *testing.T) {
+			parsedFunc, err := makeNewFunction(tc.input)
+			if err != nil {
+				t.Fatalf("unexpected error, %s", err)
+			}
+
+			simplified := simplify(parsedFunc)
+			if got := simplified.String(); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+			assertFunctionsApproxEqual(simplified, parsedFunc, t)
+		})
+	}
+
+	// Sums and products simplify to the same expression whatever order they are written in
+	for _, pair := range [][2]string{{"x sin x + 1", "1 + sin(x)*x"}, {"(x-1)(x+1)", "x^2-1"}, {"2x+x", "x*3"}} {
+		a, _ := makeNewFunction(pair[0])
+		b, _ := makeNewFunction(pair[1])
+		if !identical(a, b) {
+			t.Errorf("expected %s and %s to be identical, got %s and %s", pair[0], pair[1], simplify(a), simplify(b))
+		}
+	}
+
+	// Simplification does not change where a function is defined, so these are sampled when guessed
+	for _, pair := range [][2]string{{"x^0.5*x^0.5", "x"}, {"0*ln(x)", "0"}, {"ln(x)*ln(x)^-1", "1"}} {
+		a, _ := makeNewFunction(pair[0])
+		b, _ := makeNewFunction(pair[1])
+		if identical(a, b) {
+			t.Errorf("expected %s and %s not to be identical, both simplify to %s", pair[0], pair[1], simplify(a))
+		}
+	}
+}
+
+var TestCases_Derivative = [...]TestCase{
+	{"5", number{0}},
+	{"pi", number{0}},
+	{"x", number{1}},
+	{"3x^2 - 2x + 1", subtract{multiply{number{6}, variable{}}, number{2}}},
+	{"x sin x", add{
+		call{"sin", []expr{variable{}}},
+		multiply{variable{}, call{"cos", []expr{variable{}}}}}},
+	{"1/x", divide{number{-1}, power{variable{}, number{2}}}},
+	{"(x+1)/(x-1)", divide{number{-2}, power{subtract{variable{}, number{1}}, number{2}}}},
+	{"sqrt x", divide{number{1}, multiply{number{2}, call{"sqrt", []expr{variable{}}}}}},
+	{"2^x", multiply{power{number{2}, variable{}}, call{"ln", []expr{number{2}}}}},
+	{"x^x", multiply{
+		power{variable{}, variable{}},
+		add{call{"ln", []expr{variable{}}}, number{1}}}},
+	{"sin(x^2)", multiply{
+		multiply{number{2}, variable{}},
+		call{"cos", []expr{power{variable{}, number{2}}}}}},
+	{"cos(2x)", multiply{number{-2}, call{"sin", []expr{multiply{number{2}, variable{}}}}}},
+	{"tan x", divide{number{1}, power{call{"cos", []expr{variable{}}}, number{2}}}},
+	{"exp(-x)", multiply{number{-1}, call{"exp", []expr{multiply{number{-1}, variable{}}}}}},
+	{"ln(x^2)", divide{number{2}, variable{}}},
+	{"log x", divide{number{1}, multiply{variable{}, call{"ln", []expr{number{10}}}}}},
+	{"abs(x-3)", divide{
+		subtract{variable{}, number{3}},
+		call{"abs", []expr{subtract{variable{}, number{3}}}}}},
+	{"floor(x) + ceil(x)", number{0}},
+	{"max(x, 2)", divide{
+		add{number{1}, divide{subtract{variable{}, number{2}}, call{"abs", []expr{subtract{variable{}, number{2}}}}}},
+		number{2}}},
+	{"min(x^2, 2x)", divide{
+		subtract{
+			add{multiply{number{2}, variable{}}, number{2}},
+			multiply{
+				subtract{multiply{number{2}, variable{}}, number{2}},
+				divide{
+					subtract{power{variable{}, number{2}}, multiply{number{2}, variable{}}},
+					call{"abs", []expr{subtract{power{variable{}, number{2}}, multiply{number{2}, variable{}}}}}}}},
+		number{2}}},
+}
+
+func Test_Derive(t *testing.T) {
+	for _, tc := range TestCases_Derivative {
+		t.Run(tc.Input, func(t *testing.T) {
+			parsedFunc, err := makeNewFunction(tc.Input)
+			if err != nil {
+				t.Fatalf("unexpected error, %s", err)
+			}
+
+			derivative := parsedFunc.Derive()
+			assertFunctionsApproxEqual(derivative, tc.Expected, t)
+
+			// Derivative trees survive serialization
+			jsonData, err := MarshalExpr(derivative)
+			if err != nil {
+				t.Fatalf("unexpected error marshalling %s: %s", derivative, err)
+			}
+			loaded, err := unmarshalExpr(jsonData)
+			if err != nil {
+				t.Fatalf("unexpected error unmarshalling %s: %s", jsonData, err)
+			}
+			if loaded.String() != derivative.String() {
+				t.Errorf("expected %q after serialization, got %q", derivative, loaded)
+			}
+		})
+	}
+}
+
+var TestCases_DeriveString = [...]struct {
+	input    string
+	expected string
+}{
+	{"x^3 + x", "3x^2 + 1"},
+	{"x sin x", "sin(x) + x*cos(x)"},
+	{"sin(x^2)", "2x*cos(x^2)"},
+	{"ln x", "1/x"},
+	{"e^x", "e^x"},
+	{"e^(2x)", "2e^(2x)"},
+}
+
+func Test_DeriveString(t *testing.T) {
+	for _, tc := range TestCases_DeriveString {
+		parsedFunc, err := makeNewFunction(tc.input)
+		if err != nil {
+			t.Fatalf("unexpected error, %s", err)
+		}
+		if got := parsedFunc.Derive().String(); got != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+var TestCases_Plot = [...]struct {
+	input  string
+	lb, ub float64
+}{
+	{"x^2", -10, 10},
+	{"1/x", -1, 1},
+	{"tan x", -5, 5},
+	{"ln x", -10, -1},
+	{"sqrt x", -4, 4},
+	{"floor x", 0, 5},
+	{"5", 0, 1},
+}
+
+func Test_RenderPlot(t *testing.T) {
+	points := []queryPoint{{x: 0.5, y: 2}, {x: 3, y: math.NaN()}, {x: 100, y: 1}}
+	for _, tc := range TestCases_Plot {
+		t.Run(tc.input, func(t *testing.T) {
+			parsedFunc, err := makeNewFunction(tc.input)
+			if err != nil {
+				t.Fatalf("unexpected error, %s", err)
+			}
+
+			data, err := renderPlot(parsedFunc, tc.lb, tc.ub, points)
+			if err != nil {
+				t.Fatalf("unexpected error rendering plot, %s", err)
+			}
+			img, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("plot is not a valid PNG, %s", err)
+			}
+			if size := img.Bounds().Size(); size.X != plotWidth || size.Y != plotHeight {
+				t.Errorf("expected a %dx%d plot, got %v", plotWidth, plotHeight, size)
+			}
+		})
+	}
+
+	// Points alone are plotted while the function is hidden
+	if _, err := renderPlot(nil, -1, 1, points); err != nil {
+		t.Fatalf("unexpected error rendering points, %s", err)
+	}
+}
+
+func Test_ValueRange(t *testing.T) {
+	// Values close to the asymptote of 1/x don't stretch the view
+	var values []float64
+	for i := range 1000 {
+		values = append(values, 1/(-1+float64(i)/499.5))
+	}
+	ymin, ymax := valueRange(values, nil)
+	if ymin < -100 || ymax > 100 {
+		t.Errorf("expected the asymptote to be clipped, got [%g, %g]", ymin, ymax)
+	}
+
+	// Points are always in view
+	ymin, ymax = valueRange([]float64{0, 1, math.Inf(1)}, []queryPoint{{x: 0, y: 50}})
+	if ymin > 0 || ymax < 50 {
+		t.Errorf("expected [0, 50] to be in view, got [%g, %g]", ymin, ymax)
+	}
+
+	if ymin, ymax = valueRange([]float64{math.NaN()}, nil); ymin != -1 || ymax != 1 {
+		t.Errorf("expected [-1, 1] without defined values, got [%g, %g]", ymin, ymax)
+	}
+}
+
+func Test_RoundQueries(t *testing.T) {
+	f, _ := makeNewFunction("x^2")
+	r, err := newRound("x^2", f, -5, 5)
+	if err != nil {
+		t.Fatalf("unexpected error, %s", err)
+	}
+	// Rounds loaded from a store only have the serialized function
+	r.expr = nil
+	now := time.Now()
+
+	if q, err := r.query("a", 3, false, now); err != nil || q.Y != 9 {
+		t.Fatalf("expected f(3) = 9, got %g and error %v", q.Y, err)
+	}
+	if q, err := r.query("a", 3, true, now); err != nil || q.Y != 6 || q.Cost != derivativeHintCost {
+		t.Fatalf("expected f'(3) = 6 at cost %d, got %g at cost %d and error %v", derivativeHintCost, q.Y, q.Cost, err)
+	}
+	for _, x := range []float64{5.5, math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if _, err := r.query("a", x, false, now); !errors.Is(err, ErrOutsideBounds) {
+			t.Errorf("expected error %q querying %g, got %v", ErrOutsideBounds, x, err)
+		}
+	}
+	if used := r.queriesUsedBy("a"); used != 1+derivativeHintCost {
+		t.Errorf("expected %d queries used by a, got %d", 1+derivativeHintCost, used)
+	}
+	if points := r.points(); len(points) != 1 || points[0] != (queryPoint{x: 3, y: 9}) {
+		t.Errorf("expected only the value query to be plotted, got %v", points)
+	}
+
+	// Every user has a budget of their own, until the round runs out
+	for r.queriesUsedBy("a") < maxQueriesPerUser {
+		if _, err := r.query("a", 0, false, now); err != nil {
+			t.Fatalf("unexpected error, %s", err)
+		}
+	}
+	if _, err := r.query("a", 0, false, now); !errors.Is(err, ErrUserBudget) {
+		t.Errorf("expected error %q, got %v", ErrUserBudget, err)
+	}
+	for i := 0; r.queriesUsed() < maxQueriesPerRound; i++ {
+		if _, err := r.query(fmt.Sprint(i), 0, false, now); err != nil {
+			t.Fatalf("unexpected error, %s", err)
+		}
+	}
+	if _, err := r.query("b", 0, false, now); !errors.Is(err, ErrRoundBudget) {
+		t.Errorf("expected error %q, got %v", ErrRoundBudget, err)
+	}
+	if len(r.Queries) != maxQueriesPerRound-derivativeHintCost+1 {
+		t.Errorf("expected every query to be recorded, got %d", len(r.Queries))
+	}
+}
+
+var TestCases_RoundPoints = [...]struct {
+	queries  int
+	elapsed  time.Duration
+	expected int
+}{
+	{0, 0, maxPoints},
+	{5, 0, maxPoints - 5*queryPenalty},
+	{0, 30 * time.Second, maxPoints - 1},
+	{0, time.Minute, maxPoints - 1},
+	{3, 10*time.Minute + time.Second, maxPoints - 3*queryPenalty - 11},
+	{30, 24 * time.Hour, minPoints},
+}
+
+func Test_RoundPoints(t *testing.T) {
+	for _, tc := range TestCases_RoundPoints {
+		if got := roundPoints(tc.queries, tc.elapsed); got != tc.expected {
+			t.Errorf("%d queries in %s: expected %d points, got %d", tc.queries, tc.elapsed, tc.expected, got)
+		}
+	}
+}
+
+func Test_ScoreGuess(t *testing.T) {
+	const botID = "bot"
+	start := time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC)
+	now := start.Add(90 * time.Second)
+	user := &discordgo.User{ID: "1", Username: "alice"}
+
+	r := &Round{GuildID: "guild", AuthorID: "2", Start: start}
+	score, scored := scoreGuess(r, user, botID, now)
+	if !scored || score.Points != roundPoints(0, 90*time.Second) || score.DiscordID != user.ID {
+		t.Errorf("expected a scored guess, got %+v (scored: %t)", score, scored)
+	}
+
+	// Random rounds are authored by the bot and are practice
+	r.AuthorID = botID
+	score, scored = scoreGuess(r, user, botID, now)
+	if scored || score.Points != 0 {
+		t.Errorf("expected an unscored practice guess, got %+v (scored: %t)", score, scored)
+	}
+}
+
+func Test_MemoryRoundStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryRoundStore()
+	f, _ := makeNewFunction("sin x")
+	now := time.Now()
+
+	if _, err := store.GetActiveRound(ctx, "channel"); !errors.Is(err, ErrNoRound) {
+		t.Fatalf("expected error %q, got %v", ErrNoRound, err)
+	}
+
+	for i := range 3 {
+		r, _ := newRound("sin x", f, -1, 1)
+		r.ChannelID, r.AuthorID, r.Start = "channel", "author", now
+		if err := store.CreateRound(ctx, r); err != nil {
+			t.Fatalf("unexpected error creating round, %s", err)
+		}
+		if err := store.CreateRound(ctx, r); !errors.Is(err, ErrActiveRound) {
+			t.Fatalf("expected error %q, got %v", ErrActiveRound, err)
+		}
+
+		if err := store.AddQuery(ctx, r.ID, Query{UserID: "a", X: 0.5, Cost: 1}); err != nil {
+			t.Fatalf("unexpected error adding query, %s", err)
+		}
+		// Rounds returned by the store don't share their queries
+		r.Queries = append(r.Queries, Query{UserID: "b"})
+		active, err := store.GetActiveRound(ctx, "channel")
+		if err != nil || active.ID != r.ID || len(active.Queries) != 1 {
+			t.Fatalf("expected round %d with 1 query, got %+v and error %v", r.ID, active, err)
+		}
+
+		if err := store.AddGuess(ctx, r.ID, Guess{UserID: "a", Definition: "x", Correct: i%2 == 0}); err != nil {
+			t.Fatalf("unexpected error adding guess, %s", err)
+		}
+		winner := ""
+		if i%2 == 0 {
+			winner = "a"
+		}
+		if err := store.FinishRound(ctx, r.ID, winner, now); err != nil {
+			t.Fatalf("unexpected error finishing round, %s", err)
+		}
+	}
+
+	finished, err := store.GetFinishedRounds(ctx, "channel", 2)
+	if err != nil {
+		t.Fatalf("unexpected error, %s", err)
+	}
+	if len(finished) != 2 || finished[0].ID != 3 || finished[1].ID != 2 {
+		t.Fatalf("expected rounds 3 and 2, got %+v", finished)
+	}
+	if finished[0].WinnerID != "a" || finished[1].WinnerID != "" || len(finished[0].Guesses) != 1 {
+		t.Errorf("expected the outcome and guesses to be stored, got %+v", finished)
+	}
+	loaded, err := finished[0].function()
+	if err != nil {
+		t.Fatalf("unexpected error loading function, %s", err)
+	}
+	assertFunctionsApproxEqual(loaded, f, t)
+
+	if rounds, _ := store.GetFinishedRounds(ctx, "other", 2); len(rounds) != 0 {
+		t.Errorf("expected no rounds in another channel, got %d", len(rounds))
+	}
+}
+
+var TestCases_Degenerate = [...]struct {
+	input      string
+	degenerate bool
+}{
+	{"5", true},
+	{"x - x + 3", true},
+	{"sin(x)^2 + cos(x)^2", true},
+	{"ln(-x^2 - 1)", true},
+	{"sqrt(x - 5)", true},
+	{"exp(x^2)", true},
+	{"x^2 + 1", false},
+	{"sqrt(x + 9.5)", false},
+	{"1/x", false},
+	{"floor(x/3)", false},
+}
+
+func Test_IsDegenerate(t *testing.T) {
+	for _, tc := range TestCases_Degenerate {
+		f, err := makeNewFunction(tc.input)
+		if err != nil {
+			t.Fatalf("unexpected error, %s", err)
+		}
+		if got := isDegenerate(f, -10, 10); got != tc.degenerate {
+			t.Errorf("%s: expected degenerate to be %t, got %t", tc.input, tc.degenerate, got)
+		}
+	}
+}
+
+func Test_GenerateFunction(t *testing.T) {
+	for _, d := range difficulties {
+		t.Run(d.name, func(t *testing.T) {
+			rng := rand.New(rand.NewPCG(1, 2))
+			for range 50 {
+				f, lb, ub, ok := generateFunction(d, rng)
+				if !ok {
+					t.Fatalf("found no function")
+				}
+				if isDegenerate(f, lb, ub) {
+					t.Fatalf("generated degenerate function %s on [%g, %g]", f, lb, ub)
+				}
+				if !slices.Contains(d.bounds, [2]float64{lb, ub}) {
+					t.Errorf("expected bounds of the difficulty, got [%g, %g]", lb, ub)
+				}
+
+				// Generated functions are stored by their definition, so it must parse back
+				parsedFunc, err := makeNewFunction(f.String())
+				if err != nil {
+					t.Fatalf("unexpected error parsing %q: %s", f.String(), err)
+				}
+				assertFunctionsApproxEqualOn(parsedFunc, f, lb, ub, t)
+			}
+		})
+	}
+
+	if _, ok := getDifficulty("HARD"); !ok {
+		t.Errorf("expected difficulties to be case insensitive")
+	}
+}
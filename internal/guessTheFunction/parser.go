@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 type TokenType int
@@ -23,6 +24,9 @@ const (
 	POWER_TOKEN
 	LEFT_PAREN_TOKEN
 	RIGHT_PAREN_TOKEN
+	FUNCTION_TOKEN
+	CONSTANT_TOKEN
+	COMMA_TOKEN
 )
 
 func (t TokenType) String() string {
@@ -45,6 +49,12 @@ func (t TokenType) String() string {
 		return "("
 	case RIGHT_PAREN_TOKEN:
 		return ")"
+	case FUNCTION_TOKEN:
+		return "FUNCTION"
+	case CONSTANT_TOKEN:
+		return "CONSTANT"
+	case COMMA_TOKEN:
+		return ","
 	default:
 		return "UNKNOWN"
 	}
@@ -53,6 +63,24 @@ func (t TokenType) String() string {
 type Token struct {
 	Type  TokenType
 	Value string
+	// Position of the token in the function definition
+	Pos int
+	// Set for multiplications inserted between adjacent operands
+	Implicit bool
+}
+
+// An error at a position of the function definition.
+type PositionError struct {
+	Pos int
+	Msg string
+}
+
+func (e *PositionError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+func lexError(pos int, format string, a ...any) error {
+	return fmt.Errorf("%w: %w", ErrLex, &PositionError{Pos: pos, Msg: fmt.Sprintf(format, a...)})
 }
 
 var singleTokenTypeMap = map[byte]Token{
@@ -63,7 +91,7 @@ var singleTokenTypeMap = map[byte]Token{
 	'^': {Type: POWER_TOKEN, Value: "^"},
 	'(': {Type: LEFT_PAREN_TOKEN, Value: "("},
 	')': {Type: RIGHT_PAREN_TOKEN, Value: ")"},
-	'x': {Type: VARIABLE_TOKEN, Value: "x"},
+	',': {Type: COMMA_TOKEN, Value: ","},
 }
 
 func lexNumberString(definition string, i int) (*Token, int, error) {
@@ -77,7 +105,7 @@ func lexNumberString(definition string, i int) (*Token, int, error) {
 			i++
 		} else if definition[i] == '.' {
 			if dotSeen {
-				return nil, 0, fmt.Errorf("multiple dots in number at position %d", i)
+				return nil, 0, lexError(i, "multiple dots in number")
 			}
 			dotSeen = true
 			i++
@@ -88,10 +116,48 @@ func lexNumberString(definition string, i int) (*Token, int, error) {
 
 	onlyDot := start == i-1 && definition[start] == '.'
 	if onlyDot {
-		return nil, 0, fmt.Errorf("invalid standalone dot at position %d", start)
+		return nil, 0, lexError(start, "invalid standalone dot")
+	}
+
+	// Scientific notation, e.g. 1e5 or 2.5e-3. An e that is not followed by digits is the constant.
+	if i < len(definition) && (definition[i] == 'e' || definition[i] == 'E') {
+		j := i + 1
+		if j < len(definition) && (definition[j] == '+' || definition[j] == '-') {
+			j++
+		}
+		if j < len(definition) && unicode.IsDigit(rune(definition[j])) {
+			for j < len(definition) && unicode.IsDigit(rune(definition[j])) {
+				j++
+			}
+			i = j
+		}
+	}
+
+	return &Token{Type: NUMBER_TOKEN, Value: definition[start:i], Pos: start}, i, nil
+}
+
+// Lexes the longest variable, function or constant name at i, so names can be written without
+// spaces between them, e.g. "xsinx" or "exp" rather than "e" followed by "xp".
+func lexIdentifier(definition string, i int) (*Token, int, error) {
+	var longest *Token
+	match := func(name string, tokenType TokenType) {
+		if strings.HasPrefix(definition[i:], name) && (longest == nil || len(name) > len(longest.Value)) {
+			longest = &Token{Type: tokenType, Value: name, Pos: i}
+		}
+	}
+
+	match("x", VARIABLE_TOKEN)
+	for name := range functions {
+		match(name, FUNCTION_TOKEN)
+	}
+	for name := range constants {
+		match(name, CONSTANT_TOKEN)
 	}
 
-	return &Token{Type: NUMBER_TOKEN, Value: definition[start:i]}, i, nil
+	if longest == nil {
+		return nil, 0, lexError(i, "unknown name starting with '%c'", definition[i])
+	}
+	return longest, i + len(longest.Value), nil
 }
 
 func lexTokens(definition string) ([]Token, error) {
@@ -100,6 +166,11 @@ func lexTokens(definition string) ([]Token, error) {
 	for i < len(definition) {
 		ch := definition[i]
 
+		if unicode.IsSpace(rune(ch)) {
+			i++
+			continue
+		}
+
 		// Parse numbers and floats
 		if unicode.IsDigit(rune(ch)) || ch == '.' {
 			token, endIdx, err := lexNumberString(definition, i)
@@ -112,11 +183,25 @@ func lexTokens(definition string) ([]Token, error) {
 			continue
 		}
 
-		// Parse operators, parentheses, and variables
+		// Parse variables, functions and constants
+		if unicode.IsLetter(rune(ch)) {
+			token, endIdx, err := lexIdentifier(definition, i)
+			if err != nil {
+				return nil, err
+			}
+			i = endIdx
+
+			tokens = append(tokens, *token)
+			continue
+		}
+
+		// Parse operators, parentheses, and commas
 		token, ok := singleTokenTypeMap[ch]
 		if !ok {
-			return nil, fmt.Errorf("%w: unexpected character '%c' in input at position %d", ErrLex, ch, i)
+			r, _ := utf8.DecodeRuneInString(definition[i:])
+			return nil, lexError(i, "unexpected character '%c'", r)
 		}
+		token.Pos = i
 		tokens = append(tokens, token)
 
 		i++
@@ -124,15 +209,42 @@ func lexTokens(definition string) ([]Token, error) {
 	return tokens, nil
 }
 
-func tokenizeInput(input string) ([]Token, error) {
-	// Sanitize
-	input = strings.ReplaceAll(input, " ", "")
+func endsOperand(t Token) bool {
+	switch t.Type {
+	case NUMBER_TOKEN, VARIABLE_TOKEN, CONSTANT_TOKEN, RIGHT_PAREN_TOKEN:
+		return true
+	default:
+		return false
+	}
+}
+
+func startsOperand(t Token) bool {
+	switch t.Type {
+	case NUMBER_TOKEN, VARIABLE_TOKEN, CONSTANT_TOKEN, FUNCTION_TOKEN, LEFT_PAREN_TOKEN:
+		return true
+	default:
+		return false
+	}
+}
 
-	// Find tokens
+// Inserts multiplication between adjacent operands. Ex: "2x" becomes "2*x" and "(x+1)(x-1)"
+// becomes "(x+1)*(x-1)"
+func insertImplicitMultiplication(tokens []Token) []Token {
+	var result []Token
+	for i, token := range tokens {
+		if i > 0 && endsOperand(tokens[i-1]) && startsOperand(token) {
+			result = append(result, Token{Type: MULTIPLICATION_TOKEN, Value: "*", Pos: token.Pos, Implicit: true})
+		}
+		result = append(result, token)
+	}
+	return result
+}
+
+func tokenizeInput(input string) ([]Token, error) {
 	tokens, err := lexTokens(input)
 	if err != nil {
 		return nil, err
 	}
 
-	return tokens, nil
+	return insertImplicitMultiplication(tokens), nil
 }
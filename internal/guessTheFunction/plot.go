@@ -0,0 +1,227 @@
+package guessTheFunction
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"slices"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	plotWidth  int = 640
+	plotHeight int = 400
+	// Space around the plot area for the axis labels
+	plotMarginLeft   int = 70
+	plotMarginRight  int = 20
+	plotMarginTop    int = 20
+	plotMarginBottom int = 30
+	// Number of function samples per pixel column
+	plotSamplesPerPixel int = 2
+	pointRadius         int = 3
+	// The view is clipped to the middle samples when the full range is this many times larger,
+	// so asymptotes don't flatten the rest of the function
+	outlierRangeFactor float64 = 20
+)
+
+var (
+	plotBackground = color.RGBA{0x2b, 0x2d, 0x31, 0xff}
+	plotText       = color.RGBA{0xdb, 0xde, 0xe1, 0xff}
+	plotAxis       = color.RGBA{0x6d, 0x6f, 0x78, 0xff}
+	plotCurve      = color.RGBA{0x58, 0x65, 0xf2, 0xff}
+	plotPoint      = color.RGBA{0xf2, 0x3f, 0x43, 0xff}
+)
+
+// A queried point of the function of a round.
+type queryPoint struct {
+	x float64
+	y float64
+}
+
+// Maps function coordinates to pixels in the plot area.
+type plotView struct {
+	lb, ub     float64
+	ymin, ymax float64
+	area       image.Rectangle
+}
+
+func (v plotView) pixelX(x float64) int {
+	return v.area.Min.X + int(math.Round((x-v.lb)/(v.ub-v.lb)*float64(v.area.Dx()-1)))
+}
+
+// Pixel rows of values far outside the view are clamped, so they don't overflow.
+func (v plotView) pixelY(y float64) int {
+	row := (v.ymax - y) / (v.ymax - v.ymin) * float64(v.area.Dy()-1)
+	row = max(-float64(v.area.Dy()), min(2*float64(v.area.Dy()), row))
+	return v.area.Min.Y + int(math.Round(row))
+}
+
+// Returns the range of values to show, containing the defined values and the points. Values near
+// asymptotes are left out of the range if they would dominate it.
+func valueRange(values []float64, points []queryPoint) (float64, float64) {
+	var defined []float64
+	for _, y := range values {
+		if !isUndefined(y) {
+			defined = append(defined, y)
+		}
+	}
+	slices.Sort(defined)
+
+	ymin, ymax := math.Inf(1), math.Inf(-1)
+	if n := len(defined); n > 0 {
+		ymin, ymax = defined[0], defined[n-1]
+		lo, hi := defined[n*5/100], defined[(n-1)*95/100]
+		if ymax-ymin > outlierRangeFactor*(hi-lo) && hi > lo {
+			span := hi - lo
+			ymin, ymax = max(ymin, lo-span/2), min(ymax, hi+span/2)
+		}
+	}
+	for _, p := range points {
+		if !isUndefined(p.y) {
+			ymin, ymax = min(ymin, p.y), max(ymax, p.y)
+		}
+	}
+
+	switch {
+	case math.IsInf(ymin, 1):
+		return -1, 1
+	case ymin == ymax:
+		return ymin - 1, ymax + 1
+	}
+	pad := (ymax - ymin) / 20
+	return ymin - pad, ymax + pad
+}
+
+func drawLine(img *image.RGBA, clip image.Rectangle, x0, y0, x1, y1 int, c color.Color) {
+	dx, dy := absInt(x1-x0), -absInt(y1-y0)
+	sx, sy := signInt(x1-x0), signInt(y1-y0)
+	e := dx + dy
+	for {
+		if (image.Point{X: x0, Y: y0}).In(clip) {
+			img.Set(x0, y0, c)
+		}
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		if 2*e >= dy {
+			e += dy
+			x0 += sx
+		}
+		if 2*e <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+func signInt(a int) int {
+	switch {
+	case a < 0:
+		return -1
+	case a > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func drawLabel(img *image.RGBA, x, y int, label string) {
+	d := font.Drawer{Dst: img, Src: image.NewUniform(plotText), Face: basicfont.Face7x13, Dot: fixed.P(x, y)}
+	d.DrawString(label)
+}
+
+func labelWidth(label string) int {
+	return font.MeasureString(basicfont.Face7x13, label).Ceil()
+}
+
+// Renders a PNG plot over [lb, ub] of the function, if it is not nil, and the points. Undefined
+// regions are left empty, and the curve is broken where it jumps across the view, like at
+// asymptotes.
+func renderPlot(f expr, lb, ub float64, points []queryPoint) ([]byte, error) {
+	area := image.Rect(plotMarginLeft, plotMarginTop, plotWidth-plotMarginRight, plotHeight-plotMarginBottom)
+
+	var xs, ys []float64
+	if f != nil {
+		samples := area.Dx() * plotSamplesPerPixel
+		for i := range samples {
+			x := lb + (ub-lb)*float64(i)/float64(samples-1)
+			xs, ys = append(xs, x), append(ys, f.Eval(x))
+		}
+	}
+	ymin, ymax := valueRange(ys, points)
+	view := plotView{lb: lb, ub: ub, ymin: ymin, ymax: ymax, area: area}
+
+	img := image.NewRGBA(image.Rect(0, 0, plotWidth, plotHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(plotBackground), image.Point{}, draw.Src)
+
+	// Axes through the origin, and a border around the plot area
+	if lb < 0 && ub > 0 {
+		x := view.pixelX(0)
+		drawLine(img, area, x, area.Min.Y, x, area.Max.Y-1, plotAxis)
+	}
+	if ymin < 0 && ymax > 0 {
+		y := view.pixelY(0)
+		drawLine(img, area, area.Min.X, y, area.Max.X-1, y, plotAxis)
+	}
+	border := area.Inset(-1)
+	drawLine(img, img.Bounds(), border.Min.X, border.Min.Y, border.Max.X-1, border.Min.Y, plotAxis)
+	drawLine(img, img.Bounds(), border.Min.X, border.Max.Y-1, border.Max.X-1, border.Max.Y-1, plotAxis)
+	drawLine(img, img.Bounds(), border.Min.X, border.Min.Y, border.Min.X, border.Max.Y-1, plotAxis)
+	drawLine(img, img.Bounds(), border.Max.X-1, border.Min.Y, border.Max.X-1, border.Max.Y-1, plotAxis)
+
+	for i := 1; i < len(xs); i++ {
+		if isUndefined(ys[i-1]) || isUndefined(ys[i]) {
+			continue
+		}
+		x0, y0 := view.pixelX(xs[i-1]), view.pixelY(ys[i-1])
+		x1, y1 := view.pixelX(xs[i]), view.pixelY(ys[i])
+		// A jump across the whole view between neighbouring samples is an asymptote
+		if absInt(y1-y0) >= area.Dy() {
+			continue
+		}
+		drawLine(img, area, x0, y0, x1, y1, plotCurve)
+	}
+
+	for _, p := range points {
+		if isUndefined(p.y) || p.x < lb || p.x > ub {
+			continue
+		}
+		cx, cy := view.pixelX(p.x), view.pixelY(p.y)
+		for dy := -pointRadius; dy <= pointRadius; dy++ {
+			for dx := -pointRadius; dx <= pointRadius; dx++ {
+				if dx*dx+dy*dy <= pointRadius*pointRadius {
+					img.Set(cx+dx, cy+dy, plotPoint)
+				}
+			}
+		}
+	}
+
+	face := basicfont.Face7x13
+	textY := area.Max.Y + face.Ascent + 6
+	lbLabel, ubLabel := fmt.Sprintf("%.4g", lb), fmt.Sprintf("%.4g", ub)
+	drawLabel(img, area.Min.X, textY, lbLabel)
+	drawLabel(img, area.Max.X-labelWidth(ubLabel), textY, ubLabel)
+	yminLabel, ymaxLabel := fmt.Sprintf("%.4g", ymin), fmt.Sprintf("%.4g", ymax)
+	drawLabel(img, area.Min.X-labelWidth(ymaxLabel)-6, area.Min.Y+face.Ascent, ymaxLabel)
+	drawLabel(img, area.Min.X-labelWidth(yminLabel)-6, area.Max.Y, yminLabel)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,202 @@
+package guessTheFunction
+
+import (
+	"math"
+	"math/rand/v2"
+	"strings"
+)
+
+const (
+	maxGenerateAttempts int = 1000
+	// Number of points generated functions are checked at
+	generateSamples int = 200
+	// Generated functions may be undefined on at most this fraction of the bounds
+	maxUndefinedFraction float64 = 0.1
+	// Generated functions may not grow beyond this on the bounds, so values stay readable
+	maxGeneratedValue float64 = 1e6
+	// Probability that a node above the maximum depth is a leaf, so trees vary in shape
+	leafProbability float64 = 0.3
+)
+
+type nodeKind int
+
+const (
+	addNode nodeKind = iota
+	subtractNode
+	multiplyNode
+	divideNode
+	powerNode
+	callNode
+)
+
+type weightedNode struct {
+	kind   nodeKind
+	weight int
+}
+
+// Settings of the random functions of a difficulty.
+type difficulty struct {
+	name string
+	// Maximum depth of the expression tree, where leaves have depth 0
+	maxDepth  int
+	nodes     []weightedNode
+	functions []string
+	// Whether leaves can be pi or e
+	constants bool
+	// Numbers are whole numbers from 1 to maxCoefficient
+	maxCoefficient int
+	// Exponents are whole numbers from 2 to maxExponent
+	maxExponent int
+	// Bounds to try in order until the function is well-defined on them
+	bounds [][2]float64
+}
+
+var difficulties = []difficulty{
+	{
+		name:           "easy",
+		maxDepth:       2,
+		nodes:          []weightedNode{{addNode, 3}, {subtractNode, 2}, {multiplyNode, 3}},
+		maxCoefficient: 5,
+		bounds:         [][2]float64{{-10, 10}},
+	},
+	{
+		name:     "medium",
+		maxDepth: 3,
+		nodes: []weightedNode{
+			{addNode, 3}, {subtractNode, 2}, {multiplyNode, 3}, {divideNode, 1}, {powerNode, 2}, {callNode, 2},
+		},
+		functions:      []string{"sin", "cos", "exp", "sqrt", "abs"},
+		maxCoefficient: 9,
+		maxExponent:    3,
+		bounds:         [][2]float64{{-10, 10}, {0, 10}},
+	},
+	{
+		name:     "hard",
+		maxDepth: 4,
+		nodes: []weightedNode{
+			{addNode, 2}, {subtractNode, 2}, {multiplyNode, 3}, {divideNode, 2}, {powerNode, 2}, {callNode, 3},
+		},
+		functions:      []string{"sin", "cos", "tan", "exp", "ln", "log", "sqrt", "abs", "floor", "ceil", "min", "max"},
+		constants:      true,
+		maxCoefficient: 12,
+		maxExponent:    4,
+		bounds:         [][2]float64{{-20, 20}, {0, 20}, {1, 20}},
+	},
+}
+
+func getDifficulty(name string) (difficulty, bool) {
+	for _, d := range difficulties {
+		if strings.EqualFold(d.name, name) {
+			return d, true
+		}
+	}
+	return difficulty{}, false
+}
+
+func (d *difficulty) pickNode(rng *rand.Rand) nodeKind {
+	total := 0
+	for _, n := range d.nodes {
+		total += n.weight
+	}
+	r := rng.IntN(total)
+	for _, n := range d.nodes {
+		if r < n.weight {
+			return n.kind
+		}
+		r -= n.weight
+	}
+	return d.nodes[len(d.nodes)-1].kind
+}
+
+func (d *difficulty) coefficient(rng *rand.Rand) expr {
+	return number{Value: float64(1 + rng.IntN(d.maxCoefficient))}
+}
+
+func (d *difficulty) leaf(rng *rand.Rand) expr {
+	r := rng.Float64()
+	switch {
+	case d.constants && r < 0.1:
+		return constant{Name: []string{"pi", "e"}[rng.IntN(2)]}
+	case r < 0.55:
+		return variable{}
+	default:
+		return d.coefficient(rng)
+	}
+}
+
+// Generates an expression tree of at most the depth.
+func (d *difficulty) generate(depth int, rng *rand.Rand) expr {
+	if depth == 0 || (depth < d.maxDepth && rng.Float64() < leafProbability) {
+		return d.leaf(rng)
+	}
+
+	switch d.pickNode(rng) {
+	case addNode:
+		return add{Left: d.generate(depth-1, rng), Right: d.generate(depth-1, rng)}
+	case subtractNode:
+		return subtract{Left: d.generate(depth-1, rng), Right: d.generate(depth-1, rng)}
+	case multiplyNode:
+		return multiply{Left: d.generate(depth-1, rng), Right: d.generate(depth-1, rng)}
+	case divideNode:
+		return divide{Left: d.generate(depth-1, rng), Right: d.generate(depth-1, rng)}
+	case powerNode:
+		exponent := number{Value: float64(2 + rng.IntN(d.maxExponent-1))}
+		return power{Left: d.generate(depth-1, rng), Right: exponent}
+	default:
+		name := d.functions[rng.IntN(len(d.functions))]
+		args := make([]expr, functions[name].arity)
+		for i := range args {
+			args[i] = d.generate(depth-1, rng)
+		}
+		return call{Name: name, Args: args}
+	}
+}
+
+// Reports whether the function is unfit for a round on [lb, ub]: constant, undefined on too much
+// of the bounds, or too large.
+func isDegenerate(f expr, lb, ub float64) bool {
+	if !containsVariable(f) {
+		return true
+	}
+
+	var (
+		defined   []float64
+		undefined int
+	)
+	for i := range generateSamples {
+		y := f.Eval(lb + (ub-lb)*float64(i)/float64(generateSamples-1))
+		switch {
+		case isUndefined(y):
+			undefined++
+		case math.Abs(y) > maxGeneratedValue:
+			return true
+		default:
+			defined = append(defined, y)
+		}
+	}
+	if float64(undefined) > maxUndefinedFraction*float64(generateSamples) {
+		return true
+	}
+
+	// Functions like sin(x)^2 + cos(x)^2 are constant without simplifying to a number
+	for _, y := range defined {
+		if !approxEqual(y, defined[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Generates a simplified random function of the difficulty with the first bounds it is fit for a
+// round on. Returns false if no fit function was found.
+func generateFunction(d difficulty, rng *rand.Rand) (expr, float64, float64, bool) {
+	for range maxGenerateAttempts {
+		f := simplify(d.generate(d.maxDepth, rng))
+		for _, bounds := range d.bounds {
+			if !isDegenerate(f, bounds[0], bounds[1]) {
+				return f, bounds[0], bounds[1], true
+			}
+		}
+	}
+	return nil, 0, 0, false
+}
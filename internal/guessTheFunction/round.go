@@ -0,0 +1,155 @@
+package guessTheFunction
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	maxQueriesPerRound int = 30
+	maxQueriesPerUser  int = 10
+	// Revealing the derivative at a point costs this many queries.
+	derivativeHintCost int = 3
+)
+
+var (
+	ErrOutsideBounds = errors.New("query outside the bounds of the round")
+	ErrRoundBudget   = errors.New("query budget of the round is used up")
+	ErrUserBudget    = errors.New("query budget of the user is used up")
+)
+
+// A round of Guess the Function in a Discord channel.
+type Round struct {
+	ID        int
+	GuildID   string
+	ChannelID string
+	// Discord ID of the user who started the round, who can't take part in it
+	AuthorID string
+	// The function as the author wrote it
+	Definition string
+	// The function serialized by MarshalExpr
+	Function   json.RawMessage
+	LowerBound float64
+	UpperBound float64
+	Start      time.Time
+	// Zero while the round is active
+	End time.Time
+	// Discord ID of the user who guessed the function, empty if nobody did
+	WinnerID string
+	Queries  []Query
+	Guesses  []Guess
+
+	// The parsed function, loaded from Function when it is first needed
+	expr expr
+}
+
+// A query of the value or derivative of the function at a point.
+type Query struct {
+	UserID     string
+	X          float64
+	Y          float64
+	Derivative bool
+	Cost       int
+	Time       time.Time
+}
+
+type Guess struct {
+	UserID     string
+	Definition string
+	Correct    bool
+	Time       time.Time
+}
+
+// Returns a new round of the function, which is serialized so the round can be stored.
+func newRound(def string, f expr, lb, ub float64) (*Round, error) {
+	data, err := MarshalExpr(f)
+	if err != nil {
+		return nil, fmt.Errorf("serializing function: %w", err)
+	}
+	return &Round{Definition: def, Function: data, LowerBound: lb, UpperBound: ub, expr: f}, nil
+}
+
+func (r *Round) function() (expr, error) {
+	if r.expr == nil {
+		f, err := unmarshalExpr(r.Function)
+		if err != nil {
+			return nil, fmt.Errorf("loading function of round %d: %w", r.ID, err)
+		}
+		r.expr = f
+	}
+	return r.expr, nil
+}
+
+// Returns the number of queries used in the round.
+func (r *Round) queriesUsed() int {
+	used := 0
+	for _, q := range r.Queries {
+		used += q.Cost
+	}
+	return used
+}
+
+func (r *Round) queriesUsedBy(userID string) int {
+	used := 0
+	for _, q := range r.Queries {
+		if q.UserID == userID {
+			used += q.Cost
+		}
+	}
+	return used
+}
+
+// Returns the points revealed by value queries, which plots show.
+func (r *Round) points() []queryPoint {
+	var points []queryPoint
+	for _, q := range r.Queries {
+		if !q.Derivative {
+			points = append(points, queryPoint{x: q.X, y: q.Y})
+		}
+	}
+	return points
+}
+
+// Checks that the user may query the function at x at the cost, which must be in the bounds of the
+// round and within both the budget of the round and of the user.
+func (r *Round) checkQuery(userID string, x float64, cost int) error {
+	switch {
+	// NaN is not less or greater than any bound, so it would pass the bounds check
+	case math.IsNaN(x) || math.IsInf(x, 0):
+		return fmt.Errorf("%w: %g is not a finite number", ErrOutsideBounds, x)
+	case x < r.LowerBound || x > r.UpperBound:
+		return fmt.Errorf("%w: %g is not in [%g, %g]", ErrOutsideBounds, x, r.LowerBound, r.UpperBound)
+	case r.queriesUsed()+cost > maxQueriesPerRound:
+		return fmt.Errorf("%w: %d of %d used", ErrRoundBudget, r.queriesUsed(), maxQueriesPerRound)
+	case r.queriesUsedBy(userID)+cost > maxQueriesPerUser:
+		return fmt.Errorf("%w: %d of %d used", ErrUserBudget, r.queriesUsedBy(userID), maxQueriesPerUser)
+	}
+	return nil
+}
+
+// Evaluates the function, or its derivative, at x for the user and records the query in the
+// round. The query still has to be stored.
+func (r *Round) query(userID string, x float64, derivative bool, now time.Time) (Query, error) {
+	q := Query{UserID: userID, X: x, Derivative: derivative, Cost: 1, Time: now}
+	if derivative {
+		q.Cost = derivativeHintCost
+	}
+	if err := r.checkQuery(userID, x, q.Cost); err != nil {
+		return Query{}, err
+	}
+
+	f, err := r.function()
+	if err != nil {
+		return Query{}, err
+	}
+	if derivative {
+		q.Y = f.Derive().Eval(x)
+	} else {
+		q.Y = f.Eval(x)
+	}
+	r.Queries = append(r.Queries, q)
+	return q, nil
+}
@@ -0,0 +1,136 @@
+package guessTheFunction
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoRound     = errors.New("no round found")
+	ErrActiveRound = errors.New("the channel already has an active round")
+)
+
+type RoundStore interface {
+	// Stores the new round and sets its ID. Returns ErrActiveRound if the channel already has an
+	// active round.
+	CreateRound(ctx context.Context, r *Round) error
+	// Returns the active round of the channel with its queries and guesses, or ErrNoRound if
+	// there is none.
+	GetActiveRound(ctx context.Context, channelID string) (*Round, error)
+	// Returns the latest finished rounds of the channel with their queries and guesses, newest
+	// first.
+	GetFinishedRounds(ctx context.Context, channelID string, limit int) ([]Round, error)
+	AddQuery(ctx context.Context, roundID int, q Query) error
+	AddGuess(ctx context.Context, roundID int, g Guess) error
+	// Marks the round as finished, won by the user if winnerID is not empty.
+	FinishRound(ctx context.Context, roundID int, winnerID string, end time.Time) error
+}
+
+// Keeps rounds in memory, so they are lost when the bot restarts.
+type memoryRoundStore struct {
+	mu     sync.Mutex
+	rounds []*Round
+}
+
+func NewMemoryRoundStore() RoundStore {
+	return &memoryRoundStore{}
+}
+
+// Returns a copy of the round that does not share its queries and guesses.
+func copyRound(r *Round) Round {
+	c := *r
+	c.Queries = slices.Clone(r.Queries)
+	c.Guesses = slices.Clone(r.Guesses)
+	return c
+}
+
+func (s *memoryRoundStore) CreateRound(ctx context.Context, r *Round) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, other := range s.rounds {
+		if other.ChannelID == r.ChannelID && other.End.IsZero() {
+			return ErrActiveRound
+		}
+	}
+	r.ID = len(s.rounds) + 1
+	c := copyRound(r)
+	s.rounds = append(s.rounds, &c)
+	return nil
+}
+
+func (s *memoryRoundStore) GetActiveRound(ctx context.Context, channelID string) (*Round, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range s.rounds {
+		if r.ChannelID == channelID && r.End.IsZero() {
+			c := copyRound(r)
+			return &c, nil
+		}
+	}
+	return nil, ErrNoRound
+}
+
+func (s *memoryRoundStore) GetFinishedRounds(ctx context.Context, channelID string, limit int) ([]Round, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rounds []Round
+	for _, r := range slices.Backward(s.rounds) {
+		if len(rounds) == limit {
+			break
+		}
+		if r.ChannelID == channelID && !r.End.IsZero() {
+			rounds = append(rounds, copyRound(r))
+		}
+	}
+	return rounds, nil
+}
+
+// Returns the round of the ID, which the caller must hold the lock for.
+func (s *memoryRoundStore) round(roundID int) (*Round, error) {
+	if roundID < 1 || roundID > len(s.rounds) {
+		return nil, ErrNoRound
+	}
+	return s.rounds[roundID-1], nil
+}
+
+func (s *memoryRoundStore) AddQuery(ctx context.Context, roundID int, q Query) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.round(roundID)
+	if err != nil {
+		return err
+	}
+	r.Queries = append(r.Queries, q)
+	return nil
+}
+
+func (s *memoryRoundStore) AddGuess(ctx context.Context, roundID int, g Guess) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.round(roundID)
+	if err != nil {
+		return err
+	}
+	r.Guesses = append(r.Guesses, g)
+	return nil
+}
+
+func (s *memoryRoundStore) FinishRound(ctx context.Context, roundID int, winnerID string, end time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.round(roundID)
+	if err != nil {
+		return err
+	}
+	r.WinnerID, r.End = winnerID, end
+	return nil
+}
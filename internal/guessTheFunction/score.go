@@ -0,0 +1,101 @@
+package guessTheFunction
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+)
+
+const (
+	maxPoints int = 100
+	minPoints int = 10
+	// Points lost for every query used in the round, by anyone
+	queryPenalty int = 2
+	// Points lost for every started period of this length since the round started
+	timePenaltyPeriod time.Duration = time.Minute
+)
+
+type ScoreRepository interface {
+	// Stores the points a user was awarded for guessing the function of a round.
+	AddScore(ctx context.Context, score Score) error
+	// Returns the total points of every user with points in the guild, highest first.
+	GetLeaderboard(ctx context.Context, guildID string) ([]PlayerTotal, error)
+}
+
+type Repository interface {
+	RoundStore
+	ScoreRepository
+}
+
+// The points awarded for a correct guess.
+type Score struct {
+	GuildID   string
+	DiscordID string
+	// Username at the time of the guess, shown for users who have left the guild
+	Username string
+	Points   int
+	Queries  int
+	Duration time.Duration
+	Time     time.Time
+}
+
+type PlayerTotal struct {
+	DiscordID string
+	Username  string
+	Points    int
+	Rounds    int
+}
+
+// Returns the points for guessing a function after the queries and time, where fewer queries and
+// faster guesses give more points.
+func roundPoints(queries int, elapsed time.Duration) int {
+	periods := int((elapsed + timePenaltyPeriod - 1) / timePenaltyPeriod)
+	return max(minPoints, maxPoints-queryPenalty*queries-periods)
+}
+
+// Returns the score of the user for guessing the round at now, and whether it counts. Random
+// rounds are authored by the bot with ID botID, and are unscored solo practice.
+func scoreGuess(r *Round, user *discordgo.User, botID string, now time.Time) (Score, bool) {
+	score := Score{
+		GuildID:   r.GuildID,
+		DiscordID: user.ID,
+		Username:  user.Username,
+		Queries:   r.queriesUsed(),
+		Duration:  now.Sub(r.Start),
+		Time:      now,
+	}
+	if r.AuthorID == botID {
+		return score, false
+	}
+	score.Points = roundPoints(score.Queries, score.Duration)
+	return score, true
+}
+
+// Sends the leaderboard of the total points of the guild, with a PNG table attached if withImage
+// is true.
+func (h *Handler) sendLeaderboard(guildID, channelID string, withImage bool) error {
+	totals, err := h.db.GetLeaderboard(context.TODO(), guildID)
+	if err != nil {
+		return fmt.Errorf("getting leaderboard of guild %s: %w", guildID, err)
+	}
+
+	lb := judge.Leaderboard{
+		Title:     "Guess the Function leaderboard",
+		ValueName: "Points",
+		Entries:   make([]judge.LeaderboardEntry, len(totals)),
+	}
+	if guild, err := h.discord.State.Guild(guildID); err == nil {
+		lb.Title = guild.Name + " " + lb.Title
+	}
+	for i, t := range totals {
+		lb.Entries[i] = judge.LeaderboardEntry{
+			DiscordID: t.DiscordID,
+			Handle:    t.Username,
+			Value:     fmt.Sprintf("%d (%d rounds)", t.Points, t.Rounds),
+		}
+	}
+	return judge.SendLeaderboard(h.discord, guildID, channelID, &lb, withImage)
+}
@@ -0,0 +1,396 @@
+package guessTheFunction
+
+import (
+	"maps"
+	"math"
+	"slices"
+)
+
+// Polynomials are only expanded up to this degree, so (x+1)^1000 is left alone.
+const maxPolynomialDegree int = 32
+
+// A polynomial in x as the coefficient of every degree.
+type polynomial map[int]float64
+
+func (p polynomial) degree() int {
+	d := 0
+	for deg, c := range p {
+		if c != 0 {
+			d = max(d, deg)
+		}
+	}
+	return d
+}
+
+func (p polynomial) plus(q polynomial, scale float64) polynomial {
+	result := maps.Clone(p)
+	for deg, c := range q {
+		result[deg] += scale * c
+	}
+	return result
+}
+
+func (p polynomial) times(q polynomial) (polynomial, bool) {
+	if p.degree()+q.degree() > maxPolynomialDegree {
+		return nil, false
+	}
+	result := polynomial{}
+	for d1, c1 := range p {
+		for d2, c2 := range q {
+			result[d1+d2] += c1 * c2
+		}
+	}
+	return result, true
+}
+
+// Returns the expression as a polynomial if it only consists of numbers, x, sums, products and
+// powers with small non-negative integer exponents.
+func toPolynomial(e expr) (polynomial, bool) {
+	switch v := e.(type) {
+	case number:
+		return polynomial{0: v.Value}, true
+	case variable:
+		return polynomial{1: 1}, true
+	case add:
+		return binaryPolynomial(v.Left, v.Right, func(p, q polynomial) (polynomial, bool) { return p.plus(q, 1), true })
+	case subtract:
+		return binaryPolynomial(v.Left, v.Right, func(p, q polynomial) (polynomial, bool) { return p.plus(q, -1), true })
+	case multiply:
+		return binaryPolynomial(v.Left, v.Right, polynomial.times)
+	case power:
+		exponent, ok := v.Right.(number)
+		if !ok || exponent.Value < 0 || exponent.Value != math.Trunc(exponent.Value) ||
+			exponent.Value > float64(maxPolynomialDegree) {
+			return nil, false
+		}
+		base, ok := toPolynomial(v.Left)
+		if !ok {
+			return nil, false
+		}
+		result := polynomial{0: 1}
+		for range int(exponent.Value) {
+			if result, ok = result.times(base); !ok {
+				return nil, false
+			}
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+func binaryPolynomial(left, right expr, combine func(p, q polynomial) (polynomial, bool)) (polynomial, bool) {
+	p, ok := toPolynomial(left)
+	if !ok {
+		return nil, false
+	}
+	q, ok := toPolynomial(right)
+	if !ok {
+		return nil, false
+	}
+	return combine(p, q)
+}
+
+// A term of a sum, which is coef times e, or just coef if e is nil.
+type term struct {
+	coef float64
+	e    expr
+}
+
+func (t term) toExpr() expr {
+	switch {
+	case t.e == nil:
+		return number{Value: t.coef}
+	case t.coef == 1:
+		return t.e
+	default:
+		return multiply{Left: number{Value: t.coef}, Right: t.e}
+	}
+}
+
+// Builds the sum of the terms in order, subtracting terms with negative coefficients. Zero terms
+// are left out unless they are undefined somewhere, like 0ln(x).
+func buildSum(terms []term) expr {
+	var result expr
+	for _, t := range terms {
+		if t.coef == 0 && t.e == nil {
+			continue
+		}
+		switch {
+		case result == nil:
+			result = t.toExpr()
+		case t.coef < 0:
+			result = subtract{Left: result, Right: term{coef: -t.coef, e: t.e}.toExpr()}
+		default:
+			result = add{Left: result, Right: t.toExpr()}
+		}
+	}
+	if result == nil {
+		return number{Value: 0}
+	}
+	return result
+}
+
+func monomial(degree int) expr {
+	switch degree {
+	case 0:
+		return nil
+	case 1:
+		return variable{}
+	default:
+		return power{Left: variable{}, Right: number{Value: float64(degree)}}
+	}
+}
+
+// Returns the terms of the polynomial from the highest degree to the constant.
+func (p polynomial) terms() []term {
+	degrees := slices.Sorted(maps.Keys(p))
+	slices.Reverse(degrees)
+
+	var terms []term
+	for _, deg := range degrees {
+		if p[deg] != 0 {
+			terms = append(terms, term{coef: p[deg], e: monomial(deg)})
+		}
+	}
+	return terms
+}
+
+// Splits a constant coefficient from the expression. Ex: 2sin(x) gives 2 and sin(x)
+func splitCoefficient(e expr) (float64, expr) {
+	if m, ok := e.(multiply); ok {
+		if n, ok := m.Left.(number); ok {
+			return n.Value, m.Right
+		}
+	}
+	return 1, e
+}
+
+// Folds an operation on numbers into a number, unless the result is undefined.
+func fold(value float64) (expr, bool) {
+	if isUndefined(value) {
+		return nil, false
+	}
+	return number{Value: value}, true
+}
+
+// Simplifies the expression by folding constants, removing identities like x*1, x+0 and x^1,
+// and collecting like terms. Sums and products are ordered the same way whatever order they
+// were written in, so equal expressions simplify to the same string.
+func simplify(e expr) expr {
+	switch v := e.(type) {
+	case add, subtract:
+		return simplifySum(v)
+	case multiply:
+		return simplifyProduct(v)
+	case divide:
+		left, right := simplify(v.Left), simplify(v.Right)
+		l, lNum := left.(number)
+		r, rNum := right.(number)
+		if lNum && rNum {
+			if folded, ok := fold(l.Value / r.Value); ok {
+				return folded
+			}
+		}
+		if rNum && r.Value == 1 {
+			return left
+		}
+		return divide{Left: left, Right: right}
+	case power:
+		left, right := simplify(v.Left), simplify(v.Right)
+		l, lNum := left.(number)
+		r, rNum := right.(number)
+		switch {
+		case lNum && rNum:
+			if folded, ok := fold(math.Pow(l.Value, r.Value)); ok {
+				return folded
+			}
+		case rNum && r.Value == 1:
+			return left
+		case rNum && r.Value == 0, lNum && l.Value == 1:
+			return number{Value: 1}
+		}
+		return power{Left: left, Right: right}
+	case call:
+		args := make([]expr, len(v.Args))
+		values := make([]float64, len(v.Args))
+		allNumbers := true
+		for i, arg := range v.Args {
+			args[i] = simplify(arg)
+			n, ok := args[i].(number)
+			values[i], allNumbers = n.Value, allNumbers && ok
+		}
+		// Only fold whole results, so sin(2) is not replaced by a long decimal
+		if allNumbers {
+			value := functions[v.Name].eval(values)
+			if folded, ok := fold(value); ok && value == math.Trunc(value) {
+				return folded
+			}
+		}
+		return call{Name: v.Name, Args: args}
+	default:
+		return e
+	}
+}
+
+// Simplifies a sum by adding up its polynomial terms and the coefficients of equal terms. The
+// polynomial terms come first by descending degree, then the other terms, then the constant.
+func simplifySum(e expr) expr {
+	poly := polynomial{}
+	others := make(map[string]*term)
+	var order []string
+
+	var collect func(e expr, coef float64)
+	collect = func(e expr, coef float64) {
+		switch v := e.(type) {
+		case add:
+			collect(v.Left, coef)
+			collect(v.Right, coef)
+			return
+		case subtract:
+			collect(v.Left, coef)
+			collect(v.Right, -coef)
+			return
+		}
+
+		simplified := simplify(e)
+		if p, ok := toPolynomial(simplified); ok {
+			poly = poly.plus(p, coef)
+			return
+		}
+		switch simplified.(type) {
+		case add, subtract:
+			collect(simplified, coef)
+			return
+		}
+
+		c, rest := splitCoefficient(simplified)
+		key := rest.String()
+		if t, ok := others[key]; ok {
+			t.coef += coef * c
+		} else {
+			others[key] = &term{coef: coef * c, e: rest}
+			order = append(order, key)
+		}
+	}
+	collect(e, 1)
+
+	terms := poly.terms()
+	constTerm := term{}
+	if len(terms) > 0 && terms[len(terms)-1].e == nil {
+		constTerm = terms[len(terms)-1]
+		terms = terms[:len(terms)-1]
+	}
+	slices.Sort(order)
+	for _, key := range order {
+		terms = append(terms, *others[key])
+	}
+	return buildSum(append(terms, constTerm))
+}
+
+// Simplifies a product by multiplying its numbers and polynomial factors together, and adding up
+// the non-negative integer exponents of equal factors. Other exponents are left alone, as
+// cancelling them changes where the product is defined, like x/x does. The coefficient comes
+// first, then the polynomial, then the other factors.
+func simplifyProduct(e expr) expr {
+	coef := 1.0
+	poly := polynomial{0: 1}
+	exponents := make(map[string][]float64)
+	bases := make(map[string]expr)
+
+	var collect func(e expr)
+	collect = func(e expr) {
+		if m, ok := e.(multiply); ok {
+			collect(m.Left)
+			collect(m.Right)
+			return
+		}
+
+		simplified := simplify(e)
+		if m, ok := simplified.(multiply); ok {
+			collect(m.Left)
+			collect(m.Right)
+			return
+		}
+		if n, ok := simplified.(number); ok {
+			coef *= n.Value
+			return
+		}
+		if p, ok := toPolynomial(simplified); ok {
+			if product, ok := poly.times(p); ok {
+				poly = product
+				return
+			}
+		}
+
+		base, exponent := simplified, 1.0
+		if p, ok := simplified.(power); ok {
+			if n, ok := p.Right.(number); ok {
+				base, exponent = p.Left, n.Value
+			}
+		}
+		key := base.String()
+		bases[key] = base
+		exponents[key] = append(exponents[key], exponent)
+	}
+	collect(e)
+
+	var factors []expr
+	for _, key := range slices.Sorted(maps.Keys(exponents)) {
+		keyExponents := exponents[key]
+		if !slices.ContainsFunc(keyExponents, func(exponent float64) bool {
+			return exponent < 0 || exponent != math.Trunc(exponent)
+		}) {
+			sum := 0.0
+			for _, exponent := range keyExponents {
+				sum += exponent
+			}
+			keyExponents = []float64{sum}
+		}
+		slices.Sort(keyExponents)
+
+		for _, exponent := range keyExponents {
+			if exponent == 1 {
+				factors = append(factors, bases[key])
+			} else {
+				factors = append(factors, power{Left: bases[key], Right: number{Value: exponent}})
+			}
+		}
+	}
+
+	for deg := range poly {
+		poly[deg] *= coef
+	}
+
+	// A zero coefficient leaves the other factors, so the product stays undefined where they are.
+	// A single term polynomial gives the coefficient, e.g. 2x^2, otherwise the coefficient is
+	// part of the polynomial, e.g. (2x + 2)
+	leading := 1.0
+	var body expr
+	if terms := poly.terms(); len(terms) == 1 {
+		leading, body = terms[0].coef, terms[0].e
+	} else {
+		body = buildSum(terms)
+	}
+	for _, f := range factors {
+		if body == nil {
+			body = f
+		} else {
+			body = multiply{Left: body, Right: f}
+		}
+	}
+
+	switch {
+	case body == nil:
+		return number{Value: leading}
+	case leading == 1:
+		return body
+	default:
+		return multiply{Left: number{Value: leading}, Right: body}
+	}
+}
+
+// Reports whether the expressions simplify to the same expression.
+func identical(a, b expr) bool {
+	return simplify(a).String() == simplify(b).String()
+}
@@ -0,0 +1,402 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/utils"
+)
+
+type AccountRepository interface {
+	// Links the Discord user to the handle, replacing any account it had on the platform.
+	// Returns ErrHandleTaken if another Discord user has linked the handle.
+	LinkAccount(ctx context.Context, platform, discID, handle string) error
+	// Returns ErrAccountNotLinked if the user has not linked an account on the platform.
+	UnlinkAccount(ctx context.Context, platform, discID string) error
+	// Returns ErrAccountNotLinked if the user has not linked an account on the platform.
+	GetLinkedAccount(ctx context.Context, platform, discID string) (string, error)
+	// Returns the Discord ID of the user the handle is linked to, or ErrAccountNotLinked.
+	GetAccountOwner(ctx context.Context, platform, handle string) (string, error)
+	GetLinkedAccounts(ctx context.Context, platform string) ([]Account, error)
+
+	AddAuditEntry(ctx context.Context, entry AuditEntry) error
+	// Returns the latest audit entries of the platform made in the guild, or made outside of any
+	// guild for one of the members, newest first.
+	GetAuditEntries(ctx context.Context, platform, guildID string, memberIDs []string, limit int) ([]AuditEntry, error)
+}
+
+// A Discord user and the handle of the account it has linked on a platform.
+type Account struct {
+	DiscordID string
+	Platform  string
+	Handle    string
+}
+
+type AuditAction string
+
+const (
+	AuditLink        AuditAction = "link"
+	AuditUnlink      AuditAction = "unlink"
+	AuditAdminLink   AuditAction = "admin_link"
+	AuditAdminUnlink AuditAction = "admin_unlink"
+	AuditRename      AuditAction = "rename"
+)
+
+// A change to a linked account. ActorID is empty when the change was made automatically by the bot.
+type AuditEntry struct {
+	Platform string
+	// Guild the change was made in, empty for changes made by the bot or in DMs
+	GuildID   string
+	DiscordID string
+	ActorID   string
+	Action    AuditAction
+	OldHandle string
+	NewHandle string
+	Time      time.Time
+}
+
+// Stores an audit entry. Failing to do so should not stop the change being audited,
+// so errors are only logged.
+func AddAuditEntry(db AccountRepository, entry AuditEntry) {
+	err := db.AddAuditEntry(context.TODO(), entry)
+	if err != nil {
+		log.Printf("Failed to store audit entry %+v: %s", entry, err)
+	}
+}
+
+// Admins can list at most this many audit entries at once.
+const maxAuditCount int = 100
+
+// Called in a new goroutine after a Discord user links or unlinks an account.
+type AccountChangeHook func(discID string)
+
+// Manages existing links between Discord users and accounts on a judge.
+// Handles unlinking and admin moderation.
+type AccountService struct {
+	db      AccountRepository
+	discord *discordgo.Session
+	judge   Judge
+
+	defaultAuditCount int
+	onChange          AccountChangeHook
+}
+
+type AccountOption func(*AccountService)
+
+func NewAccountService(db AccountRepository, discord *discordgo.Session, judge Judge,
+	opts ...AccountOption) *AccountService {
+
+	const defaultAuditCount int = 10
+
+	s := &AccountService{
+		db:                db,
+		discord:           discord,
+		judge:             judge,
+		defaultAuditCount: defaultAuditCount,
+		onChange:          func(string) {},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Sets a hook that is called when a user unlinks, or an admin links or unlinks a user.
+func WithAccountChangeHook(hook AccountChangeHook) AccountOption {
+	return func(s *AccountService) {
+		s.onChange = hook
+	}
+}
+
+func (s *AccountService) UnlinkCommand(m *discordgo.MessageCreate) error {
+	handle, err := s.db.GetLinkedAccount(context.TODO(), s.judge.Platform(), m.Author.ID)
+	if errors.Is(err, ErrAccountNotLinked) {
+		return s.sendNotConnectedMessage(m.ChannelID, m.Author.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("getting linked %s account of %s: %w", s.judge.Name(), m.Author.ID, err)
+	}
+
+	err = s.db.UnlinkAccount(context.TODO(), s.judge.Platform(), m.Author.ID)
+	if err != nil {
+		return fmt.Errorf("unlinking %s account of %s: %w", s.judge.Name(), m.Author.ID, err)
+	}
+	AddAuditEntry(s.db, AuditEntry{
+		Platform:  s.judge.Platform(),
+		GuildID:   m.GuildID,
+		DiscordID: m.Author.ID,
+		ActorID:   m.Author.ID,
+		Action:    AuditUnlink,
+		OldHandle: handle,
+	})
+	go s.onChange(m.Author.ID)
+
+	log.Printf("Discord user %s (%s) unlinked %s handle '%s'.",
+		m.Author.ID, m.Author.Username, s.judge.Name(), handle)
+	msgStr := fmt.Sprintf("<@%s> is no longer connected to the %s user '%s'.", m.Author.ID, s.judge.Name(), handle)
+	_, err = s.discord.ChannelMessageSend(m.ChannelID, msgStr)
+	return err
+}
+
+// Handles `admin list|link|unlink|audit`, args[2] being the subcommand.
+func (s *AccountService) AdminCommand(args []string, m *discordgo.MessageCreate) error {
+	if len(args) < 3 {
+		return utils.UnknownCommand(s.discord, m)
+	}
+
+	isAdmin, err := utils.IsAdmin(s.discord, m.Author.ID, m.ChannelID)
+	if err != nil {
+		return fmt.Errorf("checking permissions of %s: %w", m.Author.ID, err)
+	}
+	if !isAdmin {
+		return utils.NoPermission(s.discord, m)
+	}
+
+	switch args[2] {
+	case "list":
+		return s.listConnections(m)
+	case "link":
+		if len(args) != 5 {
+			return utils.UnknownCommand(s.discord, m)
+		}
+		return s.forceLink(args[3], args[4], m)
+	case "unlink":
+		if len(args) != 4 {
+			return utils.UnknownCommand(s.discord, m)
+		}
+		return s.forceUnlink(args[3], m)
+	case "audit":
+		return s.listAuditEntries(args, m)
+	default:
+		return utils.UnknownCommand(s.discord, m)
+	}
+}
+
+// Lists every linked account belonging to a member of the guild the message was sent in.
+func (s *AccountService) listConnections(m *discordgo.MessageCreate) error {
+	accounts, err := GetLinkedAccountsInGuild(context.TODO(), s.db, s.discord, s.judge.Platform(), m.GuildID)
+	if err != nil {
+		return err
+	}
+
+	msgStr := fmt.Sprintf("## Connected %s users", s.judge.Name())
+	for i, a := range accounts {
+		msgStr += fmt.Sprintf("\n%d. <@%s>: %s", i+1, a.DiscordID, a.Handle)
+	}
+	if len(accounts) == 0 {
+		msgStr += fmt.Sprintf("\nNo members of this server are connected to %s.", s.judge.Name())
+	}
+
+	msgData := discordgo.MessageSend{
+		Content: msgStr,
+		Flags:   discordgo.MessageFlagsSuppressNotifications,
+	}
+	return utils.SendSplitMessage(s.discord, m.ChannelID, &msgData)
+}
+
+func (s *AccountService) forceLink(mention, handle string, m *discordgo.MessageCreate) error {
+	discID, ok := utils.ParseUserMention(mention)
+	if !ok {
+		return utils.UnknownCommand(s.discord, m)
+	}
+	if ok, err := s.checkMember(discID, m); !ok {
+		return err
+	}
+
+	userExists, err := s.judge.UserExists(context.TODO(), handle)
+	if err != nil {
+		return fmt.Errorf("failed to check existence of %s user '%s': %w", s.judge.Name(), handle, err)
+	}
+	if !userExists {
+		_, err = s.discord.ChannelMessageSend(m.ChannelID,
+			fmt.Sprintf("Could not find a %s user with the name '%s'.", s.judge.Name(), handle))
+		return err
+	}
+
+	oldHandle, err := s.db.GetLinkedAccount(context.TODO(), s.judge.Platform(), discID)
+	if err != nil && !errors.Is(err, ErrAccountNotLinked) {
+		return fmt.Errorf("getting linked %s account of %s: %w", s.judge.Name(), discID, err)
+	}
+
+	err = s.db.LinkAccount(context.TODO(), s.judge.Platform(), discID, handle)
+	if errors.Is(err, ErrHandleTaken) {
+		_, err = s.discord.ChannelMessageSend(m.ChannelID,
+			fmt.Sprintf("The %s user '%s' is already connected to another Discord user.", s.judge.Name(), handle))
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("linking %s to '%s': %w", discID, handle, err)
+	}
+	AddAuditEntry(s.db, AuditEntry{
+		Platform:  s.judge.Platform(),
+		GuildID:   m.GuildID,
+		DiscordID: discID,
+		ActorID:   m.Author.ID,
+		Action:    AuditAdminLink,
+		OldHandle: oldHandle,
+		NewHandle: handle,
+	})
+	go s.onChange(discID)
+
+	log.Printf("Admin %s (%s) linked Discord user %s to %s handle '%s'.",
+		m.Author.ID, m.Author.Username, discID, s.judge.Name(), handle)
+	msgData := discordgo.MessageSend{
+		Content: fmt.Sprintf("<@%s> is now connected to the %s user '%s'.", discID, s.judge.Name(), handle),
+		Flags:   discordgo.MessageFlagsSuppressNotifications,
+	}
+	_, err = s.discord.ChannelMessageSendComplex(m.ChannelID, &msgData)
+	return err
+}
+
+func (s *AccountService) forceUnlink(mention string, m *discordgo.MessageCreate) error {
+	discID, ok := utils.ParseUserMention(mention)
+	if !ok {
+		return utils.UnknownCommand(s.discord, m)
+	}
+	if ok, err := s.checkMember(discID, m); !ok {
+		return err
+	}
+
+	handle, err := s.db.GetLinkedAccount(context.TODO(), s.judge.Platform(), discID)
+	if errors.Is(err, ErrAccountNotLinked) {
+		return s.sendNotConnectedMessage(m.ChannelID, discID)
+	}
+	if err != nil {
+		return fmt.Errorf("getting linked %s account of %s: %w", s.judge.Name(), discID, err)
+	}
+
+	err = s.db.UnlinkAccount(context.TODO(), s.judge.Platform(), discID)
+	if err != nil {
+		return fmt.Errorf("unlinking %s account of %s: %w", s.judge.Name(), discID, err)
+	}
+	AddAuditEntry(s.db, AuditEntry{
+		Platform:  s.judge.Platform(),
+		GuildID:   m.GuildID,
+		DiscordID: discID,
+		ActorID:   m.Author.ID,
+		Action:    AuditAdminUnlink,
+		OldHandle: handle,
+	})
+	go s.onChange(discID)
+
+	log.Printf("Admin %s (%s) unlinked %s handle '%s' from Discord user %s.",
+		m.Author.ID, m.Author.Username, s.judge.Name(), handle, discID)
+	msgData := discordgo.MessageSend{
+		Content: fmt.Sprintf("<@%s> is no longer connected to the %s user '%s'.", discID, s.judge.Name(), handle),
+		Flags:   discordgo.MessageFlagsSuppressNotifications,
+	}
+	_, err = s.discord.ChannelMessageSendComplex(m.ChannelID, &msgData)
+	return err
+}
+
+func (s *AccountService) listAuditEntries(args []string, m *discordgo.MessageCreate) error {
+	count := s.defaultAuditCount
+	if len(args) >= 4 {
+		var err error
+		count, err = strconv.Atoi(args[3])
+		if err != nil || count <= 0 {
+			return utils.UnknownCommand(s.discord, m)
+		}
+		count = min(count, maxAuditCount)
+	}
+
+	members, err := utils.GetGuildMembers(s.discord, m.GuildID)
+	if err != nil {
+		return err
+	}
+	memberIDs := make([]string, len(members))
+	for i, member := range members {
+		memberIDs[i] = member.User.ID
+	}
+	entries, err := s.db.GetAuditEntries(context.TODO(), s.judge.Platform(), m.GuildID, memberIDs, count)
+	if err != nil {
+		return fmt.Errorf("getting audit entries: %w", err)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## %s connection audit log", s.judge.Name())
+	if len(entries) == 0 {
+		sb.WriteString("\nNo entries.")
+	}
+	for _, e := range entries {
+		actor := "bot"
+		if e.ActorID != "" {
+			actor = fmt.Sprintf("<@%s>", e.ActorID)
+		}
+		fmt.Fprintf(&sb, "\n<t:%d:f> **%s** <@%s> by %s", e.Time.Unix(), e.Action, e.DiscordID, actor)
+		if e.OldHandle != "" {
+			fmt.Fprintf(&sb, " from '%s'", e.OldHandle)
+		}
+		if e.NewHandle != "" {
+			fmt.Fprintf(&sb, " to '%s'", e.NewHandle)
+		}
+	}
+
+	msgData := discordgo.MessageSend{
+		Content: sb.String(),
+		Flags:   discordgo.MessageFlagsSuppressNotifications,
+	}
+	return utils.SendSplitMessage(s.discord, m.ChannelID, &msgData)
+}
+
+// Returns true if the user is a member of the guild the message was sent in, and otherwise tells
+// the admin that admins can only manage members of their own server.
+func (s *AccountService) checkMember(discID string, m *discordgo.MessageCreate) (bool, error) {
+	isMember, err := utils.IsGuildMember(s.discord, m.GuildID, discID)
+	if err != nil {
+		return false, err
+	}
+	if !isMember {
+		msgData := discordgo.MessageSend{
+			Content: fmt.Sprintf("<@%s> is not a member of this server.", discID),
+			Flags:   discordgo.MessageFlagsSuppressNotifications,
+		}
+		_, err = s.discord.ChannelMessageSendComplex(m.ChannelID, &msgData)
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *AccountService) sendNotConnectedMessage(channelID, discID string) error {
+	msgData := discordgo.MessageSend{
+		Content: fmt.Sprintf("<@%s> is not connected to a %s user.", discID, s.judge.Name()),
+		Flags:   discordgo.MessageFlagsSuppressNotifications,
+	}
+	_, err := s.discord.ChannelMessageSendComplex(channelID, &msgData)
+	return err
+}
+
+// Returns the accounts on the platform linked by members of the guild.
+func GetLinkedAccountsInGuild(ctx context.Context, db AccountRepository, discord *discordgo.Session,
+	platform, guildID string) ([]Account, error) {
+
+	accounts, err := db.GetLinkedAccounts(ctx, platform)
+	if err != nil {
+		return nil, fmt.Errorf("getting linked %s accounts: %w", platform, err)
+	}
+
+	guildMembers, err := utils.GetGuildMembers(discord, guildID)
+	if err != nil {
+		return nil, err
+	}
+	members := make(map[string]struct{}, len(guildMembers))
+	for _, member := range guildMembers {
+		members[member.User.ID] = struct{}{}
+	}
+
+	var result []Account
+	for _, a := range accounts {
+		if _, ok := members[a.DiscordID]; ok {
+			result = append(result, a)
+		}
+	}
+	return result, nil
+}
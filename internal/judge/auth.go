@@ -0,0 +1,306 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/utils"
+)
+
+// Links Discord users to accounts on a judge after verifying that they own the account.
+type AuthService struct {
+	db       AccountRepository
+	discord  *discordgo.Session
+	judge    Judge
+	sessions *authSessionManager
+
+	checkInterval time.Duration
+	onLink        AccountChangeHook
+}
+
+// The AuthService uses functional options for easier configuration
+type AuthOption func(*AuthService)
+
+func NewAuthService(db AccountRepository, discord *discordgo.Session, judge Judge, opts ...AuthOption) *AuthService {
+	const defaultCheckInterval time.Duration = 5 * time.Second
+
+	s := &AuthService{
+		db:            db,
+		discord:       discord,
+		judge:         judge,
+		sessions:      newAuthSessionManager(),
+		checkInterval: defaultCheckInterval,
+		onLink:        func(string) {},
+	}
+
+	// Apply each of the function options to the service
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Sets how often the verifier is checked while an authentication is in progress.
+func WithCheckInterval(interval time.Duration) AuthOption {
+	return func(s *AuthService) {
+		s.checkInterval = interval
+	}
+}
+
+// Sets a hook that is called when a user has authenticated and linked their account.
+func WithLinkHook(hook AccountChangeHook) AuthOption {
+	return func(s *AuthService) {
+		s.onLink = hook
+	}
+}
+
+// Handles `authenticate [handle] [method]` and `authenticate cancel`, args[2] being the first
+// argument after authenticate.
+func (s *AuthService) AuthCommand(args []string, m *discordgo.MessageCreate) error {
+	// Ensure correct argument count
+	if len(args) < 3 {
+		err := utils.UnknownCommand(s.discord, m)
+		return err
+	}
+	if args[2] == "cancel" {
+		return s.cancelCommand(m)
+	}
+	handle := args[2]
+
+	methodName := s.judge.DefaultVerifier()
+	if len(args) >= 4 {
+		methodName = args[3]
+	}
+	verifier, ok := s.judge.NewVerifier(methodName)
+	if !ok {
+		return utils.UnknownCommand(s.discord, m)
+	}
+
+	log.Printf("Received %s authenticate (%s) for user with handle '%s' from %s (%s).",
+		s.judge.Name(), methodName, handle, m.Author.ID, m.Author.Username)
+
+	// Register the session before doing anything else, so that concurrent commands from the same
+	// user cannot both pass the checks below
+	ctx, active, ok := s.sessions.start(m.Author.ID, handle, verifier.Timeout())
+	if !ok {
+		return s.onSessionActive(active, m)
+	}
+	started := false
+	defer func() {
+		if !started {
+			s.sessions.end(m.Author.ID)
+		}
+	}()
+
+	connectedHandle, err := s.db.GetLinkedAccount(ctx, s.judge.Platform(), m.Author.ID)
+	if !errors.Is(err, ErrAccountNotLinked) {
+		if err != nil {
+			log.Println("Failed to check in database:", err)
+		} else if connectedHandle != "" {
+			err = s.onAlreadyConnected(connectedHandle, m)
+			if err != nil {
+				log.Println("Failed to send already connected message:", err)
+			}
+			return nil
+		}
+	}
+
+	owner, err := s.db.GetAccountOwner(ctx, s.judge.Platform(), handle)
+	if err != nil && !errors.Is(err, ErrAccountNotLinked) {
+		return fmt.Errorf("checking owner of %s handle '%s': %w", s.judge.Name(), handle, err)
+	}
+	if err == nil {
+		log.Printf("%s handle '%s' is already connected to Discord user %s.", s.judge.Name(), handle, owner)
+		return s.onHandleTaken(handle, m)
+	}
+
+	userExists, err := s.judge.UserExists(ctx, handle)
+	if err != nil {
+		return fmt.Errorf("failed to check existence of %s user '%s': %w", s.judge.Name(), handle, err)
+	}
+	if !userExists {
+		log.Printf("%s user with handle '%s' does not exist.", s.judge.Name(), handle)
+		err = s.onUserNotExist(handle, m)
+		return err
+	}
+
+	// Don't block the event handler while waiting for the user to complete the verification
+	started = true
+	go func() {
+		defer s.sessions.end(m.Author.ID)
+
+		err := s.authenticate(ctx, handle, verifier, m)
+		if err != nil {
+			log.Println("Authentication failed:", err)
+		}
+	}()
+	return nil
+}
+
+func (s *AuthService) cancelCommand(m *discordgo.MessageCreate) error {
+	session := s.sessions.cancel(m.Author.ID)
+	if session == nil {
+		msgStr := fmt.Sprintf("<@%s> does not have an authentication in progress.", m.Author.ID)
+		_, err := s.discord.ChannelMessageSend(m.ChannelID, msgStr)
+		return err
+	}
+
+	log.Printf("Discord user %s (%s) cancelled authentication of %s handle '%s'.",
+		m.Author.ID, m.Author.Username, s.judge.Name(), session.handle)
+	// The message is sent by the authentication itself when it notices the cancellation
+	return nil
+}
+
+func (s *AuthService) onSessionActive(session *authSession, m *discordgo.MessageCreate) error {
+	msgStr := fmt.Sprintf("<@%s> already has an authentication in progress for '%s' which expires <t:%d:R>. "+
+		"Use `authenticate cancel` to cancel it.", m.Author.ID, session.handle, session.deadline.Unix())
+	_, err := s.discord.ChannelMessageSend(m.ChannelID, msgStr)
+	return err
+}
+
+func (s *AuthService) onAlreadyConnected(handle string, m *discordgo.MessageCreate) error {
+	log.Printf("Discord user %s (%s) is already connected to %s user '%s'.",
+		m.Author.ID, m.Author.Username, s.judge.Name(), handle)
+	msgStr := fmt.Sprintf("<@%s> is already connected to the %s user '%s'.", m.Author.ID, s.judge.Name(), handle)
+	_, err := s.discord.ChannelMessageSend(m.ChannelID, msgStr)
+	return err
+}
+
+func (s *AuthService) onHandleTaken(handle string, m *discordgo.MessageCreate) error {
+	msgStr := fmt.Sprintf("The %s user '%s' is already connected to another Discord user. "+
+		"Ask a server admin if you believe this is wrong. <@%s>", s.judge.Name(), handle, m.Author.ID)
+	_, err := s.discord.ChannelMessageSend(m.ChannelID, msgStr)
+	return err
+}
+
+func (s *AuthService) onUserNotExist(handle string, m *discordgo.MessageCreate) error {
+	_, err := s.discord.ChannelMessageSend(m.ChannelID,
+		fmt.Sprintf("Could not find a %s user with the name '%s', are you sure you spelled it correctly?",
+			s.judge.Name(), handle))
+	return err
+}
+
+// Runs the authentication until it succeeds, times out, or the context is cancelled.
+func (s *AuthService) authenticate(ctx context.Context, handle string, verifier Verifier,
+	m *discordgo.MessageCreate) error {
+
+	instructions, err := verifier.Prepare(ctx, handle)
+	if err != nil {
+		return fmt.Errorf("preparing authentication: %w", err)
+	}
+	deadline, _ := ctx.Deadline()
+	instructions = fmt.Sprintf("%s <t:%d:R> to authenticate. <@%s>", instructions, deadline.Unix(), m.Author.ID)
+
+	msg, err := s.discord.ChannelMessageSend(m.ChannelID, instructions)
+	if err != nil {
+		return fmt.Errorf("failed to send auth instructions: %w", err)
+	}
+	updateProgress := func(status string) {
+		_, err := s.discord.ChannelMessageEdit(msg.ChannelID, msg.ID, instructions+"\n"+status)
+		if err != nil {
+			log.Println("Failed to edit authentication progress message:", err)
+		}
+	}
+
+	success := s.waitForVerification(ctx, handle, verifier, updateProgress)
+	switch {
+	case success:
+		updateProgress("Status: verified.")
+		return s.onAuthSuccess(handle, m)
+	case errors.Is(ctx.Err(), context.Canceled):
+		updateProgress("Status: cancelled.")
+		return s.onAuthCancel(handle, m)
+	default:
+		updateProgress("Status: timed out.")
+		return s.onAuthFail(handle, verifier, m)
+	}
+}
+
+func (s *AuthService) onAuthSuccess(handle string, m *discordgo.MessageCreate) error {
+	err := s.db.LinkAccount(context.TODO(), s.judge.Platform(), m.Author.ID, handle)
+	if errors.Is(err, ErrHandleTaken) {
+		// Someone else connected the handle while we were waiting for the verification
+		return errors.Join(err, s.onHandleTaken(handle, m))
+	}
+	if err != nil {
+		msgErr := s.authSuccessFailMessage(m)
+		err = errors.Join(err, msgErr)
+		return fmt.Errorf("linking %s to '%s': %w", m.Author.ID, handle, err)
+	}
+	AddAuditEntry(s.db, AuditEntry{
+		Platform:  s.judge.Platform(),
+		GuildID:   m.GuildID,
+		DiscordID: m.Author.ID,
+		ActorID:   m.Author.ID,
+		Action:    AuditLink,
+		NewHandle: handle,
+	})
+	go s.onLink(m.Author.ID)
+
+	log.Printf("Successfully authenticated discord user %s (%s) with %s handle '%s'",
+		m.Author.ID, m.Author.Username, s.judge.Name(), handle)
+	// Tell user that the authentication succeeded
+	msgStr := fmt.Sprintf("Successfully authenticated discord user <@%s> with %s handle '%s'.",
+		m.Author.ID, s.judge.Name(), handle)
+	_, err = s.discord.ChannelMessageSend(m.ChannelID, msgStr)
+	if err != nil {
+		return fmt.Errorf("failed to send authentication success message: %w", err)
+	}
+
+	return nil
+}
+
+// Send discord message to let user know that the authentication 'succeeded', but something went wrong on our end
+func (s *AuthService) authSuccessFailMessage(m *discordgo.MessageCreate) error {
+	msgStr := fmt.Sprintf("Successfully verified your %s account, "+
+		"but an error occurred when storing your information. "+
+		"If the problem persists please contact one of the devs or open an issue on the "+
+		"[Github page](https://github.com/yuqzii/konkurransetilsynet). <@%s>", s.judge.Name(), m.Author.ID)
+	_, err := s.discord.ChannelMessageSend(m.ChannelID, msgStr)
+	return err
+}
+
+func (s *AuthService) onAuthFail(handle string, verifier Verifier, m *discordgo.MessageCreate) error {
+	// Send message explaining that the authentication failed
+	msgStr := fmt.Sprintf("Authentication for %s user with handle '%s' failed. %s <@%s>",
+		s.judge.Name(), handle, verifier.FailReason(), m.Author.ID)
+	_, err := s.discord.ChannelMessageSend(m.ChannelID, msgStr)
+	if err != nil {
+		return fmt.Errorf("failed to send authentication failed message: %w", err)
+	}
+	return nil
+}
+
+func (s *AuthService) onAuthCancel(handle string, m *discordgo.MessageCreate) error {
+	msgStr := fmt.Sprintf("Cancelled authentication for %s user with handle '%s'. <@%s>",
+		s.judge.Name(), handle, m.Author.ID)
+	_, err := s.discord.ChannelMessageSend(m.ChannelID, msgStr)
+	if err != nil {
+		return fmt.Errorf("failed to send authentication cancelled message: %w", err)
+	}
+	return nil
+}
+
+// Checks the verifier until it succeeds or the context is done. Calls progress with
+// a status line after every unsuccessful check.
+func (s *AuthService) waitForVerification(ctx context.Context, handle string, verifier Verifier,
+	progress func(status string)) bool {
+
+	log.Printf("Starting %s authentication check for user with handle '%s'.", s.judge.Name(), handle)
+	check := func(ctx context.Context) (bool, error) {
+		ok, err := verifier.Check(ctx, handle)
+		if err != nil {
+			return false, fmt.Errorf("authentication check for '%s': %w", handle, err)
+		}
+		return ok, nil
+	}
+	return Poll(ctx, s.checkInterval, check, func(checks int) {
+		progress(fmt.Sprintf("Status: waiting for verification (checked %d times, last <t:%d:T>).",
+			checks, time.Now().Unix()))
+	})
+}
@@ -0,0 +1,191 @@
+package judge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/utils"
+)
+
+const defaultContestListColor int = 0x50e6ac
+
+type ContestProvider interface {
+	// Identifier of the platform the contests are from, one of the platform constants.
+	Platform() string
+	// Human readable name of the platform.
+	Name() string
+	// Returns current and future contests sorted by start time.
+	GetContests() []Contest
+}
+
+type DeadlineProvider interface {
+	// Returns future deadlines sorted by time.
+	GetDeadlines() []Deadline
+}
+
+type ContestFinishListener interface {
+	OnContestFinish(c Contest)
+}
+
+// Keeps track of the upcoming contests of a platform, and notifies listeners when a contest ends.
+type ContestService struct {
+	discord *discordgo.Session
+	judge   ContestSource
+
+	listURL string
+	color   int
+
+	contests  []Contest
+	mu        sync.RWMutex
+	listeners []ContestFinishListener
+}
+
+type ContestOption func(*ContestService)
+
+func NewContestService(discord *discordgo.Session, judge ContestSource, opts ...ContestOption) *ContestService {
+	s := &ContestService{
+		discord: discord,
+		judge:   judge,
+		color:   defaultContestListColor,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Sets the link of the title in the contest list.
+func WithContestListURL(url string) ContestOption {
+	return func(s *ContestService) {
+		s.listURL = url
+	}
+}
+
+// Sets the embed color of the contest list.
+func WithContestListColor(color int) ContestOption {
+	return func(s *ContestService) {
+		s.color = color
+	}
+}
+
+// Updates the contests immediately, and then every interval.
+func (s *ContestService) StartContestUpdate(interval time.Duration) {
+	go func() {
+		for {
+			err := s.UpdateContests(context.Background())
+			if err != nil {
+				log.Printf("Failed to update upcoming %s contests: %s", s.judge.Name(), err)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func (s *ContestService) Platform() string {
+	return s.judge.Platform()
+}
+
+func (s *ContestService) Name() string {
+	return s.judge.Name()
+}
+
+func (s *ContestService) AddListener(l ContestFinishListener) {
+	s.listeners = append(s.listeners, l)
+}
+
+// Updates the upcoming contests from the judge. Notifies the listeners of every previously
+// known contest that has ended since the last update.
+func (s *ContestService) UpdateContests(ctx context.Context) error {
+	contests, err := s.judge.UpcomingContests(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	var finished []Contest
+	for _, c := range s.contests {
+		if !c.End().After(now) {
+			finished = append(finished, c)
+		}
+	}
+	s.contests = filterFinished(contests, now)
+	s.mu.Unlock()
+
+	for _, c := range finished {
+		go s.onContestFinish(c)
+	}
+	return nil
+}
+
+func (s *ContestService) GetContests() []Contest {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := make([]Contest, len(s.contests))
+	copy(res, s.contests)
+	return res
+}
+
+func (s *ContestService) ListContests(channelID string) error {
+	pages := ContestPages(fmt.Sprintf("Upcoming %s contests", s.judge.Name()), s.listURL, s.color,
+		s.GetContests())
+	return utils.SendPages(s.discord, channelID, pages)
+}
+
+func (s *ContestService) onContestFinish(c Contest) {
+	for _, l := range s.listeners {
+		l.OnContestFinish(c)
+	}
+}
+
+// Creates embeds listing the contests, which should be sorted by start time, split into pages.
+func ContestPages(title, url string, color int, contests []Contest) []*discordgo.MessageEmbed {
+	const contestsPerPage int = 10
+
+	embed := &discordgo.MessageEmbed{
+		Title:     title,
+		URL:       url,
+		Color:     color,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	// Add embed for each contest
+	now := time.Now()
+	for _, contest := range contests {
+		name := contest.Name
+		if icon := PlatformIcon(contest.Platform); icon != "" {
+			name = icon + " " + name
+		}
+		f := &discordgo.MessageEmbedField{
+			Name:   name,
+			Inline: false,
+		}
+
+		if contest.Start.After(now) {
+			f.Value = fmt.Sprintf("Starts <t:%d:R>", contest.Start.Unix())
+		} else {
+			f.Value = fmt.Sprintf("In progress, ends <t:%d:R>", contest.End().Unix())
+		}
+
+		embed.Fields = append(embed.Fields, f)
+	}
+
+	return utils.FieldPages(embed, contestsPerPage)
+}
+
+// Removes contests that have ended
+func filterFinished(contests []Contest, now time.Time) (result []Contest) {
+	for _, c := range contests {
+		if c.End().After(now) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
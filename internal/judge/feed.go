@@ -0,0 +1,306 @@
+package judge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/utils"
+)
+
+type FeedRepository interface {
+	// Returns the platforms disabled in the contest feed of the guild.
+	GetDisabledPlatforms(ctx context.Context, guildID string) ([]string, error)
+	SetPlatformEnabled(ctx context.Context, guildID, platform string, enabled bool) error
+}
+
+var platformIcons = map[string]string{
+	Codeforces: "📊",
+	AtCoder:    "🇯🇵",
+	CodeChef:   "👨‍🍳",
+	LeetCode:   "🧩",
+	Kattis:     "🐱",
+	Olympiad:   "🏅",
+}
+
+// Returns the icon shown next to contests from the platform, or an empty string if it has none.
+func PlatformIcon(platform string) string {
+	return platformIcons[platform]
+}
+
+// Contests with the same normalized name starting this close are considered the same contest.
+const duplicateStartTolerance time.Duration = 10 * time.Minute
+
+// Combines the contests and deadlines of every provider into one timeline, which each guild can
+// limit to the platforms it is interested in.
+type ContestFeed struct {
+	discord *discordgo.Session
+	db      FeedRepository
+
+	providers         []ContestProvider
+	deadlineProviders []DeadlineProvider
+
+	// Disabled platforms by guild, loaded lazily from the database
+	disabled map[string]map[string]struct{}
+	mu       sync.RWMutex
+}
+
+func NewContestFeed(discord *discordgo.Session, db FeedRepository) *ContestFeed {
+	return &ContestFeed{
+		discord:  discord,
+		db:       db,
+		disabled: make(map[string]map[string]struct{}),
+	}
+}
+
+// Adds a provider to the feed. When providers report the same contest, the contest from the
+// provider added first is kept.
+func (f *ContestFeed) AddProvider(provider ContestProvider) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.providers = append(f.providers, provider)
+}
+
+func (f *ContestFeed) AddDeadlineProvider(provider DeadlineProvider) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.deadlineProviders = append(f.deadlineProviders, provider)
+}
+
+// Returns the contests of every provider sorted by start time, without duplicates.
+func (f *ContestFeed) GetContests() []Contest {
+	f.mu.RLock()
+	providers := slices.Clone(f.providers)
+	f.mu.RUnlock()
+
+	var contests []providedContest
+	for i, provider := range providers {
+		for _, c := range provider.GetContests() {
+			contests = append(contests, providedContest{Contest: c, provider: i})
+		}
+	}
+	slices.SortStableFunc(contests, func(a, b providedContest) int {
+		return a.Start.Compare(b.Start)
+	})
+	return removeDuplicates(contests)
+}
+
+// Returns the deadlines of every deadline provider sorted by time.
+func (f *ContestFeed) GetDeadlines() []Deadline {
+	f.mu.RLock()
+	providers := slices.Clone(f.deadlineProviders)
+	f.mu.RUnlock()
+
+	var deadlines []Deadline
+	for _, provider := range providers {
+		deadlines = append(deadlines, provider.GetDeadlines()...)
+	}
+	slices.SortStableFunc(deadlines, func(a, b Deadline) int {
+		return a.Time.Compare(b.Time)
+	})
+	return deadlines
+}
+
+// Returns the contests of the platforms enabled in the guild.
+func (f *ContestFeed) GetGuildContests(guildID string) []Contest {
+	var result []Contest
+	for _, c := range f.GetContests() {
+		if f.IsEnabled(guildID, c.Platform) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// Returns whether the platform is enabled in the guild. Platforms are enabled unless disabled.
+func (f *ContestFeed) IsEnabled(guildID, platform string) bool {
+	disabled, err := f.getDisabled(guildID)
+	if err != nil {
+		log.Printf("Failed to get disabled platforms of guild %s: %s", guildID, err)
+		return true
+	}
+	_, isDisabled := disabled[platform]
+	return !isDisabled
+}
+
+func (f *ContestFeed) getDisabled(guildID string) (map[string]struct{}, error) {
+	f.mu.RLock()
+	disabled, ok := f.disabled[guildID]
+	f.mu.RUnlock()
+	if ok {
+		return disabled, nil
+	}
+
+	platforms, err := f.db.GetDisabledPlatforms(context.TODO(), guildID)
+	if err != nil {
+		return nil, err
+	}
+	disabled = make(map[string]struct{}, len(platforms))
+	for _, p := range platforms {
+		disabled[p] = struct{}{}
+	}
+
+	f.mu.Lock()
+	f.disabled[guildID] = disabled
+	f.mu.Unlock()
+	return disabled, nil
+}
+
+func (f *ContestFeed) HandleCommand(args []string, m *discordgo.MessageCreate) error {
+	if len(args) < 2 {
+		pages := ContestPages("Upcoming contests", "", defaultContestListColor, f.GetGuildContests(m.GuildID))
+		if err := utils.SendPages(f.discord, m.ChannelID, pages); err != nil {
+			return fmt.Errorf("listing contests: %w", err)
+		}
+		return nil
+	}
+
+	switch args[1] {
+	case "platforms":
+		return f.listPlatforms(m)
+	case "enable", "disable":
+		return f.setEnabledCommand(args, m)
+	default:
+		return utils.UnknownCommand(f.discord, m)
+	}
+}
+
+func (f *ContestFeed) listPlatforms(m *discordgo.MessageCreate) error {
+	msg := "Platforms in the contest feed of this server:"
+	for _, p := range f.platforms() {
+		status := "enabled"
+		if !f.IsEnabled(m.GuildID, p.Platform()) {
+			status = "disabled"
+		}
+		msg += fmt.Sprintf("\n%s %s (`%s`): %s", PlatformIcon(p.Platform()), p.Name(), p.Platform(), status)
+	}
+
+	_, err := f.discord.ChannelMessageSend(m.ChannelID, msg)
+	return err
+}
+
+// enable|disable <platform>
+func (f *ContestFeed) setEnabledCommand(args []string, m *discordgo.MessageCreate) error {
+	isAdmin, err := utils.IsAdmin(f.discord, m.Author.ID, m.ChannelID)
+	if err != nil {
+		return fmt.Errorf("checking permissions of %s: %w", m.Author.ID, err)
+	}
+	if !isAdmin {
+		return utils.NoPermission(f.discord, m)
+	}
+
+	if len(args) != 3 {
+		_, err := f.discord.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Usage: `!contests %s [platform]`", args[1]))
+		return err
+	}
+
+	platform := strings.ToLower(args[2])
+	var provider ContestProvider
+	for _, p := range f.platforms() {
+		if p.Platform() == platform {
+			provider = p
+		}
+	}
+	if provider == nil {
+		_, err := f.discord.ChannelMessageSend(m.ChannelID,
+			fmt.Sprintf("Unknown platform `%s`. See `!contests platforms`.", platform))
+		return err
+	}
+
+	enabled := args[1] == "enable"
+	err = f.db.SetPlatformEnabled(context.TODO(), m.GuildID, platform, enabled)
+	if err != nil {
+		return fmt.Errorf("setting %s enabled in guild %s: %w", platform, m.GuildID, err)
+	}
+
+	// Reload from the database on next use
+	f.mu.Lock()
+	delete(f.disabled, m.GuildID)
+	f.mu.Unlock()
+
+	_, err = f.discord.ChannelMessageSend(m.ChannelID,
+		fmt.Sprintf("%s contests are now %sd in this server.", provider.Name(), args[1]))
+	return err
+}
+
+// Returns one provider of every platform in the feed.
+func (f *ContestFeed) platforms() []ContestProvider {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var result []ContestProvider
+	seen := make(map[string]struct{})
+	for _, p := range f.providers {
+		if _, ok := seen[p.Platform()]; ok {
+			continue
+		}
+		seen[p.Platform()] = struct{}{}
+		result = append(result, p)
+	}
+	return result
+}
+
+// A contest with the index of the provider that reported it.
+type providedContest struct {
+	Contest
+	provider int
+}
+
+// Removes contests that are already in the list, either with the same ID or with the same name
+// and almost the same start time. Of contests with the same name, the one from the provider added
+// first is kept, whichever starts first. Contests must be sorted by start time.
+func removeDuplicates(contests []providedContest) []Contest {
+	var result []providedContest
+	seen := make(map[string]struct{})
+	for _, c := range contests {
+		if _, ok := seen[contestKey(c.Contest)]; ok {
+			continue
+		}
+
+		name := normalizeContestName(c.Name)
+		duplicate := false
+		// Only the latest contests can start close enough to be duplicates
+		for i := len(result) - 1; i >= 0 && c.Start.Sub(result[i].Start) <= duplicateStartTolerance; i-- {
+			if normalizeContestName(result[i].Name) == name {
+				if c.provider < result[i].provider {
+					result[i] = c
+				}
+				duplicate = true
+				break
+			}
+		}
+		seen[contestKey(c.Contest)] = struct{}{}
+		if !duplicate {
+			result = append(result, c)
+		}
+	}
+
+	// Replaced contests may start a little later than the ones after them
+	slices.SortStableFunc(result, func(a, b providedContest) int {
+		return a.Start.Compare(b.Start)
+	})
+	unique := make([]Contest, len(result))
+	for i, c := range result {
+		unique[i] = c.Contest
+	}
+	return unique
+}
+
+// Lowercase letters and digits of the name, so that formatting differences between platforms
+// do not matter.
+func normalizeContestName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
@@ -0,0 +1,36 @@
+package judge
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_RemoveDuplicates(t *testing.T) {
+	const (
+		cfProvider = iota
+		acProvider
+		calendarProvider
+	)
+	start := time.Date(2026, 8, 10, 9, 0, 0, 0, time.UTC)
+	contests := []providedContest{
+		// The calendar lists the contest a little earlier than the judge
+		{Contest{Platform: Olympiad, ID: "nio/Final", Name: "NIO Final 2026", Start: start.Add(-5 * time.Minute)}, calendarProvider},
+		{Contest{Platform: Codeforces, ID: "2100", Name: "Codeforces Round 1000 (Div. 2)", Start: start}, cfProvider},
+		{Contest{Platform: Codeforces, ID: "2100", Name: "Codeforces Round 1000 (Div. 2)", Start: start}, cfProvider},
+		{Contest{Platform: Codeforces, ID: "2101", Name: "NIO final 2026", Start: start}, cfProvider},
+		{Contest{Platform: Olympiad, ID: "ioi/Day 1", Name: "IOI 2026: Day 1", Start: start}, calendarProvider},
+		{Contest{Platform: Olympiad, ID: "mirror", Name: "codeforces round 1000 div 2", Start: start.Add(5 * time.Minute)}, calendarProvider},
+		{Contest{Platform: AtCoder, ID: "abc500", Name: "Codeforces Round 1000 (Div. 2)", Start: start.Add(time.Hour)}, acProvider},
+	}
+
+	result := removeDuplicates(contests)
+	expected := []string{"codeforces:2101", "codeforces:2100", "olympiad:ioi/Day 1", "atcoder:abc500"}
+	if len(result) != len(expected) {
+		t.Fatalf("expected %d contests, got %+v", len(expected), result)
+	}
+	for i, key := range expected {
+		if contestKey(result[i]) != key {
+			t.Errorf("contest %d: expected %s, got %s", i, key, contestKey(result[i]))
+		}
+	}
+}
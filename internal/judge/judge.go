@@ -0,0 +1,105 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Platform identifiers, used when storing linked accounts.
+const (
+	Codeforces string = "codeforces"
+	AtCoder    string = "atcoder"
+	Kattis     string = "kattis"
+	CodeChef   string = "codechef"
+	LeetCode   string = "leetcode"
+	Olympiad   string = "olympiad"
+)
+
+var ErrAccountNotLinked = errors.New("account not linked")
+var ErrHandleTaken = errors.New("handle already linked to another user")
+var ErrNoRating = errors.New("the user does not have a rating")
+var ErrUnsupported = errors.New("not supported by the judge")
+
+// A platform hosting contests.
+type ContestSource interface {
+	// Identifier of the platform, one of the platform constants.
+	Platform() string
+	// Human readable name of the platform.
+	Name() string
+
+	// Returns current and future contests sorted by start time.
+	UpcomingContests(ctx context.Context) ([]Contest, error)
+}
+
+// A competitive programming platform.
+type Judge interface {
+	ContestSource
+
+	// Returns the current rating of the user, or ErrNoRating if the user is unrated.
+	UserRating(ctx context.Context, handle string) (*Rating, error)
+	// Returns the count latest submissions of the user, newest first, or ErrUnsupported if the
+	// judge does not expose submissions.
+	Submissions(ctx context.Context, handle string, count int) ([]Submission, error)
+	UserExists(ctx context.Context, handle string) (bool, error)
+
+	// Returns the name of the verification method used when the user does not specify one.
+	DefaultVerifier() string
+	// Returns a new verifier for the method, or false if the judge does not support it.
+	NewVerifier(method string) (Verifier, bool)
+}
+
+// A way of verifying that a Discord user owns an account on a judge.
+// A new Verifier is created for every authentication, so implementations may keep state
+// between Prepare and Check.
+type Verifier interface {
+	// Prepares the verification and returns instructions for the user. The deadline of the
+	// authentication is appended to the instructions.
+	Prepare(ctx context.Context, handle string) (instructions string, err error)
+	// Returns true if the user has completed the instructions.
+	Check(ctx context.Context, handle string) (bool, error)
+	// Explains why the authentication failed if Check never returned true.
+	FailReason() string
+	// How long the user has to complete the instructions.
+	Timeout() time.Duration
+}
+
+type Contest struct {
+	Platform string
+	ID       string
+	Name     string
+	URL      string
+	Start    time.Time
+	Duration time.Duration
+}
+
+func (c *Contest) End() time.Time {
+	return c.Start.Add(c.Duration)
+}
+
+// A deadline, such as the registration deadline of an olympiad. Reminders are sent the given
+// durations before the deadline.
+type Deadline struct {
+	Platform  string
+	ID        string
+	Name      string
+	URL       string
+	Time      time.Time
+	Reminders []time.Duration
+}
+
+type Rating struct {
+	Handle string
+	// Rating after the latest rating change
+	Rating int
+	// Rating before the latest rating change
+	OldRating int
+}
+
+type Submission struct {
+	ID          string
+	ProblemID   string
+	ProblemName string
+	Time        time.Time
+	Accepted    bool
+}
@@ -0,0 +1,225 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/utils"
+)
+
+type lbGuildData struct {
+	guildID   string
+	channelID string
+}
+
+// Sends leaderboards of the ratings of linked accounts on a judge.
+type LeaderboardService struct {
+	discord *discordgo.Session
+	judge   Judge
+	db      AccountRepository
+	guilds  GuildProvider
+
+	channelName          string
+	ratingUpdateInterval time.Duration
+	withImage            bool
+
+	data []lbGuildData
+	mu   sync.RWMutex
+}
+
+type LeaderboardOption func(*LeaderboardService)
+
+func NewLeaderboardService(discord *discordgo.Session, judge Judge, db AccountRepository,
+	guilds GuildProvider, opts ...LeaderboardOption) *LeaderboardService {
+
+	const defaultRatingUpdateInterval time.Duration = 30 * time.Minute
+
+	s := &LeaderboardService{
+		discord:              discord,
+		judge:                judge,
+		db:                   db,
+		guilds:               guilds,
+		channelName:          judge.Platform() + "-leaderboard",
+		ratingUpdateInterval: defaultRatingUpdateInterval,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func WithRatingUpdateInterval(interval time.Duration) LeaderboardOption {
+	return func(s *LeaderboardService) {
+		s.ratingUpdateInterval = interval
+	}
+}
+
+// Sets the name of the channel leaderboards are sent to in every guild.
+func WithLeaderboardChannelName(name string) LeaderboardOption {
+	return func(s *LeaderboardService) {
+		s.channelName = name
+	}
+}
+
+// Attaches a PNG table to the leaderboards.
+func WithLeaderboardImage() LeaderboardOption {
+	return func(s *LeaderboardService) {
+		s.withImage = true
+	}
+}
+
+// Sends a leaderboard message for every guild the bot is in.
+func (s *LeaderboardService) SendLeaderboardMessageAll(c Contest) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, data := range s.data {
+		go func() {
+			err := s.SendLeaderboardMessage(data.guildID, data.channelID, c)
+			if err != nil {
+				log.Printf("Error when sending leaderboard message to all guilds (guild %s): %s",
+					data.guildID, err)
+			}
+		}()
+	}
+}
+
+func (s *LeaderboardService) SendLeaderboardMessage(guildID string, channelID string, c Contest) error {
+	ratings, err := s.getRatingsInGuild(guildID)
+	if err != nil {
+		return fmt.Errorf("getting ratings in guild %s: %w", guildID, err)
+	}
+	// Sort by new rating descending
+	sort.Slice(ratings, func(i, j int) bool {
+		return ratings[i].rating.Rating > ratings[j].rating.Rating
+	})
+
+	guild, err := s.discord.State.Guild(guildID)
+	if err != nil {
+		return fmt.Errorf("getting guild of ID %s: %w", guildID, err)
+	}
+	colorer, hasColors := s.judge.(RankColorer)
+	lb := Leaderboard{
+		Title:     fmt.Sprintf("%s %s leaderboard after %s", guild.Name, s.judge.Name(), c.Name),
+		URL:       c.URL,
+		ValueName: "Rating",
+		Entries:   make([]LeaderboardEntry, len(ratings)),
+	}
+	for i, rating := range ratings {
+		lb.Entries[i] = LeaderboardEntry{
+			DiscordID: rating.discordID,
+			Handle:    rating.rating.Handle,
+			Value:     strconv.Itoa(rating.rating.Rating),
+		}
+		if hasColors {
+			lb.Entries[i].Color = colorer.RankColor(rating.rating.Rating)
+		}
+	}
+
+	return SendLeaderboard(s.discord, guildID, channelID, &lb, s.withImage)
+}
+
+// Calls hasUpdated every rating update interval, and sends true to the returned channel when
+// it reports that the ratings have been updated.
+func (s *LeaderboardService) StartRatingUpdateCheck(hasUpdated func(ctx context.Context) (bool, error)) <-chan bool {
+	updatedChan := make(chan bool)
+	go func() {
+		errCnt := 0
+		const maxErrs uint8 = 3
+
+		for {
+			time.Sleep(s.ratingUpdateInterval)
+			updated, err := hasUpdated(context.TODO())
+			if err != nil {
+				errCnt++
+				log.Printf("Failed to check %s rating update (attempt %d of %d): %s",
+					s.judge.Name(), errCnt, maxErrs, err)
+				if errCnt == int(maxErrs) {
+					log.Printf("Stopping %s rating update check.", s.judge.Name())
+					close(updatedChan)
+					return
+				}
+			}
+			if updated {
+				updatedChan <- true
+				close(updatedChan)
+				return
+			}
+		}
+	}()
+	return updatedChan
+}
+
+type guildRating struct {
+	rating    Rating
+	discordID string
+}
+
+func (s *LeaderboardService) getRatingsInGuild(guildID string) ([]*guildRating, error) {
+	accounts, err := GetLinkedAccountsInGuild(context.TODO(), s.db, s.discord, s.judge.Platform(), guildID)
+	if err != nil {
+		return nil, fmt.Errorf("getting %s accounts in %s: %w", s.judge.Name(), guildID, err)
+	}
+
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("no connected %s in the guild", s.judge.Name())
+	}
+
+	ratingChan := make(chan *guildRating)
+	var wg sync.WaitGroup
+	for _, account := range accounts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			rating, err := s.judge.UserRating(context.TODO(), account.Handle)
+			if errors.Is(err, ErrNoRating) {
+				return
+			}
+			if err != nil {
+				log.Printf("Getting %s rating from handle %s failed: %s", s.judge.Name(), account.Handle, err)
+				return
+			}
+			ratingChan <- &guildRating{rating: *rating, discordID: account.DiscordID}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(ratingChan)
+	}()
+
+	var ratings []*guildRating
+	for rating := range ratingChan {
+		ratings = append(ratings, rating)
+	}
+
+	return ratings, nil
+}
+
+func (s *LeaderboardService) UpdateData() error {
+	guilds := s.guilds.GetGuilds()
+	channels, err := utils.CreateChannelIfNotExist(s.discord, s.channelName, guilds)
+	if err != nil {
+		return err
+	}
+
+	var newData []lbGuildData
+	for i := range guilds {
+		newData = append(newData, lbGuildData{guilds[i].ID, channels[i]})
+	}
+
+	s.mu.Lock()
+	s.data = newData
+	s.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,114 @@
+package judge
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/utils"
+)
+
+const (
+	leaderboardColor   int = 0x50e6ac
+	leaderboardPerPage int = 20
+	maxNameLength      int = 20
+)
+
+// Implemented by judges with colored ranks, so names can be colored by rating on leaderboards.
+type RankColorer interface {
+	// Returns the RGB color of the rank of the rating, or 0 for unrated users.
+	RankColor(rating int) int
+}
+
+type Leaderboard struct {
+	Title string
+	// Linked from the title if not empty
+	URL string
+	// What the values are, e.g. "Rating"
+	ValueName string
+	// The rows in the order of the standings
+	Entries []LeaderboardEntry
+}
+
+type LeaderboardEntry struct {
+	DiscordID string
+	Handle    string
+	Value     string
+	// RGB color of the name, or 0 for the default color
+	Color int
+}
+
+// Sends the leaderboard as pages of embeds with buttons to turn them, with colored names. A PNG
+// table of the whole leaderboard is attached if withImage is true.
+func SendLeaderboard(discord *discordgo.Session, guildID, channelID string, lb *Leaderboard, withImage bool) error {
+	entries := lb.Entries
+	names := make([]string, len(entries))
+	nameWidth := 0
+	for i, e := range entries {
+		names[i] = memberName(discord, guildID, e.DiscordID, e.Handle)
+		nameWidth = max(nameWidth, len([]rune(names[i])))
+	}
+
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		// Pad before coloring, as the escape codes would count towards the width
+		name := fmt.Sprintf("%-*s", nameWidth, names[i])
+		if e.Color != 0 {
+			name = utils.ANSIColor(name, e.Color)
+		}
+		lines[i] = fmt.Sprintf("%3d. %s %-16s %s", i+1, name, e.Handle, e.Value)
+	}
+	if len(lines) == 0 {
+		lines = []string{"Nobody is on the leaderboard yet."}
+	}
+	pages := utils.EmbedPages(lb.Title, leaderboardColor, lines, leaderboardPerPage, "ansi")
+	for _, page := range pages {
+		page.URL = lb.URL
+	}
+
+	var files []*discordgo.File
+	if withImage && len(entries) > 0 {
+		rows := make([][]string, len(entries))
+		colors := make([]int, len(entries))
+		for i, e := range entries {
+			rows[i] = []string{strconv.Itoa(i + 1), names[i], e.Handle, e.Value}
+			colors[i] = e.Color
+		}
+		img, err := utils.RenderTable([]string{"#", "Name", "Handle", lb.ValueName}, rows, colors)
+		if err != nil {
+			return fmt.Errorf("rendering leaderboard table: %w", err)
+		}
+		files = append(files, &discordgo.File{
+			Name:        "leaderboard.png",
+			ContentType: "image/png",
+			Reader:      bytes.NewReader(img),
+		})
+	}
+
+	if err := utils.SendPages(discord, channelID, pages, files...); err != nil {
+		return fmt.Errorf("sending leaderboard: %w", err)
+	}
+	return nil
+}
+
+// Returns the name the member is shown with in the guild, or the fallback if the member is not
+// in the state.
+func memberName(discord *discordgo.Session, guildID, discordID, fallback string) string {
+	member, err := discord.State.Member(guildID, discordID)
+	if err != nil || member.User == nil {
+		return fallback
+	}
+
+	name := member.Nick
+	if name == "" {
+		name = member.User.GlobalName
+	}
+	if name == "" {
+		name = member.User.Username
+	}
+	if runes := []rune(name); len(runes) > maxNameLength {
+		name = string(runes[:maxNameLength-3]) + "..."
+	}
+	return name
+}
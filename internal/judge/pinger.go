@@ -0,0 +1,237 @@
+package judge
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/utils"
+)
+
+type GuildProvider interface {
+	GetGuilds() []*discordgo.Guild
+}
+
+type pingData struct {
+	guildID string
+	channel string
+	role    string
+}
+
+// Pings a role in every guild shortly before contests in the feed start, and when the reminders
+// of deadlines in the feed are due. Guilds are only pinged for the platforms they have enabled.
+type Pinger struct {
+	discord *discordgo.Session
+	feed    *ContestFeed
+	guilds  GuildProvider
+
+	pingTime        time.Duration
+	pingChannelName string
+	pingRoleName    string
+
+	pingData     []pingData
+	pingedIDs    map[string]struct{}
+	remindedKeys map[string]struct{}
+	mu           sync.RWMutex
+}
+
+type PingerOption func(*Pinger)
+
+func NewPinger(discord *discordgo.Session, guilds GuildProvider, feed *ContestFeed, opts ...PingerOption) *Pinger {
+	const (
+		defaultPingTime        time.Duration = 1 * time.Hour
+		defaultPingChannelName string        = "contest-pings"
+		defaultPingRoleName    string        = "Contest Ping"
+	)
+
+	p := &Pinger{
+		discord:         discord,
+		feed:            feed,
+		guilds:          guilds,
+		pingedIDs:       make(map[string]struct{}),
+		remindedKeys:    make(map[string]struct{}),
+		pingTime:        defaultPingTime,
+		pingChannelName: defaultPingChannelName,
+		pingRoleName:    defaultPingRoleName,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+func WithPingTime(t time.Duration) PingerOption {
+	return func(p *Pinger) {
+		p.pingTime = t
+	}
+}
+
+func WithPingChannelName(name string) PingerOption {
+	return func(p *Pinger) {
+		p.pingChannelName = name
+	}
+}
+
+func WithPingRoleName(name string) PingerOption {
+	return func(p *Pinger) {
+		p.pingRoleName = name
+	}
+}
+
+// Start goroutine that checks whether it should issue a ping for upcoming contests
+func (p *Pinger) StartContestPingCheck(interval time.Duration) {
+	go func() {
+		for {
+			time.Sleep(interval)
+			err := p.checkContestPing()
+			if err != nil {
+				log.Println("Automatic contest ping failed:", err)
+			}
+			err = p.checkDeadlineReminders()
+			if err != nil {
+				log.Println("Automatic deadline reminder failed:", err)
+			}
+		}
+	}()
+}
+
+func (p *Pinger) checkContestPing() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for _, c := range p.feed.GetContests() {
+		untilStart := c.Start.Sub(now)
+		shouldPing := untilStart >= 0 && untilStart <= p.pingTime
+		_, isPinged := p.pingedIDs[contestKey(c)]
+		if shouldPing && !isPinged {
+			err := p.pingContest(c)
+			if err != nil {
+				return fmt.Errorf("pinging contest: %w", err)
+			}
+		} else if untilStart > p.pingTime {
+			// Contests are sorted, so no more contests should be pinged after
+			// the first that starts too late
+			break
+		}
+	}
+
+	return nil
+}
+
+// Caller must hold p.mu.
+func (p *Pinger) pingContest(c Contest) error {
+	// Add contest to set
+	p.pingedIDs[contestKey(c)] = struct{}{}
+
+	// Issue ping for every ping channel (essentially for every server)
+	for _, data := range p.pingData {
+		if !p.feed.IsEnabled(data.guildID, c.Platform) {
+			continue
+		}
+		name := c.Name
+		if icon := PlatformIcon(c.Platform); icon != "" {
+			name = icon + " " + name
+		}
+		_, err := p.discord.ChannelMessageSend(data.channel,
+			fmt.Sprintf("<@&%s> **%s** is starting <t:%d:R>",
+				data.role, name, c.Start.Unix()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Pinger) checkDeadlineReminders() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for _, d := range p.feed.GetDeadlines() {
+		reminder, ok := dueReminder(d, now)
+		if !ok {
+			continue
+		}
+		key := fmt.Sprintf("%s:%s:%s", d.Platform, d.ID, reminder)
+		if _, isReminded := p.remindedKeys[key]; isReminded {
+			continue
+		}
+
+		p.remindedKeys[key] = struct{}{}
+		err := p.remindDeadline(d)
+		if err != nil {
+			return fmt.Errorf("reminding deadline: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Returns the smallest reminder of the deadline that has been reached. Only the latest reminder is
+// sent, so a deadline added shortly before it passes does not send every earlier reminder at once.
+func dueReminder(d Deadline, now time.Time) (time.Duration, bool) {
+	untilDeadline := d.Time.Sub(now)
+	if untilDeadline < 0 {
+		return 0, false
+	}
+
+	var due time.Duration
+	found := false
+	for _, r := range d.Reminders {
+		if untilDeadline <= r && (!found || r < due) {
+			due = r
+			found = true
+		}
+	}
+	return due, found
+}
+
+// Caller must hold p.mu.
+func (p *Pinger) remindDeadline(d Deadline) error {
+	for _, data := range p.pingData {
+		if !p.feed.IsEnabled(data.guildID, d.Platform) {
+			continue
+		}
+		_, err := p.discord.ChannelMessageSend(data.channel,
+			fmt.Sprintf("<@&%s> Deadline for **%s** is <t:%d:R>", data.role, d.Name, d.Time.Unix()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Pinger) UpdatePingData() error {
+	guilds := p.guilds.GetGuilds()
+	channels, err := utils.CreateChannelIfNotExist(p.discord, p.pingChannelName, guilds)
+	if err != nil {
+		return fmt.Errorf("finding/creating ping channel: %w", err)
+	}
+
+	roles, err := utils.CreateRoleIfNotExists(p.discord, p.pingRoleName, guilds)
+	if err != nil {
+		return fmt.Errorf("finding/creating ping role: %w", err)
+	}
+
+	var newList []pingData
+	for i := range guilds {
+		newList = append(newList, pingData{guildID: guilds[i].ID, channel: channels[i], role: roles[i]})
+	}
+
+	p.mu.Lock()
+	p.pingData = newList
+	p.mu.Unlock()
+	return nil
+}
+
+// Contest IDs are only unique within a platform
+func contestKey(c Contest) string {
+	return c.Platform + ":" + c.ID
+}
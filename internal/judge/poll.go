@@ -0,0 +1,34 @@
+package judge
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Calls check every interval until it returns true or the context is done, and returns whether
+// check succeeded. Errors from check are logged and the check is retried on the next interval.
+// pending, if not nil, is called with the number of checks made after every unsuccessful check.
+func Poll(ctx context.Context, interval time.Duration, check func(ctx context.Context) (bool, error),
+	pending func(checks int)) bool {
+
+	for checks := 1; ; checks++ {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(interval):
+		}
+
+		ok, err := check(ctx)
+		if err != nil {
+			log.Printf("Poll check failed: %v, retrying...", err)
+			continue
+		}
+		if ok {
+			return true
+		}
+		if pending != nil {
+			pending(checks)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package judge
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type authSession struct {
+	handle   string
+	deadline time.Time
+	cancel   context.CancelFunc
+}
+
+// Keeps track of active authentications, allowing at most one per Discord user.
+type authSessionManager struct {
+	sessions map[string]*authSession
+	mu       sync.Mutex
+}
+
+func newAuthSessionManager() *authSessionManager {
+	return &authSessionManager{sessions: make(map[string]*authSession)}
+}
+
+// Starts a new session for the Discord user that expires after timeout. Returns false and the
+// already active session if the user has one.
+// The returned context is cancelled when the session ends, and end must be called when the
+// authentication has finished.
+func (m *authSessionManager) start(discID, handle string,
+	timeout time.Duration) (ctx context.Context, active *authSession, ok bool) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if active, exists := m.sessions[discID]; exists {
+		return nil, active, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	session := &authSession{
+		handle:   handle,
+		deadline: time.Now().Add(timeout),
+		cancel:   cancel,
+	}
+	m.sessions[discID] = session
+	return ctx, session, true
+}
+
+// Removes the session of the Discord user and releases its context.
+func (m *authSessionManager) end(discID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if session, ok := m.sessions[discID]; ok {
+		session.cancel()
+		delete(m.sessions, discID)
+	}
+}
+
+// Cancels the active session of the Discord user. Returns the cancelled session, or nil if
+// the user did not have one.
+func (m *authSessionManager) cancel(discID string) *authSession {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[discID]
+	if !ok {
+		return nil
+	}
+	session.cancel()
+	return session
+}
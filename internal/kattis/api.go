@@ -0,0 +1,228 @@
+package kattis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+	"golang.org/x/time/rate"
+)
+
+type api interface {
+	judge.Judge
+
+	getUser(ctx context.Context, handle string) (*user, error)
+	getProblems(ctx context.Context) ([]problem, error)
+	problemURL(id string) string
+}
+
+type client struct {
+	client  *http.Client
+	limiter *rate.Limiter
+	url     string
+
+	profileTimeout time.Duration
+	problemPages   int
+}
+
+type clientOption func(*client)
+
+// Creates a Kattis client. Kattis does not have a public API, so everything is scraped from
+// the HTML pages under url.
+func NewClient(httpClient *http.Client, requestsPerSecond float64, burst int, url string,
+	opts ...clientOption) *client {
+
+	const (
+		defaultProfileTimeout time.Duration = 5 * time.Minute
+		defaultProblemPages   int           = 5
+	)
+
+	c := &client{
+		client:         httpClient,
+		limiter:        rate.NewLimiter(rate.Limit(requestsPerSecond), burst),
+		url:            url,
+		profileTimeout: defaultProfileTimeout,
+		problemPages:   defaultProblemPages,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Sets the timeout of authentication through the profile token.
+func WithProfileTimeout(timeout time.Duration) clientOption {
+	return func(c *client) {
+		c.profileTimeout = timeout
+	}
+}
+
+// Sets how many pages of the problem list are read when picking problems.
+func WithProblemPages(pages int) clientOption {
+	return func(c *client) {
+		c.problemPages = pages
+	}
+}
+
+var ErrKattisIssue = errors.New("issue with the Kattis server")
+var ErrClientIssue = errors.New("(skill) issue with our client")
+var errNotFound = errors.New("not found")
+
+type user struct {
+	Handle string
+	Name   string
+	Score  float64
+	// Zero if the user is unranked
+	Rank int
+}
+
+type problem struct {
+	ID         string
+	Name       string
+	Difficulty float64
+}
+
+// Performs a GET request and returns the body if the response status is successful.
+func (c *client) get(ctx context.Context, endpoint string) (body []byte, err error) {
+	// Wait for rate limiter permission
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.url+endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, res.Body.Close())
+	}()
+
+	if err = responseCodeCheck(res); err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(res.Body)
+}
+
+var (
+	userNameRegex    = regexp.MustCompile(`(?s)<div class="image_info-text-main-header">(.*?)</div>`)
+	userStatRegex    = regexp.MustCompile(`(?s)<span class="info_label">(Rank|Score)</span>\s*<span class="important_text">([^<]*)</span>`)
+	problemRowRegex  = regexp.MustCompile(`(?s)<tr>(.*?)</tr>`)
+	problemLinkRegex = regexp.MustCompile(`<a href="/problems/([^"/]+)">([^<]+)</a>`)
+	difficultyRegex  = regexp.MustCompile(`<span class="difficulty_number[^"]*">\s*([\d.]+)`)
+)
+
+// Scrapes the name, score and rank from the profile of the user.
+func (c *client) getUser(ctx context.Context, handle string) (*user, error) {
+	body, err := c.get(ctx, "users/"+url.PathEscape(handle))
+	if err != nil {
+		return nil, fmt.Errorf("getting Kattis profile of '%s': %w", handle, err)
+	}
+
+	u := user{Handle: handle}
+	if match := userNameRegex.FindSubmatch(body); match != nil {
+		u.Name = html.UnescapeString(strings.TrimSpace(string(match[1])))
+	}
+
+	for _, match := range userStatRegex.FindAllSubmatch(body, -1) {
+		// Numbers are formatted with thousand separators, and unranked users have a dash
+		value := strings.ReplaceAll(strings.TrimSpace(string(match[2])), ",", "")
+		if value == "" || value == "-" {
+			continue
+		}
+
+		switch string(match[1]) {
+		case "Rank":
+			u.Rank, err = strconv.Atoi(value)
+		case "Score":
+			u.Score, err = strconv.ParseFloat(value, 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: parsing %s of '%s': %w", ErrClientIssue, match[1], handle, err)
+		}
+	}
+
+	return &u, nil
+}
+
+// Scrapes the first pages of the problem list. Problems without a difficulty are skipped.
+func (c *client) getProblems(ctx context.Context) ([]problem, error) {
+	var problems []problem
+	for page := range c.problemPages {
+		body, err := c.get(ctx, "problems?page="+strconv.Itoa(page))
+		if err != nil {
+			return nil, fmt.Errorf("getting Kattis problem page %d: %w", page, err)
+		}
+
+		found := 0
+		for _, row := range problemRowRegex.FindAllSubmatch(body, -1) {
+			linkMatch := problemLinkRegex.FindSubmatch(row[1])
+			difficultyMatch := difficultyRegex.FindSubmatch(row[1])
+			if linkMatch == nil || difficultyMatch == nil {
+				continue
+			}
+			difficulty, err := strconv.ParseFloat(string(difficultyMatch[1]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: parsing difficulty of %s: %w", ErrClientIssue, linkMatch[1], err)
+			}
+
+			problems = append(problems, problem{
+				ID:         string(linkMatch[1]),
+				Name:       html.UnescapeString(string(linkMatch[2])),
+				Difficulty: difficulty,
+			})
+			found++
+		}
+
+		// Past the last page
+		if found == 0 {
+			break
+		}
+	}
+
+	return problems, nil
+}
+
+func (c *client) userExists(ctx context.Context, handle string) (bool, error) {
+	_, err := c.get(ctx, "users/"+url.PathEscape(handle))
+	if errors.Is(err, errNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *client) problemURL(id string) string {
+	return c.url + "problems/" + id
+}
+
+func responseCodeCheck(res *http.Response) error {
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %s", errNotFound, res.Status)
+	case res.StatusCode/100 == 4:
+		return fmt.Errorf("%w: %s", ErrClientIssue, res.Status)
+	case res.StatusCode/100 == 5:
+		return fmt.Errorf("%w: %s", ErrKattisIssue, res.Status)
+	default:
+		return nil
+	}
+}
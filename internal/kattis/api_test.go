@@ -0,0 +1,131 @@
+package kattis
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// Serves the fixtures in testdata in place of open.kattis.com
+func newFakeKattis(t *testing.T) *client {
+	serveFile := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			data, err := os.ReadFile("testdata/" + name)
+			if err != nil {
+				t.Fatalf("reading fixture %s: %s", name, err)
+			}
+			_, _ = w.Write(data)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/ola", serveFile("user.html"))
+	mux.HandleFunc("/problems", func(w http.ResponseWriter, r *http.Request) {
+		// Only the first page has problems
+		if r.URL.Query().Get("page") != "0" {
+			_, _ = w.Write([]byte("<table><tbody></tbody></table>"))
+			return
+		}
+		serveFile("problems.html")(w, r)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return NewClient(server.Client(), 1000, 1000, server.URL+"/", WithProblemPages(3))
+}
+
+func Test_GetUser(t *testing.T) {
+	c := newFakeKattis(t)
+
+	u, err := c.getUser(context.Background(), "ola")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := user{Handle: "ola", Name: "Ola Nordmann konk-0123456789ab", Score: 1024.5, Rank: 1337}
+	if *u != expected {
+		t.Errorf("expected %+v, got %+v", expected, *u)
+	}
+}
+
+func Test_UserExists(t *testing.T) {
+	c := newFakeKattis(t)
+
+	exists, err := c.UserExists(context.Background(), "ola")
+	if err != nil || !exists {
+		t.Errorf("expected ola to exist, got %t, %v", exists, err)
+	}
+
+	exists, err = c.UserExists(context.Background(), "nobody")
+	if err != nil || exists {
+		t.Errorf("expected nobody to not exist, got %t, %v", exists, err)
+	}
+}
+
+func Test_NameVerifier(t *testing.T) {
+	c := newFakeKattis(t)
+
+	v := &nameVerifier{client: c, token: "konk-0123456789ab"}
+	ok, err := v.Check(context.Background(), "ola")
+	if err != nil || !ok {
+		t.Errorf("expected token in name to verify, got %t, %v", ok, err)
+	}
+
+	v.token = "konk-ffffffffffff"
+	ok, err = v.Check(context.Background(), "ola")
+	if err != nil || ok {
+		t.Errorf("expected missing token to not verify, got %t, %v", ok, err)
+	}
+}
+
+func Test_GetProblems(t *testing.T) {
+	c := newFakeKattis(t)
+
+	problems, err := c.getProblems(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []problem{
+		{ID: "hello", Name: "Hello World!", Difficulty: 1.2},
+		{ID: "quadrant", Name: "Quadrant Selection", Difficulty: 3.1},
+		{ID: "knightsfen", Name: "Knights & Fen", Difficulty: 6.8},
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %d: %+v", len(expected), len(problems), problems)
+	}
+	for i := range expected {
+		if problems[i] != expected[i] {
+			t.Errorf("problem %d: expected %+v, got %+v", i, expected[i], problems[i])
+		}
+	}
+}
+
+func Test_ProblemOfTheDay(t *testing.T) {
+	c := newFakeKattis(t)
+	s := newPotdService(c)
+
+	expected := map[string]string{"easy": "hello", "medium": "quadrant", "hard": "knightsfen"}
+	day := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, r := range difficultyRanges {
+		p, err := s.get(context.Background(), r, day)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", r.Name, err)
+		}
+		if p.ID != expected[r.Name] {
+			t.Errorf("%s: expected %s, got %s", r.Name, expected[r.Name], p.ID)
+		}
+	}
+
+	// The pick is kept for the rest of the day
+	s.picks["easy"] = potdPick{day: "2025-06-01", problem: problem{ID: "cached"}}
+	p, err := s.get(context.Background(), difficultyRanges[0], day.Add(6*time.Hour))
+	if err != nil || p.ID != "cached" {
+		t.Errorf("expected cached pick on the same day, got %+v, %v", p, err)
+	}
+	p, err = s.get(context.Background(), difficultyRanges[0], day.Add(24*time.Hour))
+	if err != nil || p.ID != "hello" {
+		t.Errorf("expected new pick on the next day, got %+v, %v", p, err)
+	}
+}
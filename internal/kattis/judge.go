@@ -0,0 +1,87 @@
+package kattis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+)
+
+// The client implements judge.Judge so that account linking can be shared with the other judges.
+// Kattis has neither rated contests nor a public submission list, so those methods are unsupported.
+
+func (c *client) Platform() string {
+	return judge.Kattis
+}
+
+func (c *client) Name() string {
+	return "Kattis"
+}
+
+func (c *client) UpcomingContests(ctx context.Context) ([]judge.Contest, error) {
+	return nil, judge.ErrUnsupported
+}
+
+func (c *client) UserRating(ctx context.Context, handle string) (*judge.Rating, error) {
+	return nil, judge.ErrUnsupported
+}
+
+func (c *client) Submissions(ctx context.Context, handle string, count int) ([]judge.Submission, error) {
+	return nil, judge.ErrUnsupported
+}
+
+func (c *client) UserExists(ctx context.Context, handle string) (bool, error) {
+	return c.userExists(ctx, handle)
+}
+
+const verifierProfile = "profile"
+
+func (c *client) DefaultVerifier() string {
+	return verifierProfile
+}
+
+func (c *client) NewVerifier(method string) (judge.Verifier, bool) {
+	if method != verifierProfile {
+		return nil, false
+	}
+	return &nameVerifier{client: c, timeout: c.profileTimeout}, true
+}
+
+// Verifies by having the user put a random token in the name shown on their Kattis profile.
+type nameVerifier struct {
+	client  *client
+	timeout time.Duration
+
+	token string
+}
+
+func (v *nameVerifier) Prepare(ctx context.Context, handle string) (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating authentication token: %w", err)
+	}
+	v.token = "konk-" + hex.EncodeToString(b)
+
+	return fmt.Sprintf("Put `%s` in the name of your [Kattis profile](%susers/%s/edit)",
+		v.token, v.client.url, handle), nil
+}
+
+func (v *nameVerifier) Check(ctx context.Context, handle string) (bool, error) {
+	u, err := v.client.getUser(ctx, handle)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(u.Name, v.token), nil
+}
+
+func (v *nameVerifier) FailReason() string {
+	return fmt.Sprintf("Did not find `%s` in the name of the profile.", v.token)
+}
+
+func (v *nameVerifier) Timeout() time.Duration {
+	return v.timeout
+}
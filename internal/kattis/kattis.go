@@ -0,0 +1,132 @@
+package kattis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+	"github.com/yuqzii/konkurransetilsynet/internal/utils"
+)
+
+type Handler struct {
+	discord *discordgo.Session
+	db      judge.AccountRepository
+
+	client api
+
+	auth     *judge.AuthService
+	accounts *judge.AccountService
+	potd     *potdService
+}
+
+func NewHandler(db judge.AccountRepository, discord *discordgo.Session, client api) *Handler {
+	return &Handler{
+		discord:  discord,
+		db:       db,
+		client:   client,
+		auth:     judge.NewAuthService(db, discord, client),
+		accounts: judge.NewAccountService(db, discord, client),
+		potd:     newPotdService(client),
+	}
+}
+
+func (h *Handler) HandleCommand(args []string, m *discordgo.MessageCreate) error {
+	if len(args) < 2 {
+		return utils.UnknownCommand(h.discord, m)
+	}
+
+	switch args[1] {
+	case "authenticate":
+		err := h.auth.AuthCommand(args, m)
+		if err != nil {
+			err = errors.Join(err, h.checkAPIError(err, m.ChannelID))
+			return fmt.Errorf("authentication command failed: %w", err)
+		}
+	case "unlink":
+		err := h.accounts.UnlinkCommand(m)
+		if err != nil {
+			return fmt.Errorf("unlink command failed: %w", err)
+		}
+	case "admin":
+		err := h.accounts.AdminCommand(args, m)
+		if err != nil {
+			err = errors.Join(err, h.checkAPIError(err, m.ChannelID))
+			return fmt.Errorf("admin command failed: %w", err)
+		}
+	case "leaderboard":
+		withImage := len(args) >= 3 && strings.EqualFold(args[2], "png")
+		err := h.sendScoreLeaderboard(m.GuildID, m.ChannelID, withImage)
+		if err != nil {
+			err = errors.Join(err, h.checkAPIError(err, m.ChannelID))
+			return fmt.Errorf("leaderboard command failed: %w", err)
+		}
+	case "potd":
+		err := h.potdCommand(args, m)
+		if err != nil {
+			err = errors.Join(err, h.checkAPIError(err, m.ChannelID))
+			return fmt.Errorf("problem of the day command failed: %w", err)
+		}
+	default:
+		err := utils.UnknownCommand(h.discord, m)
+		return err
+	}
+
+	return nil
+}
+
+// Sends the problem of the day, defaulting to the easy range.
+func (h *Handler) potdCommand(args []string, m *discordgo.MessageCreate) error {
+	name := difficultyRanges[0].Name
+	if len(args) >= 3 {
+		name = strings.ToLower(args[2])
+	}
+
+	r, ok := getDifficultyRange(name)
+	if !ok {
+		names := make([]string, len(difficultyRanges))
+		for i, r := range difficultyRanges {
+			names[i] = r.Name
+		}
+		msg := fmt.Sprintf("Unknown difficulty `%s`, use one of %s.", name, strings.Join(names, ", "))
+		_, err := h.discord.ChannelMessageSend(m.ChannelID, msg)
+		return err
+	}
+
+	p, err := h.potd.get(context.TODO(), r, time.Now())
+	if err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("Today's %s Kattis problem is [%s](%s) (difficulty %.1f).",
+		r.Name, p.Name, h.client.problemURL(p.ID), p.Difficulty)
+	_, err = h.discord.ChannelMessageSend(m.ChannelID, msg)
+	return err
+}
+
+func (h *Handler) checkAPIError(checkErr error, channelID string) error {
+	var err error
+	if errors.Is(checkErr, ErrKattisIssue) {
+		err = h.sendKattisIssueMessage(channelID)
+	} else if errors.Is(checkErr, ErrClientIssue) {
+		err = h.sendClientIssueMessage(channelID)
+	}
+	return err
+}
+
+func (h *Handler) sendKattisIssueMessage(channelID string) error {
+	msg := "There is an issue with the Kattis servers."
+	_, err := h.discord.ChannelMessageSend(channelID, msg)
+	return err
+}
+
+func (h *Handler) sendClientIssueMessage(channelID string) error {
+	msg := "There is an issue with our Kattis client.\n" +
+		"Please open an issue on [Github](https://github.com/Yuqzii/Konkurransetilsynet/issues) " +
+		"or contact the developers."
+	_, err := h.discord.ChannelMessageSend(channelID, msg)
+	return err
+}
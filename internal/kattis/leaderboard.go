@@ -0,0 +1,85 @@
+package kattis
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+)
+
+type guildScore struct {
+	user      user
+	discordID string
+}
+
+// Sends a leaderboard of the Kattis scores of the connected members of the guild, with a PNG table
+// attached if withImage is true.
+func (h *Handler) sendScoreLeaderboard(guildID, channelID string, withImage bool) error {
+	scores, err := h.getScoresInGuild(guildID)
+	if err != nil {
+		return fmt.Errorf("getting scores in guild %s: %w", guildID, err)
+	}
+	if len(scores) == 0 {
+		_, err := h.discord.ChannelMessageSend(channelID, "No members of this server are connected to Kattis.")
+		return err
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].user.Score > scores[j].user.Score
+	})
+
+	guild, err := h.discord.State.Guild(guildID)
+	if err != nil {
+		return fmt.Errorf("getting guild of ID %s: %w", guildID, err)
+	}
+	lb := judge.Leaderboard{
+		Title:     guild.Name + " Kattis leaderboard",
+		ValueName: "Score",
+		Entries:   make([]judge.LeaderboardEntry, len(scores)),
+	}
+	for i, score := range scores {
+		lb.Entries[i] = judge.LeaderboardEntry{
+			DiscordID: score.discordID,
+			Handle:    score.user.Handle,
+			Value:     fmt.Sprintf("%.1f", score.user.Score),
+		}
+	}
+	return judge.SendLeaderboard(h.discord, guildID, channelID, &lb, withImage)
+}
+
+func (h *Handler) getScoresInGuild(guildID string) ([]*guildScore, error) {
+	accounts, err := judge.GetLinkedAccountsInGuild(context.TODO(), h.db, h.discord, judge.Kattis, guildID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Kattis accounts in %s: %w", guildID, err)
+	}
+
+	scoreChan := make(chan *guildScore)
+	var wg sync.WaitGroup
+	for _, account := range accounts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			u, err := h.client.getUser(context.TODO(), account.Handle)
+			if err != nil {
+				log.Printf("Getting Kattis score from handle %s failed: %s", account.Handle, err)
+				return
+			}
+			scoreChan <- &guildScore{user: *u, discordID: account.DiscordID}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(scoreChan)
+	}()
+
+	var scores []*guildScore
+	for score := range scoreChan {
+		scores = append(scores, score)
+	}
+
+	return scores, nil
+}
@@ -0,0 +1,94 @@
+package kattis
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// A range of Kattis difficulties, matching the categories shown on Kattis.
+type difficultyRange struct {
+	Name string
+	Min  float64
+	// Exclusive
+	Max float64
+}
+
+var difficultyRanges = []difficultyRange{
+	{Name: "easy", Min: 0, Max: 2.8},
+	{Name: "medium", Min: 2.8, Max: 5.5},
+	{Name: "hard", Min: 5.5, Max: 100},
+}
+
+func getDifficultyRange(name string) (difficultyRange, bool) {
+	for _, r := range difficultyRanges {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return difficultyRange{}, false
+}
+
+type potdPick struct {
+	day     string
+	problem problem
+}
+
+// Picks one problem per difficulty range every day, so that everyone asking on the same day
+// gets the same problem.
+type potdService struct {
+	client api
+
+	picks map[string]potdPick
+	mu    sync.Mutex
+}
+
+func newPotdService(client api) *potdService {
+	return &potdService{client: client, picks: make(map[string]potdPick)}
+}
+
+// Returns the problem of the day in the difficulty range, picking a new one if the day has changed.
+func (s *potdService) get(ctx context.Context, r difficultyRange, now time.Time) (*problem, error) {
+	day := now.UTC().Format(time.DateOnly)
+
+	s.mu.Lock()
+	pick, ok := s.picks[r.Name]
+	s.mu.Unlock()
+	if ok && pick.day == day {
+		return &pick.problem, nil
+	}
+
+	problems, err := s.client.getProblems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p, err := pickProblem(problems, r)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Another request may have picked a problem while we were fetching
+	if pick, ok := s.picks[r.Name]; ok && pick.day == day {
+		return &pick.problem, nil
+	}
+	s.picks[r.Name] = potdPick{day: day, problem: *p}
+	return p, nil
+}
+
+func pickProblem(problems []problem, r difficultyRange) (*problem, error) {
+	var candidates []problem
+	for _, p := range problems {
+		if p.Difficulty >= r.Min && p.Difficulty < r.Max {
+			candidates = append(candidates, p)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no %s problems found", r.Name)
+	}
+	return &candidates[rand.Intn(len(candidates))], nil
+}
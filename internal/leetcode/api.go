@@ -0,0 +1,131 @@
+package leetcode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+	"golang.org/x/time/rate"
+)
+
+// Reads upcoming contests from the LeetCode GraphQL API. The client implements judge.ContestSource.
+type client struct {
+	client  *http.Client
+	limiter *rate.Limiter
+	url     string
+}
+
+func NewClient(httpClient *http.Client, requestsPerSecond float64, burst int, url string) *client {
+	return &client{
+		client:  httpClient,
+		limiter: rate.NewLimiter(rate.Limit(requestsPerSecond), burst),
+		url:     url,
+	}
+}
+
+var ErrLeetCodeIssue = errors.New("issue with the LeetCode server")
+var ErrClientIssue = errors.New("(skill) issue with our client")
+
+const upcomingContestsQuery = "{ upcomingContests { title titleSlug startTime duration } }"
+
+type contest struct {
+	Title     string `json:"title"`
+	TitleSlug string `json:"titleSlug"`
+	// Unix seconds
+	StartTime int64 `json:"startTime"`
+	// Seconds
+	Duration int64 `json:"duration"`
+}
+
+type graphQLResponse struct {
+	Data struct {
+		UpcomingContests []contest `json:"upcomingContests"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (c *client) Platform() string {
+	return judge.LeetCode
+}
+
+func (c *client) Name() string {
+	return "LeetCode"
+}
+
+func (c *client) UpcomingContests(ctx context.Context) ([]judge.Contest, error) {
+	contests, err := c.getUpcomingContests(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]judge.Contest, len(contests))
+	for i, cont := range contests {
+		result[i] = judge.Contest{
+			Platform: judge.LeetCode,
+			ID:       cont.TitleSlug,
+			Name:     cont.Title,
+			URL:      c.url + "contest/" + cont.TitleSlug,
+			Start:    time.Unix(cont.StartTime, 0),
+			Duration: time.Duration(cont.Duration) * time.Second,
+		}
+	}
+	// The API does not sort the contests
+	slices.SortFunc(result, func(a, b judge.Contest) int {
+		return a.Start.Compare(b.Start)
+	})
+	return result, nil
+}
+
+func (c *client) getUpcomingContests(ctx context.Context) (contests []contest, err error) {
+	// Wait for rate limiter permission
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	query, err := json.Marshal(map[string]string{"query": upcomingContestsQuery})
+	if err != nil {
+		return nil, fmt.Errorf("encoding query: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url+"graphql", bytes.NewReader(query))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, res.Body.Close())
+	}()
+
+	switch {
+	case res.StatusCode/100 == 4:
+		return nil, fmt.Errorf("%w: %s", ErrClientIssue, res.Status)
+	case res.StatusCode/100 == 5:
+		return nil, fmt.Errorf("%w: %s", ErrLeetCodeIssue, res.Status)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading LeetCode response: %w", err)
+	}
+	var response graphQLResponse
+	if err = json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("%w: decoding LeetCode response: %w", ErrClientIssue, err)
+	}
+	if len(response.Errors) != 0 {
+		return nil, fmt.Errorf("%w: %s", ErrClientIssue, response.Errors[0].Message)
+	}
+	return response.Data.UpcomingContests, nil
+}
@@ -0,0 +1,55 @@
+package leetcode
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+)
+
+func Test_UpcomingContests(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /graphql", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["query"] != upcomingContestsQuery {
+			t.Errorf("unexpected request body %v, %v", body, err)
+		}
+
+		data, err := os.ReadFile("testdata/upcoming.json")
+		if err != nil {
+			t.Fatalf("reading fixture: %s", err)
+		}
+		_, _ = w.Write(data)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	c := NewClient(server.Client(), 1000, 1000, server.URL+"/")
+
+	contests, err := c.UpcomingContests(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Sorted by start time
+	expected := []judge.Contest{
+		{Platform: judge.LeetCode, ID: "biweekly-contest-159", Name: "Biweekly Contest 159",
+			URL: server.URL + "/contest/biweekly-contest-159", Start: time.Unix(1750516200, 0), Duration: 90 * time.Minute},
+		{Platform: judge.LeetCode, ID: "weekly-contest-455", Name: "Weekly Contest 455",
+			URL: server.URL + "/contest/weekly-contest-455", Start: time.Unix(1750559400, 0), Duration: 90 * time.Minute},
+	}
+	if len(contests) != len(expected) {
+		t.Fatalf("expected %d contests, got %+v", len(expected), contests)
+	}
+	for i, exp := range expected {
+		got := contests[i]
+		if got.Platform != exp.Platform || got.ID != exp.ID || got.Name != exp.Name || got.URL != exp.URL ||
+			!got.Start.Equal(exp.Start) || got.Duration != exp.Duration {
+			t.Errorf("contest %d: expected %+v, got %+v", i, exp, got)
+		}
+	}
+}
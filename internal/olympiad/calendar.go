@@ -0,0 +1,331 @@
+package olympiad
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+)
+
+// The version of the calendar file format this package reads.
+const calendarVersion int = 1
+
+type Repository interface {
+	// Returns the events added, edited or removed through commands.
+	GetOlympiadEvents(ctx context.Context) ([]StoredEvent, error)
+	// Stores the event, replacing any stored event with the same ID.
+	SaveOlympiadEvent(ctx context.Context, e Event) error
+	// Stores that the event is removed, hiding it even if it is in the calendar file.
+	RemoveOlympiadEvent(ctx context.Context, id string) error
+}
+
+// An event stored through commands. Removed events only have their ID set.
+type StoredEvent struct {
+	Event   Event
+	Removed bool
+}
+
+// A duration stored as a Go duration string, such as "5h" or "168h".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// An edition of an olympiad, such as NIO 2026/2027 or IOI 2027.
+type Event struct {
+	ID        string     `json:"id"`
+	Olympiad  string     `json:"olympiad"`
+	Name      string     `json:"name"`
+	URL       string     `json:"url,omitempty"`
+	Onsite    bool       `json:"onsite"`
+	Rounds    []Round    `json:"rounds,omitempty"`
+	Deadlines []Deadline `json:"deadlines,omitempty"`
+}
+
+type Round struct {
+	Name     string    `json:"name"`
+	Start    time.Time `json:"start"`
+	Duration Duration  `json:"duration"`
+}
+
+type Deadline struct {
+	Name string    `json:"name"`
+	Time time.Time `json:"time"`
+	// Overrides the default reminders of the calendar when set
+	Reminders []Duration `json:"reminders,omitempty"`
+}
+
+type calendarFile struct {
+	Version           int        `json:"version"`
+	DeadlineReminders []Duration `json:"deadlineReminders"`
+	Events            []Event    `json:"events"`
+}
+
+// The olympiad events from the calendar file in the repository, combined with the changes made
+// through commands. Rounds are provided as contests and deadlines as deadlines to the pinger.
+type Calendar struct {
+	db Repository
+
+	reminders []time.Duration
+	events    map[string]*Event
+	mu        sync.RWMutex
+}
+
+// Loads the calendar file at path and applies the changes stored in the database.
+func LoadCalendar(ctx context.Context, db Repository, path string) (*Calendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading calendar file: %w", err)
+	}
+	file, err := parseCalendarFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing calendar file %s: %w", path, err)
+	}
+
+	c := &Calendar{db: db, events: make(map[string]*Event)}
+	for _, r := range file.DeadlineReminders {
+		c.reminders = append(c.reminders, time.Duration(r))
+	}
+	for _, e := range file.Events {
+		c.events[e.ID] = &e
+	}
+
+	stored, err := db.GetOlympiadEvents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting stored olympiad events: %w", err)
+	}
+	for _, s := range stored {
+		// Stored events replace the whole event, so later changes to the file entry are hidden
+		if _, ok := c.events[s.Event.ID]; ok {
+			log.Printf("Olympiad event %s in %s is overridden by the database, so changes to it in the file are ignored.",
+				s.Event.ID, path)
+		}
+		if s.Removed {
+			delete(c.events, s.Event.ID)
+		} else {
+			c.events[s.Event.ID] = &s.Event
+		}
+	}
+
+	return c, nil
+}
+
+func parseCalendarFile(data []byte) (*calendarFile, error) {
+	var file calendarFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if file.Version != calendarVersion {
+		return nil, fmt.Errorf("unsupported calendar version %d, expected %d", file.Version, calendarVersion)
+	}
+
+	ids := make(map[string]struct{}, len(file.Events))
+	for _, e := range file.Events {
+		if e.ID == "" {
+			return nil, fmt.Errorf("event '%s' has no id", e.Name)
+		}
+		if _, ok := ids[e.ID]; ok {
+			return nil, fmt.Errorf("duplicate event id %s", e.ID)
+		}
+		ids[e.ID] = struct{}{}
+	}
+	return &file, nil
+}
+
+// Returns the events sorted by their first round, with events without rounds last.
+func (c *Calendar) getEvents() []Event {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	events := make([]Event, 0, len(c.events))
+	for _, e := range c.events {
+		events = append(events, *e)
+	}
+	slices.SortStableFunc(events, func(a, b Event) int {
+		switch {
+		case len(a.Rounds) == 0 && len(b.Rounds) == 0:
+			return 0
+		case len(a.Rounds) == 0:
+			return 1
+		case len(b.Rounds) == 0:
+			return -1
+		}
+		return a.Rounds[0].Start.Compare(b.Rounds[0].Start)
+	})
+	return events
+}
+
+func (c *Calendar) Platform() string {
+	return judge.Olympiad
+}
+
+func (c *Calendar) Name() string {
+	return "Olympiads"
+}
+
+// Returns the rounds of every event that have not ended, sorted by start time.
+func (c *Calendar) GetContests() []judge.Contest {
+	now := time.Now()
+	var contests []judge.Contest
+	for _, e := range c.getEvents() {
+		for _, r := range e.Rounds {
+			contest := judge.Contest{
+				Platform: judge.Olympiad,
+				ID:       e.ID + "/" + r.Name,
+				Name:     fmt.Sprintf("%s: %s", e.Name, r.Name),
+				URL:      e.URL,
+				Start:    r.Start,
+				Duration: time.Duration(r.Duration),
+			}
+			if contest.End().After(now) {
+				contests = append(contests, contest)
+			}
+		}
+	}
+
+	slices.SortStableFunc(contests, func(a, b judge.Contest) int {
+		return a.Start.Compare(b.Start)
+	})
+	return contests
+}
+
+// Returns the future deadlines of every event sorted by time.
+func (c *Calendar) GetDeadlines() []judge.Deadline {
+	c.mu.RLock()
+	defaultReminders := c.reminders
+	c.mu.RUnlock()
+
+	now := time.Now()
+	var deadlines []judge.Deadline
+	for _, e := range c.getEvents() {
+		for _, d := range e.Deadlines {
+			if !d.Time.After(now) {
+				continue
+			}
+
+			reminders := defaultReminders
+			if len(d.Reminders) != 0 {
+				reminders = make([]time.Duration, len(d.Reminders))
+				for i, r := range d.Reminders {
+					reminders[i] = time.Duration(r)
+				}
+			}
+
+			deadlines = append(deadlines, judge.Deadline{
+				Platform:  judge.Olympiad,
+				ID:        e.ID + "/" + d.Name,
+				Name:      fmt.Sprintf("%s: %s", e.Name, d.Name),
+				URL:       e.URL,
+				Time:      d.Time,
+				Reminders: reminders,
+			})
+		}
+	}
+
+	slices.SortStableFunc(deadlines, func(a, b judge.Deadline) int {
+		return a.Time.Compare(b.Time)
+	})
+	return deadlines
+}
+
+// Applies edit to the event with the ID and stores the result. Returns false if the event does
+// not exist.
+func (c *Calendar) editEvent(ctx context.Context, id string, edit func(e *Event)) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	existing, ok := c.events[id]
+	if !ok {
+		return false, nil
+	}
+	// Edit a copy so that the calendar is unchanged if storing fails
+	e := *existing
+	e.Rounds = slices.Clone(e.Rounds)
+	e.Deadlines = slices.Clone(e.Deadlines)
+	edit(&e)
+
+	if err := c.db.SaveOlympiadEvent(ctx, e); err != nil {
+		return true, fmt.Errorf("storing olympiad event %s: %w", id, err)
+	}
+	c.events[id] = &e
+	return true, nil
+}
+
+// Adds the event, or updates the details of the event with the same ID while keeping its rounds
+// and deadlines.
+func (c *Calendar) addEvent(ctx context.Context, e Event) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if existing, ok := c.events[e.ID]; ok {
+		e.Rounds = existing.Rounds
+		e.Deadlines = existing.Deadlines
+		if e.URL == "" {
+			e.URL = existing.URL
+		}
+	}
+
+	if err := c.db.SaveOlympiadEvent(ctx, e); err != nil {
+		return fmt.Errorf("storing olympiad event %s: %w", e.ID, err)
+	}
+	c.events[e.ID] = &e
+	return nil
+}
+
+// Returns false if the event does not exist.
+func (c *Calendar) removeEvent(ctx context.Context, id string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.events[id]; !ok {
+		return false, nil
+	}
+
+	if err := c.db.RemoveOlympiadEvent(ctx, id); err != nil {
+		return true, fmt.Errorf("removing olympiad event %s: %w", id, err)
+	}
+	delete(c.events, id)
+	return true, nil
+}
+
+// Replaces the round with the same name, keeping the rounds sorted by start time.
+func (e *Event) setRound(r Round) {
+	e.Rounds = slices.DeleteFunc(e.Rounds, func(old Round) bool { return old.Name == r.Name })
+	e.Rounds = append(e.Rounds, r)
+	slices.SortStableFunc(e.Rounds, func(a, b Round) int { return a.Start.Compare(b.Start) })
+}
+
+// Replaces the deadline with the same name, keeping the deadlines sorted by time.
+func (e *Event) setDeadline(d Deadline) {
+	e.Deadlines = slices.DeleteFunc(e.Deadlines, func(old Deadline) bool { return old.Name == d.Name })
+	e.Deadlines = append(e.Deadlines, d)
+	slices.SortStableFunc(e.Deadlines, func(a, b Deadline) int { return a.Time.Compare(b.Time) })
+}
+
+// Removes the round or deadline with the name. Returns false if there is none.
+func (e *Event) removeDate(name string) bool {
+	rounds, deadlines := len(e.Rounds), len(e.Deadlines)
+	e.Rounds = slices.DeleteFunc(e.Rounds, func(r Round) bool { return r.Name == name })
+	e.Deadlines = slices.DeleteFunc(e.Deadlines, func(d Deadline) bool { return d.Name == name })
+	return len(e.Rounds) != rounds || len(e.Deadlines) != deadlines
+}
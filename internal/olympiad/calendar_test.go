@@ -0,0 +1,109 @@
+package olympiad
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yuqzii/konkurransetilsynet/internal/judge"
+)
+
+type fakeRepository struct {
+	stored []StoredEvent
+}
+
+func (r *fakeRepository) GetOlympiadEvents(ctx context.Context) ([]StoredEvent, error) {
+	return r.stored, nil
+}
+
+func (r *fakeRepository) SaveOlympiadEvent(ctx context.Context, e Event) error {
+	r.stored = append(r.stored, StoredEvent{Event: e})
+	return nil
+}
+
+func (r *fakeRepository) RemoveOlympiadEvent(ctx context.Context, id string) error {
+	r.stored = append(r.stored, StoredEvent{Event: Event{ID: id}, Removed: true})
+	return nil
+}
+
+// The calendar file in the repository must always be loadable
+func Test_RepositoryCalendarFile(t *testing.T) {
+	data, err := os.ReadFile("../../olympiads.json")
+	if err != nil {
+		t.Fatalf("reading calendar file: %s", err)
+	}
+	if _, err := parseCalendarFile(data); err != nil {
+		t.Errorf("parsing calendar file: %s", err)
+	}
+}
+
+func Test_LoadCalendar(t *testing.T) {
+	now := time.Now()
+	file := `{
+		"version": 1,
+		"deadlineReminders": ["168h", "24h"],
+		"events": [
+			{"id": "nio", "olympiad": "NIO", "name": "NIO", "onsite": false,
+			 "rounds": [{"name": "Final", "start": "` + now.Add(48*time.Hour).Format(time.RFC3339) + `", "duration": "5h"},
+			            {"name": "First round", "start": "` + now.Add(-48*time.Hour).Format(time.RFC3339) + `", "duration": "3h"}],
+			 "deadlines": [{"name": "Registration", "time": "` + now.Add(24*time.Hour).Format(time.RFC3339) + `"},
+			               {"name": "Travel", "time": "` + now.Add(72*time.Hour).Format(time.RFC3339) + `", "reminders": ["12h"]}]},
+			{"id": "boi", "olympiad": "BOI", "name": "BOI", "onsite": true,
+			 "rounds": [{"name": "Day 1", "start": "` + now.Add(24*time.Hour).Format(time.RFC3339) + `", "duration": "5h"}]}
+		]
+	}`
+	path := filepath.Join(t.TempDir(), "olympiads.json")
+	if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &fakeRepository{stored: []StoredEvent{
+		{Event: Event{ID: "boi"}, Removed: true},
+		{Event: Event{ID: "ioi", Olympiad: "IOI", Name: "IOI", Rounds: []Round{
+			{Name: "Day 1", Start: now.Add(96 * time.Hour), Duration: Duration(5 * time.Hour)},
+		}}},
+	}}
+	c, err := LoadCalendar(context.Background(), repo, path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The finished round and the removed event are left out
+	contests := c.GetContests()
+	expectedIDs := []string{"nio/Final", "ioi/Day 1"}
+	if len(contests) != len(expectedIDs) {
+		t.Fatalf("expected %d contests, got %+v", len(expectedIDs), contests)
+	}
+	for i, id := range expectedIDs {
+		if contests[i].ID != id || contests[i].Platform != judge.Olympiad {
+			t.Errorf("contest %d: expected %s, got %+v", i, id, contests[i])
+		}
+	}
+
+	deadlines := c.GetDeadlines()
+	if len(deadlines) != 2 {
+		t.Fatalf("expected 2 deadlines, got %+v", deadlines)
+	}
+	if len(deadlines[0].Reminders) != 2 || deadlines[0].Reminders[0] != 168*time.Hour {
+		t.Errorf("expected default reminders, got %v", deadlines[0].Reminders)
+	}
+	if len(deadlines[1].Reminders) != 1 || deadlines[1].Reminders[0] != 12*time.Hour {
+		t.Errorf("expected overridden reminders, got %v", deadlines[1].Reminders)
+	}
+}
+
+func Test_ParseCalendarFileErrors(t *testing.T) {
+	tests := map[string]string{
+		"wrong version": `{"version": 2, "events": []}`,
+		"duplicate id":  `{"version": 1, "events": [{"id": "a"}, {"id": "a"}]}`,
+		"missing id":    `{"version": 1, "events": [{"name": "a"}]}`,
+		"bad duration":  `{"version": 1, "deadlineReminders": ["a week"], "events": []}`,
+	}
+	for name, file := range tests {
+		if _, err := parseCalendarFile([]byte(file)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package olympiad
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	// Commands take times in Norwegian time, which must work without tzdata on the host
+	_ "time/tzdata"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/yuqzii/konkurransetilsynet/internal/utils"
+)
+
+// Layout of times given in commands. Times are in Norwegian time.
+const commandTimeLayout = "2006-01-02T15:04"
+
+var norway = mustLoadLocation("Europe/Oslo")
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+type Handler struct {
+	discord  *discordgo.Session
+	Calendar *Calendar
+
+	// The calendar is shared by every guild, so only administrators of the owner guild and the
+	// bot owners can edit it
+	ownerGuildID string
+	ownerIDs     []string
+}
+
+type HandlerOption func(*Handler)
+
+// Lets the administrators of the guild edit the calendar.
+func WithOwnerGuild(guildID string) HandlerOption {
+	return func(h *Handler) {
+		h.ownerGuildID = guildID
+	}
+}
+
+// Lets the users edit the calendar from any guild.
+func WithOwners(userIDs ...string) HandlerOption {
+	return func(h *Handler) {
+		h.ownerIDs = append(h.ownerIDs, userIDs...)
+	}
+}
+
+func NewHandler(discord *discordgo.Session, calendar *Calendar, opts ...HandlerOption) *Handler {
+	h := &Handler{discord: discord, Calendar: calendar}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Returns true if the author of the message may edit the calendar.
+func (h *Handler) canEdit(m *discordgo.MessageCreate) (bool, error) {
+	if slices.Contains(h.ownerIDs, m.Author.ID) {
+		return true, nil
+	}
+	if h.ownerGuildID == "" || m.GuildID != h.ownerGuildID {
+		return false, nil
+	}
+	isAdmin, err := utils.IsAdmin(h.discord, m.Author.ID, m.ChannelID)
+	if err != nil {
+		return false, fmt.Errorf("checking permissions of %s: %w", m.Author.ID, err)
+	}
+	return isAdmin, nil
+}
+
+func (h *Handler) HandleCommand(args []string, m *discordgo.MessageCreate) error {
+	if len(args) < 2 {
+		return utils.UnknownCommand(h.discord, m)
+	}
+
+	if args[1] == "list" {
+		err := h.listEvents(m.ChannelID)
+		if err != nil {
+			return fmt.Errorf("listing olympiad events: %w", err)
+		}
+		return nil
+	}
+
+	canEdit, err := h.canEdit(m)
+	if err != nil {
+		return err
+	}
+	if !canEdit {
+		_, err := h.discord.ChannelMessageSend(m.ChannelID,
+			"The olympiad calendar can only be edited by the administrators of the server that owns the bot.")
+		return err
+	}
+
+	switch args[1] {
+	case "add":
+		err = h.addCommand(args, m)
+	case "url":
+		err = h.urlCommand(args, m)
+	case "round":
+		err = h.roundCommand(args, m)
+	case "deadline":
+		err = h.deadlineCommand(args, m)
+	case "remove":
+		err = h.removeCommand(args, m)
+	default:
+		return utils.UnknownCommand(h.discord, m)
+	}
+	if err != nil {
+		return fmt.Errorf("olympiad %s command failed: %w", args[1], err)
+	}
+	return nil
+}
+
+func (h *Handler) listEvents(channelID string) error {
+	const eventsPerPage int = 10
+
+	embed := &discordgo.MessageEmbed{
+		Title:     "Olympiad calendar",
+		Color:     0xba0c2f,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	for _, e := range h.Calendar.getEvents() {
+		format := "online"
+		if e.Onsite {
+			format = "onsite"
+		}
+
+		var lines []string
+		for _, r := range e.Rounds {
+			lines = append(lines, fmt.Sprintf("%s: <t:%d:f>", r.Name, r.Start.Unix()))
+		}
+		for _, d := range e.Deadlines {
+			lines = append(lines, fmt.Sprintf("%s deadline: <t:%d:f>", d.Name, d.Time.Unix()))
+		}
+		if len(lines) == 0 {
+			lines = append(lines, "Dates not announced yet")
+		}
+		if e.URL != "" {
+			lines = append(lines, e.URL)
+		}
+
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  fmt.Sprintf("%s (`%s`, %s)", e.Name, e.ID, format),
+			Value: strings.Join(lines, "\n"),
+		})
+	}
+
+	return utils.SendPages(h.discord, channelID, utils.FieldPages(embed, eventsPerPage))
+}
+
+// add <id> <olympiad> <online|onsite> <name>
+func (h *Handler) addCommand(args []string, m *discordgo.MessageCreate) error {
+	if len(args) < 6 {
+		return h.sendUsage(m.ChannelID, "add [id] [olympiad] [online|onsite] [name]")
+	}
+
+	var onsite bool
+	switch args[4] {
+	case "onsite":
+		onsite = true
+	case "online":
+		onsite = false
+	default:
+		return h.sendUsage(m.ChannelID, "add [id] [olympiad] [online|onsite] [name]")
+	}
+
+	e := Event{
+		ID:       args[2],
+		Olympiad: strings.ToUpper(args[3]),
+		Name:     strings.Join(args[5:], " "),
+		Onsite:   onsite,
+	}
+	if err := h.Calendar.addEvent(context.TODO(), e); err != nil {
+		return err
+	}
+	return h.sendConfirmation(m.ChannelID, fmt.Sprintf("Saved **%s** as `%s`.", e.Name, e.ID))
+}
+
+// url <id> <url>
+func (h *Handler) urlCommand(args []string, m *discordgo.MessageCreate) error {
+	if len(args) != 4 {
+		return h.sendUsage(m.ChannelID, "url [id] [url]")
+	}
+
+	ok, err := h.Calendar.editEvent(context.TODO(), args[2], func(e *Event) {
+		e.URL = args[3]
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return h.sendUnknownEvent(m.ChannelID, args[2])
+	}
+	return h.sendConfirmation(m.ChannelID, fmt.Sprintf("Updated the link of `%s`.", args[2]))
+}
+
+// round <id> <start> <duration> <name>
+func (h *Handler) roundCommand(args []string, m *discordgo.MessageCreate) error {
+	const usage = "round [id] [start, e.g. 2026-02-05T09:00] [duration, e.g. 5h] [name]"
+	if len(args) < 6 {
+		return h.sendUsage(m.ChannelID, usage)
+	}
+
+	start, err := time.ParseInLocation(commandTimeLayout, args[3], norway)
+	if err != nil {
+		return h.sendUsage(m.ChannelID, usage)
+	}
+	duration, err := time.ParseDuration(args[4])
+	if err != nil || duration <= 0 {
+		return h.sendUsage(m.ChannelID, usage)
+	}
+	r := Round{Name: strings.Join(args[5:], " "), Start: start, Duration: Duration(duration)}
+
+	ok, err := h.Calendar.editEvent(context.TODO(), args[2], func(e *Event) {
+		e.setRound(r)
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return h.sendUnknownEvent(m.ChannelID, args[2])
+	}
+	return h.sendConfirmation(m.ChannelID,
+		fmt.Sprintf("Saved round **%s** of `%s` starting <t:%d:f>.", r.Name, args[2], r.Start.Unix()))
+}
+
+// deadline <id> <time> <name>
+func (h *Handler) deadlineCommand(args []string, m *discordgo.MessageCreate) error {
+	const usage = "deadline [id] [time, e.g. 2026-01-15T23:59] [name]"
+	if len(args) < 5 {
+		return h.sendUsage(m.ChannelID, usage)
+	}
+
+	t, err := time.ParseInLocation(commandTimeLayout, args[3], norway)
+	if err != nil {
+		return h.sendUsage(m.ChannelID, usage)
+	}
+	d := Deadline{Name: strings.Join(args[4:], " "), Time: t}
+
+	ok, err := h.Calendar.editEvent(context.TODO(), args[2], func(e *Event) {
+		e.setDeadline(d)
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return h.sendUnknownEvent(m.ChannelID, args[2])
+	}
+	return h.sendConfirmation(m.ChannelID,
+		fmt.Sprintf("Saved deadline **%s** of `%s` at <t:%d:f>.", d.Name, args[2], d.Time.Unix()))
+}
+
+// remove <id> [round or deadline name]
+func (h *Handler) removeCommand(args []string, m *discordgo.MessageCreate) error {
+	if len(args) < 3 {
+		return h.sendUsage(m.ChannelID, "remove [id] [round or deadline name (optional)]")
+	}
+	id := args[2]
+
+	if len(args) == 3 {
+		ok, err := h.Calendar.removeEvent(context.TODO(), id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return h.sendUnknownEvent(m.ChannelID, id)
+		}
+		return h.sendConfirmation(m.ChannelID, fmt.Sprintf("Removed `%s`.", id))
+	}
+
+	name := strings.Join(args[3:], " ")
+	removed := false
+	ok, err := h.Calendar.editEvent(context.TODO(), id, func(e *Event) {
+		removed = e.removeDate(name)
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return h.sendUnknownEvent(m.ChannelID, id)
+	}
+	if !removed {
+		return h.sendConfirmation(m.ChannelID, fmt.Sprintf("`%s` has no round or deadline named **%s**.", id, name))
+	}
+	return h.sendConfirmation(m.ChannelID, fmt.Sprintf("Removed **%s** from `%s`.", name, id))
+}
+
+func (h *Handler) sendUsage(channelID, usage string) error {
+	_, err := h.discord.ChannelMessageSend(channelID, fmt.Sprintf("Usage: `!olympiad %s`", usage))
+	return err
+}
+
+func (h *Handler) sendUnknownEvent(channelID, id string) error {
+	_, err := h.discord.ChannelMessageSend(channelID,
+		fmt.Sprintf("There is no olympiad event with id `%s`. See `!olympiad list`.", id))
+	return err
+}
+
+func (h *Handler) sendConfirmation(channelID, msg string) error {
+	_, err := h.discord.ChannelMessageSend(channelID, msg)
+	return err
+}
@@ -52,9 +52,25 @@ func getChannelIDByName(name string, guildID string, s *discordgo.Session) (stri
 	return "", nil
 }
 
+type RoleOption func(*discordgo.RoleParams)
+
+// Sets the color of roles that are created.
+func WithRoleColor(color int) RoleOption {
+	return func(params *discordgo.RoleParams) {
+		params.Color = &color
+	}
+}
+
 // Creates a role in every guild if it does not already have one.
 // Return a slice of role IDs, one for each guild. Includes preexisting roles with the name.
-func CreateRoleIfNotExists(s *discordgo.Session, roleName string, guilds []*discordgo.Guild) (result []string, err error) {
+func CreateRoleIfNotExists(s *discordgo.Session, roleName string, guilds []*discordgo.Guild,
+	opts ...RoleOption) (result []string, err error) {
+
+	params := discordgo.RoleParams{Name: roleName}
+	for _, opt := range opts {
+		opt(&params)
+	}
+
 	for _, guild := range guilds {
 		role, err := getRoleIDByName(roleName, guild.ID, s)
 		if err != nil {
@@ -63,9 +79,7 @@ func CreateRoleIfNotExists(s *discordgo.Session, roleName string, guilds []*disc
 
 		// Create new role if there does not exist one
 		if role == "" {
-			newRole, err := s.GuildRoleCreate(guild.ID, &discordgo.RoleParams{
-				Name: roleName,
-			})
+			newRole, err := s.GuildRoleCreate(guild.ID, &params)
 			if err != nil {
 				return nil, fmt.Errorf("creating role '%s' in guild %s: %w", roleName, guild.ID, err)
 			}
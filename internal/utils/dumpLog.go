@@ -13,6 +13,8 @@ func dumpLog(s *discordgo.Session, m *discordgo.MessageCreate) error {
 		return err
 	}
 
-	_, err = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("```%s```", string(log)))
+	// Only the end of the log fits in a message
+	parts := SplitMessage(fmt.Sprintf("```\n%s```", string(log)), MaxMessageLength)
+	_, err = s.ChannelMessageSend(m.ChannelID, parts[len(parts)-1])
 	return err
 }
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Discord returns at most this many members per request.
+const guildMembersPageSize int = 1000
+
+// Returns every member of the guild, fetched page by page. Session.Guild does not include the
+// members, and listing them requires the GUILD_MEMBERS intent to be enabled for the bot.
+func GetGuildMembers(s *discordgo.Session, guildID string) ([]*discordgo.Member, error) {
+	var (
+		members []*discordgo.Member
+		after   string
+	)
+	for {
+		page, err := s.GuildMembers(guildID, after, guildMembersPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("getting members of guild %s: %w", guildID, err)
+		}
+		members = append(members, page...)
+		if len(page) < guildMembersPageSize {
+			return members, nil
+		}
+		after = page[len(page)-1].User.ID
+	}
+}
+
+// Returns true if the user is a member of the guild.
+func IsGuildMember(s *discordgo.Session, guildID, userID string) (bool, error) {
+	_, err := s.GuildMember(guildID, userID)
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeUnknownMember {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("getting member %s of guild %s: %w", userID, guildID, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,322 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	MaxMessageLength          int = 2000
+	MaxEmbedDescriptionLength int = 4096
+
+	pageButtonPrefix string        = "page"
+	pageSetLifetime  time.Duration = 24 * time.Hour
+)
+
+// Splits content into parts of at most limit characters, preferring to split at line breaks.
+// Code blocks that are split are closed at the end of a part and reopened in the next.
+func SplitMessage(content string, limit int) []string {
+	if len(content) <= limit {
+		return []string{content}
+	}
+
+	var (
+		parts []string
+		part  strings.Builder
+		// The opening line of the code block we are in, e.g. "```ansi", or empty outside code blocks
+		fence string
+	)
+	flush := func() {
+		text := strings.TrimSuffix(part.String(), "\n")
+		if fence != "" {
+			text += "\n```"
+		}
+		parts = append(parts, text)
+		part.Reset()
+		if fence != "" {
+			part.WriteString(fence + "\n")
+		}
+	}
+
+	for _, line := range strings.SplitAfter(content, "\n") {
+		// An odd number of fences opens or closes a code block
+		toggles := strings.Count(line, "```")%2 == 1
+		// Leave room to close the code block if we are in one after the line
+		room := limit
+		if (fence != "") != toggles {
+			room -= len("\n```")
+		}
+
+		for len(line) > room-part.Len() {
+			reopened := 0
+			if fence != "" {
+				reopened = len(fence) + 1
+			}
+			if part.Len() > reopened {
+				flush()
+				continue
+			}
+			// The line does not fit in an empty part either
+			// Leave room to close the code block the part is in or the line opens
+			cut := limit - part.Len()
+			if fence != "" || toggles {
+				cut -= len("\n```")
+			}
+			cut = min(cut, len(line))
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			part.WriteString(line[:cut])
+			line = line[cut:]
+			flush()
+		}
+		part.WriteString(line)
+
+		if toggles {
+			if fence == "" {
+				fence = "```" + strings.TrimSpace(line[strings.LastIndex(line, "```")+len("```"):])
+			} else {
+				fence = ""
+			}
+		}
+	}
+	if part.Len() > 0 {
+		parts = append(parts, part.String())
+	}
+	return parts
+}
+
+// Sends the message, split into several messages if the content is longer than Discord allows.
+// Embeds, files and components are sent with the last message.
+func SendSplitMessage(s *discordgo.Session, channelID string, msg *discordgo.MessageSend) error {
+	parts := SplitMessage(msg.Content, MaxMessageLength)
+	for i, part := range parts {
+		data := *msg
+		data.Content = part
+		if i < len(parts)-1 {
+			data.Embeds, data.Files, data.Components = nil, nil, nil
+		}
+		if _, err := s.ChannelMessageSendComplex(channelID, &data); err != nil {
+			return fmt.Errorf("sending part %d of %d: %w", i+1, len(parts), err)
+		}
+	}
+	return nil
+}
+
+// Builds embeds with at most perPage lines each, splitting pages that would be longer than an
+// embed description allows. The lines are wrapped in a code block of the language if it is not
+// empty.
+func EmbedPages(title string, color int, lines []string, perPage int, codeLanguage string) []*discordgo.MessageEmbed {
+	chunks := slices.Collect(slices.Chunk(lines, perPage))
+	if len(chunks) == 0 {
+		chunks = [][]string{nil}
+	}
+
+	var descriptions []string
+	for _, chunk := range chunks {
+		description := strings.Join(chunk, "\n")
+		if codeLanguage != "" {
+			description = "```" + codeLanguage + "\n" + description + "\n```"
+		}
+		descriptions = append(descriptions, SplitMessage(description, MaxEmbedDescriptionLength)...)
+	}
+
+	pages := make([]*discordgo.MessageEmbed, len(descriptions))
+	for i, description := range descriptions {
+		pages[i] = &discordgo.MessageEmbed{
+			Title:       title,
+			Description: description,
+			Color:       color,
+		}
+		if len(descriptions) > 1 {
+			pages[i].Footer = &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Page %d of %d", i+1, len(descriptions))}
+		}
+	}
+	return pages
+}
+
+// Splits the fields of the embed into pages of at most perPage fields, which otherwise are copies
+// of the embed.
+func FieldPages(embed *discordgo.MessageEmbed, perPage int) []*discordgo.MessageEmbed {
+	chunks := slices.Collect(slices.Chunk(embed.Fields, perPage))
+	if len(chunks) <= 1 {
+		return []*discordgo.MessageEmbed{embed}
+	}
+
+	pages := make([]*discordgo.MessageEmbed, len(chunks))
+	for i, fields := range chunks {
+		page := *embed
+		page.Fields = fields
+		page.Footer = &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Page %d of %d", i+1, len(chunks))}
+		pages[i] = &page
+	}
+	return pages
+}
+
+type pageSet struct {
+	pages   []*discordgo.MessageEmbed
+	created time.Time
+}
+
+// Pages sent with SendPages by ID, so HandlePageButton can turn them
+var pageSets = struct {
+	sets   map[int]*pageSet
+	nextID int
+	mu     sync.Mutex
+}{sets: make(map[int]*pageSet)}
+
+// Sends the first page with buttons to go to the previous and next pages. The files are attached
+// to the message, and stay when the page is turned. Pages can be turned for a day.
+func SendPages(s *discordgo.Session, channelID string, pages []*discordgo.MessageEmbed,
+	files ...*discordgo.File) error {
+
+	msg := discordgo.MessageSend{
+		Embeds: pages[:1],
+		Files:  files,
+	}
+	if len(pages) > 1 {
+		pageSets.mu.Lock()
+		// Forget expired pages
+		for id, set := range pageSets.sets {
+			if time.Since(set.created) > pageSetLifetime {
+				delete(pageSets.sets, id)
+			}
+		}
+		id := pageSets.nextID
+		pageSets.nextID++
+		pageSets.sets[id] = &pageSet{pages: pages, created: time.Now()}
+		pageSets.mu.Unlock()
+
+		msg.Components = pageButtons(id, 0, len(pages))
+	}
+
+	_, err := s.ChannelMessageSendComplex(channelID, &msg)
+	return err
+}
+
+func pageButtons(setID, page, pageCount int) []discordgo.MessageComponent {
+	customID := func(target int) string {
+		return fmt.Sprintf("%s:%d:%d", pageButtonPrefix, setID, target)
+	}
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+		discordgo.Button{
+			Label:    "Previous",
+			Style:    discordgo.SecondaryButton,
+			CustomID: customID(page - 1),
+			Disabled: page == 0,
+		},
+		discordgo.Button{
+			Label:    "Next",
+			Style:    discordgo.SecondaryButton,
+			CustomID: customID(page + 1),
+			Disabled: page == pageCount-1,
+		},
+	}}}
+}
+
+// Turns the page when a button of a message sent with SendPages is pressed. Should be added as a
+// handler to the session.
+func HandlePageButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+	parts := strings.Split(i.MessageComponentData().CustomID, ":")
+	if len(parts) != 3 || parts[0] != pageButtonPrefix {
+		return
+	}
+	setID, err1 := strconv.Atoi(parts[1])
+	page, err2 := strconv.Atoi(parts[2])
+	if err1 != nil || err2 != nil {
+		return
+	}
+
+	pageSets.mu.Lock()
+	set, ok := pageSets.sets[setID]
+	pageSets.mu.Unlock()
+
+	var response discordgo.InteractionResponse
+	if !ok || page < 0 || page >= len(set.pages) {
+		response = discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "These pages have expired. Run the command again to see them.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}
+	} else {
+		response = discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Embeds:     set.pages[page : page+1],
+				Components: pageButtons(setID, page, len(set.pages)),
+			},
+		}
+	}
+
+	if err := s.InteractionRespond(i.Interaction, &response); err != nil {
+		log.Printf("Failed to turn page: %s", err)
+	}
+}
+
+// The colored ANSI codes in Discord by the hue they are closest to, and the gray and white codes
+// for colors with little saturation.
+var (
+	ansiHues = []struct {
+		code int
+		hue  float64
+	}{
+		{31, 0},
+		{33, 60},
+		{32, 120},
+		{36, 180},
+		{34, 240},
+		{35, 300},
+	}
+	ansiGray  = 30
+	ansiWhite = 37
+)
+
+// Colors text in an ANSI code block with the ANSI color closest to the RGB color.
+func ANSIColor(text string, rgb int) string {
+	const minSaturation float64 = 0.25
+
+	r, g, b := float64(rgb>>16&0xff)/255, float64(rgb>>8&0xff)/255, float64(rgb&0xff)/255
+	high, low := max(r, g, b), min(r, g, b)
+
+	code := ansiGray
+	if high == 0 || (high-low)/high < minSaturation {
+		if high > 0.75 {
+			code = ansiWhite
+		}
+	} else {
+		var hue float64
+		switch high {
+		case r:
+			hue = 60 * (g - b) / (high - low)
+		case g:
+			hue = 60*(b-r)/(high-low) + 120
+		default:
+			hue = 60*(r-g)/(high-low) + 240
+		}
+		hue = math.Mod(hue+360, 360)
+
+		bestDist := 360.0
+		for _, c := range ansiHues {
+			dist := math.Abs(hue - c.hue)
+			dist = min(dist, 360-dist)
+			if dist < bestDist {
+				code, bestDist = c.code, dist
+			}
+		}
+	}
+	return fmt.Sprintf("\u001b[0;%dm%s\u001b[0m", code, text)
+}
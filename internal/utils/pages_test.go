@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_SplitMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		limit    int
+		expected []string
+	}{
+		{"fits", "line 1\nline 2", 20, []string{"line 1\nline 2"}},
+		{"at line breaks", "aaaa\nbbbb\ncccc", 10, []string{"aaaa\nbbbb", "cccc"}},
+		{"long line", "abcdefghijkl", 10, []string{"abcdefghij", "kl"}},
+		{"code block", "```ansi\n1\n2\n3\n```", 16, []string{"```ansi\n1\n2\n```", "```ansi\n3\n```"}},
+		{"long line in code block", "```\nabcdefghij\n```", 12, []string{"```\nabcd\n```", "```\nefgh\n```", "```\nij\n```"}},
+		{"after code block", "```\na\n```\nb c d e f", 12, []string{"```\na\n```", "b c d e f"}},
+		{"multibyte", "æøåæøå", 9, []string{"æøåæ", "øå"}},
+		{"long line opening code block", "hello\n```" + strings.Repeat("a", 1995), 2000,
+			[]string{"hello", "```" + strings.Repeat("a", 1993), "aa"}},
+		{"long line closing code block", "```\nab\ncdefgh```", 12, []string{"```\nab\n```", "```\ncdef\n```", "```\ngh```"}},
+	}
+
+	for _, test := range tests {
+		got := SplitMessage(test.content, test.limit)
+		if strings.Join(got, "|") != strings.Join(test.expected, "|") {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, got)
+		}
+		for _, part := range got {
+			if len(part) > test.limit {
+				t.Errorf("%s: part %q is longer than %d", test.name, part, test.limit)
+			}
+		}
+	}
+}
+
+func Test_EmbedPages(t *testing.T) {
+	lines := make([]string, 45)
+	for i := range lines {
+		lines[i] = "line"
+	}
+
+	pages := EmbedPages("Title", 0, lines, 20, "ansi")
+	if len(pages) != 3 {
+		t.Fatalf("expected 3 pages, got %d", len(pages))
+	}
+	if pages[2].Footer == nil || pages[2].Footer.Text != "Page 3 of 3" {
+		t.Errorf("expected footer 'Page 3 of 3', got %+v", pages[2].Footer)
+	}
+	if expected := "```ansi\n" + strings.Repeat("line\n", 5) + "```"; pages[2].Description != expected {
+		t.Errorf("expected last page %q, got %q", expected, pages[2].Description)
+	}
+
+	single := EmbedPages("Title", 0, lines[:3], 20, "")
+	if len(single) != 1 || single[0].Footer != nil {
+		t.Errorf("expected a single page without footer, got %d pages", len(single))
+	}
+}
+
+func Test_ANSIColor(t *testing.T) {
+	tests := []struct {
+		rgb      int
+		expected string
+	}{
+		{0xff0000, "\u001b[0;31mx\u001b[0m"},
+		{0x0000ff, "\u001b[0;34mx\u001b[0m"},
+		{0x008000, "\u001b[0;32mx\u001b[0m"},
+		{0x808080, "\u001b[0;30mx\u001b[0m"},
+		{0xff8c00, "\u001b[0;33mx\u001b[0m"},
+		{0xaa00aa, "\u001b[0;35mx\u001b[0m"},
+		{0xffffff, "\u001b[0;37mx\u001b[0m"},
+	}
+
+	for _, test := range tests {
+		if got := ANSIColor("x", test.rgb); got != test.expected {
+			t.Errorf("color %06x: expected %q, got %q", test.rgb, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Returns true if the user has administrator permissions in the channel.
+func IsAdmin(s *discordgo.Session, userID, channelID string) (bool, error) {
+	perms, err := s.UserChannelPermissions(userID, channelID)
+	if err != nil {
+		return false, fmt.Errorf("getting permissions of %s in channel %s: %w", userID, channelID, err)
+	}
+	return perms&discordgo.PermissionAdministrator != 0, nil
+}
+
+// Parses a user mention (<@id> or <@!id>) or a raw user ID into a user ID.
+func ParseUserMention(mention string) (string, bool) {
+	id := strings.TrimPrefix(mention, "<@")
+	id = strings.TrimPrefix(id, "!")
+	id = strings.TrimSuffix(id, ">")
+
+	if id == "" {
+		return "", false
+	}
+	for _, ch := range id {
+		if ch < '0' || ch > '9' {
+			return "", false
+		}
+	}
+	return id, true
+}
+
+func NoPermission(s *discordgo.Session, m *discordgo.MessageCreate) error {
+	_, err := s.ChannelMessageSend(m.ChannelID, "You need administrator permissions to use this command.")
+	return err
+}
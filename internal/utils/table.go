@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Renders a table as a PNG image. rowColors holds an RGB text color for every row, where 0 is
+// the default color.
+func RenderTable(headers []string, rows [][]string, rowColors []int) ([]byte, error) {
+	const (
+		padding    int = 10
+		columnGap  int = 16
+		lineHeight int = 18
+	)
+	var (
+		face       = basicfont.Face7x13
+		background = color.RGBA{0x2b, 0x2d, 0x31, 0xff}
+		stripe     = color.RGBA{0x32, 0x34, 0x39, 0xff}
+		text       = color.RGBA{0xdb, 0xde, 0xe1, 0xff}
+		header     = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	)
+
+	// Every character in the font has the same width
+	charWidth := font.MeasureString(face, "0").Ceil()
+	widths := make([]int, len(headers))
+	for _, row := range append([][]string{headers}, rows...) {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len([]rune(cell))*charWidth)
+		}
+	}
+	width := 2*padding + columnGap*(len(headers)-1)
+	for _, w := range widths {
+		width += w
+	}
+	height := 2*padding + lineHeight*(len(rows)+1)
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	drawRow := func(y int, cells []string, c color.Color) {
+		d := font.Drawer{Dst: img, Src: image.NewUniform(c), Face: face}
+		x := padding
+		for i, cell := range cells {
+			d.Dot = fixed.P(x, y+face.Ascent+(lineHeight-face.Height)/2)
+			d.DrawString(cell)
+			x += widths[i] + columnGap
+		}
+	}
+
+	drawRow(padding, headers, header)
+	for i, row := range rows {
+		y := padding + lineHeight*(i+1)
+		if i%2 == 0 {
+			draw.Draw(img, image.Rect(0, y, width, y+lineHeight), image.NewUniform(stripe), image.Point{}, draw.Src)
+		}
+		var c color.Color = text
+		if i < len(rowColors) && rowColors[i] != 0 {
+			rgb := rowColors[i]
+			c = color.RGBA{uint8(rgb >> 16), uint8(rgb >> 8), uint8(rgb), 0xff}
+		}
+		drawRow(y, row, c)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}